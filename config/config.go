@@ -1,14 +1,62 @@
 package config
 
 import (
+	"errors"
+	"fmt"
 	"log"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/constants"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/helpers"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/entities"
+)
+
+var (
+	errNoProcessors       = errors.New("no payment processor configured")
+	errInvalidProcessor   = errors.New("payment processor needs a name and an url")
+	errDuplicateProcessor = errors.New("payment processor configured twice")
+	errNeedsRedis         = errors.New("the memory storage backend runs without redis, which this needs")
 )
 
 type Config struct {
-	ServerPort               string `json:"SERVER_PORT"`
-	PaymentProcessorDefault  string `json:"PAYMENT_PROCESSOR_DEFAULT"`
-	PaymentProcessorFallback string `json:"PAYMENT_PROCESSOR_FALLBACK"`
+	ServerPort                string                                                       `json:"SERVER_PORT"`
+	PaymentProcessorDefault   string                                                       `json:"PAYMENT_PROCESSOR_DEFAULT"`
+	PaymentProcessorFallback  string                                                       `json:"PAYMENT_PROCESSOR_FALLBACK"`
+	InstanceName              string                                                       `optional:"true"`
+	RinhaToken                string                                                       `optional:"true"`
+	PaymentQueueBackend       string                                                       `optional:"true"`
+	PaymentQueuePath          string                                                       `optional:"true"`
+	StorageBackend            string                                                       `optional:"true"`
+	StorageSecondaryBackend   string                                                       `optional:"true"`
+	StorageMode               string                                                       `optional:"true"`
+	PaymentRoutingStrategy    string                                                       `optional:"true"`
+	WorkerPoolOverflow        string                                                       `optional:"true"`
+	PaymentProcessors         []entities.ProcessorConfig                                   `optional:"true"`
+	CircuitBreakers           map[entities.ProcessorProvider]entities.CircuitBreakerPolicy `optional:"true"`
+	ConcurrencyLimit          entities.ConcurrencyLimitPolicy                              `optional:"true"`
+	CircuitBreaker            entities.CircuitBreakerPolicy                                `optional:"true"`
+	PaymentHedging            entities.HedgingPolicy                                       `optional:"true"`
+	PaymentRetryDelay         time.Duration                                                `optional:"true"`
+	WorkerPoolBlockTimeout    time.Duration                                                `optional:"true"`
+	ShutdownTimeout           time.Duration                                                `optional:"true"`
+	ShutdownGrace             time.Duration                                                `optional:"true"`
+	ReconciliationInterval    time.Duration                                                `optional:"true"`
+	ReconciliationWindow      time.Duration                                                `optional:"true"`
+	ReconciliationBucket      time.Duration                                                `optional:"true"`
+	PaymentRoutingLatencyCost float64                                                      `optional:"true"`
+	PaymentRoutingWaitCost    float64                                                      `optional:"true"`
+	PaymentMaxAttempts        int                                                          `optional:"true"`
+	WorkerPoolWorkers         int                                                          `optional:"true"`
+	WorkerPoolQueueSize       int                                                          `optional:"true"`
+	WorkerPoolRejectStatus    int                                                          `optional:"true"`
+	RedisMigrateLegacyKeys    bool                                                         `optional:"true"`
+	ReconciliationEnabled     bool                                                         `optional:"true"`
+	ReconciliationRepair      bool                                                         `optional:"true"`
 }
 
 func New() *Config {
@@ -17,14 +65,266 @@ func New() *Config {
 
 func setup() *Config {
 	config := &Config{
-		ServerPort:               os.Getenv("SERVER_PORT"),
-		PaymentProcessorDefault:  os.Getenv("PAYMENT_PROCESSOR_DEFAULT"),
-		PaymentProcessorFallback: os.Getenv("PAYMENT_PROCESSOR_FALLBACK"),
+		ServerPort:                os.Getenv("SERVER_PORT"),
+		PaymentProcessorDefault:   os.Getenv("PAYMENT_PROCESSOR_DEFAULT"),
+		PaymentProcessorFallback:  os.Getenv("PAYMENT_PROCESSOR_FALLBACK"),
+		InstanceName:              getEnv("HOSTNAME", "local"),
+		RinhaToken:                os.Getenv("RINHA_TOKEN"),
+		PaymentMaxAttempts:        getEnvInt("PAYMENT_MAX_ATTEMPTS", constants.DefaultPaymentMaxAttempts),
+		PaymentRetryDelay:         getEnvDuration("PAYMENT_RETRY_DELAY", constants.DefaultPaymentRetryDelay),
+		StorageBackend:            getEnv("STORAGE_BACKEND", constants.StorageBackendRedis),
+		StorageSecondaryBackend:   os.Getenv("STORAGE_SECONDARY_BACKEND"),
+		StorageMode:               getEnv("STORAGE_MODE", constants.StorageModeSingle),
+		PaymentRoutingStrategy:    getEnv("PAYMENT_ROUTING_STRATEGY", constants.PaymentRoutingPriority),
+		PaymentRoutingLatencyCost: getEnvFloat("PAYMENT_ROUTING_LATENCY_COST", constants.DefaultRoutingLatencyCost),
+		PaymentRoutingWaitCost:    getEnvFloat("PAYMENT_ROUTING_WAIT_COST", constants.DefaultRoutingWaitCost),
+		RedisMigrateLegacyKeys:    getEnvBool("REDIS_MIGRATE_LEGACY_KEYS"),
+		ReconciliationEnabled:     getEnvBool("RECONCILIATION_ENABLED"),
+		ReconciliationRepair:      getEnvBool("RECONCILIATION_REPAIR"),
+		ReconciliationInterval:    getEnvDuration("RECONCILIATION_INTERVAL", constants.DefaultReconciliationInterval),
+		ReconciliationWindow:      getEnvDuration("RECONCILIATION_WINDOW", constants.DefaultReconciliationWindow),
+		ReconciliationBucket:      getEnvDuration("RECONCILIATION_BUCKET", constants.DefaultReconciliationBucket),
+		WorkerPoolWorkers:         getEnvInt("WORKER_POOL_WORKERS", constants.DefaultWorkerPoolWorkers),
+		WorkerPoolOverflow:        getEnv("WORKER_POOL_OVERFLOW", constants.WorkerPoolOverflowSpill),
+		WorkerPoolBlockTimeout:    getEnvDuration("WORKER_POOL_BLOCK_TIMEOUT", constants.DefaultWorkerPoolBlockTimeout),
+		WorkerPoolRejectStatus:    getEnvInt("WORKER_POOL_REJECT_STATUS", constants.HTTPStatusServiceUnavailable),
+		ShutdownTimeout:           getEnvDuration("SHUTDOWN_TIMEOUT", constants.DefaultShutdownTimeout),
+		ShutdownGrace:             getEnvDuration("SHUTDOWN_GRACE", constants.DefaultShutdownGrace),
+	}
+
+	config.ConcurrencyLimit = entities.ConcurrencyLimitPolicy{
+		Mode:             getEnv("CONCURRENCY_LIMIT_MODE", constants.ConcurrencyLimitModeFixed),
+		InitialLimit:     getEnvInt("CONCURRENCY_LIMIT_INITIAL", constants.DefaultConcurrencyLimit),
+		MinLimit:         getEnvInt("CONCURRENCY_LIMIT_MIN", constants.DefaultConcurrencyMinLimit),
+		MaxLimit:         getEnvInt("CONCURRENCY_LIMIT_MAX", constants.DefaultConcurrencyMaxLimit),
+		SlowCallDuration: getEnvDuration("CONCURRENCY_LIMIT_SLOW_CALL_DURATION", constants.DefaultConcurrencySlowCall),
+		Backoff:          getEnvFloat("CONCURRENCY_LIMIT_BACKOFF", constants.DefaultConcurrencyBackoff),
+		Tolerance:        getEnvFloat("CONCURRENCY_LIMIT_TOLERANCE", constants.DefaultConcurrencyTolerance),
+	}
+
+	config.PaymentHedging = entities.HedgingPolicy{
+		Enabled:   getEnvBool("PAYMENT_HEDGING_ENABLED"),
+		Factor:    getEnvFloat("PAYMENT_HEDGING_FACTOR", constants.DefaultHedgingFactor),
+		MinBudget: getEnvDuration("PAYMENT_HEDGING_MIN_BUDGET", constants.DefaultHedgingMinBudget),
+	}
+
+	// a memory storage runs a single instance, which needs no redis stream
+	queueBackend := constants.PaymentQueueBackendRedis
+	if config.StorageBackend == constants.StorageBackendMemory {
+		queueBackend = constants.PaymentQueueBackendFile
+	}
+
+	config.PaymentQueueBackend = getEnv("PAYMENT_QUEUE_BACKEND", queueBackend)
+
+	config.WorkerPoolQueueSize = getEnvInt(
+		"WORKER_POOL_QUEUE_SIZE",
+		config.WorkerPoolWorkers*constants.DefaultWorkerPoolQueueFactor,
+	)
+
+	// overloaded clients are either told to come back later or to slow down
+	if config.WorkerPoolRejectStatus != constants.HTTPStatusTooManyRequests {
+		config.WorkerPoolRejectStatus = constants.HTTPStatusServiceUnavailable
+	}
+
+	config.CircuitBreaker = getEnvCircuitBreaker("CIRCUIT_BREAKER_", entities.CircuitBreakerPolicy{
+		Mode:                  constants.CircuitBreakerModeConsecutive,
+		WindowType:            constants.CircuitBreakerWindowCount,
+		WindowSize:            constants.DefaultCircuitBreakerWindowSize,
+		MinimumCalls:          constants.DefaultCircuitBreakerMinimumCalls,
+		FailureThreshold:      constants.MaxAttemptsBeforeOpen,
+		FailureRateThreshold:  constants.DefaultCircuitBreakerFailureRate,
+		SlowCallRateThreshold: constants.DefaultCircuitBreakerSlowCallRate,
+		SlowCallDuration:      constants.DefaultCircuitBreakerSlowCall,
+		RecoveryTimeout:       constants.RecoveryTimeout,
+		HalfOpenProbes:        constants.DefaultCircuitBreakerProbes,
+		CacheTTL:              constants.DefaultCircuitBreakerCacheTTL,
+	})
+
+	config.PaymentQueuePath = getEnv(
+		"PAYMENT_QUEUE_PATH",
+		filepath.Join(os.TempDir(), "payment-queue-"+config.InstanceName+".log"),
+	)
+
+	processors, err := getEnvProcessors(config)
+	if err != nil {
+		log.Fatalf("error reading PAYMENT_PROCESSORS: %v", err)
+	}
+
+	config.PaymentProcessors = processors
+	// each processor breaker takes the shared policy with its own overrides
+	config.CircuitBreakers = make(map[entities.ProcessorProvider]entities.CircuitBreakerPolicy, len(processors))
+
+	for _, processor := range processors {
+		config.CircuitBreakers[processor.Name] = getEnvCircuitBreaker(
+			"CIRCUIT_BREAKER_"+envName(string(processor.Name))+"_", config.CircuitBreaker,
+		)
 	}
 
 	if err := validate(config); err != nil {
 		log.Fatalf("error validating config: %v", err)
 	}
 
+	if err := validateInProcess(config); err != nil {
+		log.Fatalf("error validating config: %v", err)
+	}
+
+	// the scheduled reconciliation reads the processors admin summaries
+	if config.ReconciliationEnabled && config.RinhaToken == "" {
+		log.Fatal("error validating config: RINHA_TOKEN is required when RECONCILIATION_ENABLED is set")
+	}
+
 	return config
 }
+
+func getEnv(key string, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+
+	return defaultValue
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || value <= 0 {
+		return defaultValue
+	}
+
+	return value
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(key))
+	if err != nil || value <= 0 {
+		return defaultValue
+	}
+
+	return value
+}
+
+// getEnvFloat accepts zero, only negative or malformed values fall back.
+func getEnvFloat(key string, defaultValue float64) float64 {
+	value, err := strconv.ParseFloat(os.Getenv(key), 64)
+	if err != nil || value < 0 {
+		return defaultValue
+	}
+
+	return value
+}
+
+func getEnvBool(key string) bool {
+	value, err := strconv.ParseBool(os.Getenv(key))
+
+	return err == nil && value
+}
+
+// getEnvCircuitBreaker reads the policy variables under prefix, such as
+// CIRCUIT_BREAKER_FAILURE_THRESHOLD, keeping base for the ones not set.
+func getEnvCircuitBreaker(prefix string, base entities.CircuitBreakerPolicy) entities.CircuitBreakerPolicy {
+	return entities.CircuitBreakerPolicy{
+		Mode:                  getEnv(prefix+"MODE", base.Mode),
+		WindowType:            getEnv(prefix+"WINDOW_TYPE", base.WindowType),
+		WindowSize:            getEnvInt(prefix+"WINDOW_SIZE", base.WindowSize),
+		MinimumCalls:          getEnvInt(prefix+"MINIMUM_CALLS", base.MinimumCalls),
+		FailureThreshold:      int32(getEnvInt(prefix+"FAILURE_THRESHOLD", int(base.FailureThreshold))),
+		FailureRateThreshold:  getEnvFloat(prefix+"FAILURE_RATE", base.FailureRateThreshold),
+		SlowCallRateThreshold: getEnvFloat(prefix+"SLOW_CALL_RATE", base.SlowCallRateThreshold),
+		SlowCallDuration:      getEnvDuration(prefix+"SLOW_CALL_DURATION", base.SlowCallDuration),
+		RecoveryTimeout:       getEnvDuration(prefix+"RECOVERY_TIMEOUT", base.RecoveryTimeout),
+		HalfOpenProbes:        getEnvInt(prefix+"HALF_OPEN_PROBES", base.HalfOpenProbes),
+		CacheTTL:              getEnvDuration(prefix+"CACHE_TTL", base.CacheTTL),
+	}
+}
+
+// envName turns a processor name into its part of a variable name, so the
+// breaker of "cheap-slow" reads CIRCUIT_BREAKER_CHEAP_SLOW_*.
+func envName(name string) string {
+	return strings.Map(func(char rune) rune {
+		if unicode.IsLetter(char) || unicode.IsDigit(char) {
+			return unicode.ToUpper(char)
+		}
+
+		return '_'
+	}, name)
+}
+
+// validateInProcess rejects what needs redis along a memory storage backend,
+// which keeps claims, status, retries and dead letters in the process. Every
+// other backend keeps all of them but the claims on redis.
+func validateInProcess(config *Config) error {
+	if config.StorageBackend != constants.StorageBackendMemory {
+		return nil
+	}
+
+	if config.PaymentQueueBackend != constants.PaymentQueueBackendFile {
+		return constants.NewErrorWrapper(errNeedsRedis, "PAYMENT_QUEUE_BACKEND="+config.PaymentQueueBackend)
+	}
+
+	for name, policy := range config.CircuitBreakers {
+		if policy.Mode == constants.CircuitBreakerModeDistributed {
+			return constants.NewErrorWrapper(errNeedsRedis, "distributed circuit breaker of "+string(name))
+		}
+	}
+
+	return nil
+}
+
+// getEnvProcessors reads PAYMENT_PROCESSORS, a JSON list such as
+// [{"name":"default","url":"http://...","priority":1,"fee":0.05}], falling
+// back to the default and fallback processor urls.
+func getEnvProcessors(config *Config) ([]entities.ProcessorConfig, error) {
+	raw := os.Getenv("PAYMENT_PROCESSORS")
+	if raw == "" {
+		return []entities.ProcessorConfig{
+			{
+				Name:     entities.Default,
+				URL:      config.PaymentProcessorDefault,
+				Fee:      constants.DefaultProcessorFee,
+				Priority: 1,
+				Weight:   1,
+			},
+			{
+				Name:     entities.Fallback,
+				URL:      config.PaymentProcessorFallback,
+				Fee:      constants.FallbackProcessorFee,
+				Priority: 2, //nolint:mnd // tried after the default one
+				Weight:   1,
+			},
+		}, nil
+	}
+
+	var processors []entities.ProcessorConfig
+	if err := helpers.Unmarshal([]byte(raw), &processors); err != nil {
+		return nil, fmt.Errorf("error parsing processors: %w", err)
+	}
+
+	names := map[entities.ProcessorProvider]bool{}
+
+	for index := range processors {
+		processor := &processors[index]
+
+		if processor.Name == "" || processor.URL == "" {
+			return nil, constants.NewErrorWrapper(errInvalidProcessor, index)
+		}
+
+		if names[processor.Name] {
+			return nil, constants.NewErrorWrapper(errDuplicateProcessor, processor.Name)
+		}
+
+		names[processor.Name] = true
+
+		if processor.Priority == 0 {
+			processor.Priority = index + 1
+		}
+
+		if processor.Weight == 0 {
+			processor.Weight = 1
+		}
+	}
+
+	if len(processors) == 0 {
+		return nil, errNoProcessors
+	}
+
+	return processors, nil
+}
@@ -0,0 +1,65 @@
+package constants
+
+import "time"
+
+const (
+	CircuitBreakerClosed int32 = iota
+	CircuitBreakerOpen
+	CircuitBreakerHalfOpen
+)
+
+func CircuitBreakerStateName(state int32) string {
+	switch state {
+	case CircuitBreakerClosed:
+		return "closed"
+	case CircuitBreakerOpen:
+		return "open"
+	case CircuitBreakerHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+const (
+	// CircuitBreakerModeConsecutive opens after a number of failures in a row.
+	CircuitBreakerModeConsecutive = "consecutive"
+	// CircuitBreakerModeSlidingWindow opens on the failure or slow call rate of
+	// the last calls.
+	CircuitBreakerModeSlidingWindow = "sliding-window"
+
+	CircuitBreakerWindowCount = "count"
+	CircuitBreakerWindowTime  = "time"
+
+	// calls for a count window, seconds for a time window.
+	DefaultCircuitBreakerWindowSize   = 20
+	DefaultCircuitBreakerMinimumCalls = 10
+	// rates in percent of the calls in the window.
+	DefaultCircuitBreakerFailureRate  = 50
+	DefaultCircuitBreakerSlowCallRate = 80
+	DefaultCircuitBreakerSlowCall     = time.Second
+	DefaultCircuitBreakerProbes       = 3
+)
+
+const (
+	// CircuitBreakerModeDistributed keeps a consecutive failures breaker in
+	// Redis, shared by every instance.
+	CircuitBreakerModeDistributed = "distributed"
+
+	CircuitBreakerKeyPrefix = "circuit-breaker:"
+	// how long an instance trusts the shared state it last read.
+	DefaultCircuitBreakerCacheTTL = 20 * time.Millisecond
+)
+
+// CircuitBreakerHistorySize is how many transitions each breaker keeps.
+const CircuitBreakerHistorySize = 32
+
+const (
+	CircuitBreakerReasonFailureThreshold = "failure threshold reached"
+	CircuitBreakerReasonFailureRate      = "failure rate threshold exceeded"
+	CircuitBreakerReasonSlowCallRate     = "slow call rate threshold exceeded"
+	CircuitBreakerReasonRecoveryTimeout  = "recovery timeout elapsed"
+	CircuitBreakerReasonProbeFailed      = "probe call failed"
+	CircuitBreakerReasonProbesSucceeded  = "probe calls succeeded"
+	CircuitBreakerReasonCallSucceeded    = "call succeeded"
+)
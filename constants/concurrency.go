@@ -0,0 +1,27 @@
+package constants
+
+import "time"
+
+const (
+	// ConcurrencyLimitModeFixed keeps the initial limit.
+	ConcurrencyLimitModeFixed = "fixed"
+	// ConcurrencyLimitModeAIMD grows the limit slowly while calls go well and
+	// cuts it by a ratio on every failed or slow one.
+	ConcurrencyLimitModeAIMD = "aimd"
+	// ConcurrencyLimitModeGradient follows the ratio between the usual and the
+	// current latency, shrinking the limit as soon as calls queue up.
+	ConcurrencyLimitModeGradient = "gradient"
+
+	DefaultConcurrencyLimit    = 10
+	DefaultConcurrencyMinLimit = 1
+	DefaultConcurrencyMaxLimit = 50
+	// AIMD takes calls this slow as a sign of overload.
+	DefaultConcurrencySlowCall = time.Second
+	DefaultConcurrencyBackoff  = 0.9
+	// gradient lets the latency grow this much over its usual value first.
+	DefaultConcurrencyTolerance = 1.5
+
+	// a payment attempt gives up waiting for room under the limit after this
+	// long and is retried like one the processor did not answer.
+	ConcurrencyAcquireTimeout = time.Second
+)
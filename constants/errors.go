@@ -10,6 +10,23 @@ var (
 	ErrAmountMustBeGreaterThanZero   = errors.New("amount must be greater than 0")
 	ErrCorrelationIDIsRequired       = errors.New("correlationId is required")
 	ErrGettingPaymentRequestFromPool = errors.New("error getting payment request from pool")
+	ErrDeadLetterNotFound            = errors.New("dead letter payment not found")
+	ErrDuplicatePayment              = errors.New("payment already accepted")
+	ErrPaymentNotFound               = errors.New("payment not found")
+	ErrInvalidAmount                 = errors.New("invalid amount")
+	ErrAmountTooPrecise              = errors.New("amount has more precision than cents")
+	ErrReconciliationNotFound        = errors.New("no reconciliation has run yet")
+	ErrInvalidReconciliationWindow   = errors.New("reconciliation window must end after it starts")
+	ErrHealthRateLimited             = errors.New("health check rate limited")
+	ErrHealthCheckTimedOut           = errors.New("health check timed out")
+	ErrPaymentDeferred               = errors.New("payment deferred until a processor is worth paying")
+	ErrPaymentDeferredTooLong        = errors.New("payment deferred too many times")
+	ErrCircuitBreakerOpen            = errors.New("circuit breaker open")
+	ErrWorkerPoolSaturated           = errors.New("worker pool saturated")
+	ErrWorkerPoolClosed              = errors.New("worker pool shut down")
+	ErrPaymentHedged                 = errors.New("payment owned by another processor")
+	ErrRetryLeaseLost                = errors.New("retry lease ran out before a worker took it")
+	ErrTooManyPaymentsHeld           = errors.New("too many payments waiting for the retry queue")
 )
 
 func NewErrorWrapper(err error, message any) error {
@@ -0,0 +1,32 @@
+package constants
+
+import "time"
+
+const (
+	HealthStatusUp   = "up"
+	HealthStatusDown = "down"
+
+	// an instance with this many payments waiting in its intake queue stops
+	// reporting itself ready.
+	ReadinessMaxBacklog = 10000
+
+	HealthLeaderKey   = "health:leader"
+	HealthSnapshotKey = "health:snapshot"
+
+	// processors allow one health call every 5 seconds.
+	HealthCheckInterval = 5100 * time.Millisecond
+	// a processor whose health call takes longer is unreachable, well before
+	// the leader has to renew its lease.
+	HealthCheckTimeout = HealthCheckInterval / 2
+	// the leader renews its lease every check, followers take over once it expires.
+	HealthLeaderLease = 2 * HealthCheckInterval
+	// a snapshot nobody refreshed for this long is dropped instead of trusted.
+	HealthSnapshotTTL = 3 * HealthCheckInterval
+	// how often every instance reads the shared snapshot.
+	HealthSnapshotRefresh = 500 * time.Millisecond
+	// a dependency slower than this to answer a ping is reported as down.
+	HealthPingTimeout = time.Second
+	// the default processor is treated as failing while its health reports a
+	// minimum response time this slow.
+	HealthSlowDefaultResponseTime = 50 * time.Millisecond
+)
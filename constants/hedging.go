@@ -0,0 +1,12 @@
+package constants
+
+import "time"
+
+const (
+	// DefaultHedgingFactor times the processor minimum response time is how
+	// long a payment waits for it before being hedged.
+	DefaultHedgingFactor = 3.0
+	// DefaultHedgingMinBudget keeps a fast processor from being hedged on
+	// every hiccup.
+	DefaultHedgingMinBudget = 100 * time.Millisecond
+)
@@ -0,0 +1,30 @@
+package constants
+
+import "time"
+
+const (
+	PaymentQueueBackendRedis = "redis"
+	PaymentQueueBackendFile  = "file"
+
+	PaymentQueueStreamPrefix = "payments:intake:"
+	PaymentRetryQueueKey     = "payments:retry"
+	PaymentDeadLetterKey     = "payments:dead-letter"
+
+	DefaultPaymentMaxAttempts = 5
+	DefaultPaymentRetryDelay  = 500 * time.Millisecond
+	MaxPaymentRetryDelay      = 10 * time.Second
+	PaymentRetryPollInterval  = 200 * time.Millisecond
+	PaymentRetryBatchSize     = 50
+	// a claimed retry not acknowledged by then is due again, its claimer
+	// being presumed dead.
+	PaymentRetryLease = 30 * time.Second
+	// payments neither the worker pool nor the retry queue took are kept in
+	// memory up to this many, new ones are turned away from then on.
+	MaxHeldPayments = 1000
+	// a payment put off this many times, about a minute at the default retry
+	// delay, is dead-lettered instead.
+	MaxPaymentDeferrals = 120
+	// the status of a payment keeps its latest transitions only, a payment
+	// deferred over and over would otherwise grow it for every attempt.
+	PaymentStatusHistoryLimit = 32
+)
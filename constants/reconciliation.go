@@ -0,0 +1,15 @@
+package constants
+
+import "time"
+
+const (
+	DefaultReconciliationInterval = time.Minute
+	DefaultReconciliationWindow   = 5 * time.Minute
+	DefaultReconciliationBucket   = 5 * time.Second
+
+	// leave out the last moments, payments there may be processed but not saved yet.
+	ReconciliationSettleDelay = 2 * time.Second
+
+	ReconciliationTriggerScheduled = "scheduled"
+	ReconciliationTriggerManual    = "manual"
+)
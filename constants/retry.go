@@ -0,0 +1,22 @@
+package constants
+
+import "time"
+
+const (
+	// RetryJitterNone waits the exponential delay as it is.
+	RetryJitterNone = "none"
+	// RetryJitterFull waits anything up to the exponential delay.
+	RetryJitterFull = "full"
+	// RetryJitterEqual waits at least half of the exponential delay.
+	RetryJitterEqual = "equal"
+	// RetryJitterDecorrelated waits up to three times the previous delay, so
+	// callers that failed together drift apart.
+	RetryJitterDecorrelated = "decorrelated"
+
+	// the processor client retries a payment request this much before the
+	// payment attempt fails.
+	PaymentRequestMaxAttempts = 5
+	PaymentRequestBaseDelay   = 5 * time.Millisecond
+	PaymentRequestMaxDelay    = 250 * time.Millisecond
+	PaymentRequestMaxElapsed  = 2 * time.Second
+)
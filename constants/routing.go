@@ -0,0 +1,29 @@
+package constants
+
+import "time"
+
+const (
+	PaymentRoutingPriority       = "priority"
+	PaymentRoutingLowestFee      = "lowest-fee"
+	PaymentRoutingLowestLatency  = "lowest-latency"
+	PaymentRoutingWeightedRandom = "weighted-random"
+	PaymentRoutingNetRevenue     = "net-revenue"
+
+	DefaultProcessorFee  = 0.05
+	FallbackProcessorFee = 0.15
+
+	// a failed call counts as this slow when ranking by latency.
+	RoutingFailureLatency = DefaultRequestTimeout
+	// weight of the newest sample in the latency and failure moving averages, in percent.
+	RoutingSmoothing = 20
+	// the failure probability of a route halves every this long without failures.
+	RoutingRecoveryHalfLife = 5 * time.Second
+
+	// share of the amount lost per second a payment takes, see PAYMENT_ROUTING_LATENCY_COST.
+	DefaultRoutingLatencyCost = 0.01
+	// share of the amount lost by putting a payment off until the cheapest
+	// processor recovers; above the fee difference it never pays to wait.
+	DefaultRoutingWaitCost = 0.12
+
+	RoutingDeferred = "deferred"
+)
@@ -0,0 +1,12 @@
+package constants
+
+import "time"
+
+const (
+	// DefaultShutdownTimeout stays under the ten seconds docker waits before
+	// killing the container.
+	DefaultShutdownTimeout = 8 * time.Second
+	// DefaultShutdownGrace is how long readiness fails before the server stops
+	// accepting connections, for the load balancer to notice.
+	DefaultShutdownGrace = time.Second
+)
@@ -15,6 +15,7 @@ const (
 	HTTPStatusNotAcceptable          = http.StatusNotAcceptable
 	HTTPStatusTooManyRequests        = http.StatusTooManyRequests
 	HTTPStatusInternalServerError    = http.StatusInternalServerError
+	HTTPStatusServiceUnavailable     = http.StatusServiceUnavailable
 	HTTPStatusForbidden              = http.StatusForbidden
 	HTTPStatusUnprocessableEntity    = http.StatusUnprocessableEntity
 	HTTPStatusNotFound               = http.StatusNotFound
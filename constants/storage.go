@@ -0,0 +1,30 @@
+package constants
+
+import "time"
+
+const (
+	StorageBackendRedis     = "redis"
+	StorageBackendHazelcast = "hazelcast"
+	StorageBackendPostgres  = "postgres"
+	StorageBackendMemory    = "memory"
+
+	// StorageModeSingle uses only the primary backend.
+	StorageModeSingle = "single"
+	// StorageModeWriteThrough saves to both backends and reads from the primary.
+	StorageModeWriteThrough = "write-through"
+	// StorageModeReadFallback reads from the secondary when the primary fails.
+	StorageModeReadFallback = "read-fallback"
+	// StorageModeShadow mirrors writes to the secondary in the background, for
+	// migrating between backends, and never lets it affect the result.
+	StorageModeShadow = "shadow"
+
+	HazelcastPaymentsMap = "payments"
+
+	// saves waiting to be mirrored to the shadow backend, more are dropped
+	ShadowSaveQueueSize = 1024
+
+	// write-through retries the secondary save this much before failing it
+	SecondarySaveMaxAttempts = 3
+	SecondarySaveBaseDelay   = 10 * time.Millisecond
+	SecondarySaveMaxDelay    = 100 * time.Millisecond
+)
@@ -6,4 +6,8 @@ const (
 	DefaultRequestTimeout = 5 * time.Second
 	MaxAttemptsBeforeOpen = 5
 	RecoveryTimeout       = 10 * time.Second
+	PaymentEntryTTL       = 20 * time.Minute
+	// summaries read buckets up to this far ahead of the local clock, for
+	// payments saved by an instance whose clock runs ahead.
+	PaymentClockSkew = time.Minute
 )
@@ -0,0 +1,20 @@
+package constants
+
+import "time"
+
+const (
+	// WorkerPoolOverflowBlock makes submissions wait for room up to a deadline.
+	WorkerPoolOverflowBlock = "block"
+	// WorkerPoolOverflowReject turns payments away while the pool is full.
+	WorkerPoolOverflowReject = "reject"
+	// WorkerPoolOverflowSpill accepts payments the pool cannot take and leaves
+	// them in the retry queue until workers are free.
+	WorkerPoolOverflowSpill = "spill"
+
+	DefaultWorkerPoolWorkers = 10
+	// tasks waiting for a worker, per worker.
+	DefaultWorkerPoolQueueFactor  = 2
+	DefaultWorkerPoolBlockTimeout = 50 * time.Millisecond
+	// how long rejected clients are told to wait before trying again.
+	WorkerPoolRetryAfter = time.Second
+)
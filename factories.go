@@ -1,52 +1,432 @@
 package main
 
 import (
+	"errors"
+	"log"
+
+	"github.com/google/uuid"
 	"github.com/marincor/rinha-de-backend-2025-marincor-golang/config"
 	"github.com/marincor/rinha-de-backend-2025-marincor-golang/constants"
 	healthcheck "github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/application/usecases/health_check"
+	listcircuitbreakers "github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/application/usecases/list_circuit_breakers"
+	managedeadletters "github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/application/usecases/manage_dead_letters"
 	processpayment "github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/application/usecases/process_payment"
+	reconcilepayments "github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/application/usecases/reconcile_payments"
+	retrievepaymentstatus "github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/application/usecases/retrieve_payment_status"
 	retrievepaymentsummary "github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/application/usecases/retrieve_payment_summary"
 	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/contracts"
 	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/entities"
 	circuitbreaker "github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/infra/circuit_breaker"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/infra/clients/hazelcast"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/infra/clients/memory"
 	paymentprocessor "github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/infra/clients/payment_processor"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/infra/clients/postgres"
 	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/infra/clients/redis"
+	concurrencylimiter "github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/infra/concurrency_limiter"
+	filequeue "github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/infra/file_queue"
+	healthmonitor "github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/infra/health_monitor"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/infra/metrics"
+	paymentrouter "github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/infra/payment_router"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/infra/storage"
+	circuitbreakercontroller "github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/presentation/controllers/circuit_breaker"
+	deadlettercontroller "github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/presentation/controllers/dead_letter"
 	healthcontroller "github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/presentation/controllers/health"
+	metricscontroller "github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/presentation/controllers/metrics"
 	paymentcontroller "github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/presentation/controllers/payment"
+	reconciliationcontroller "github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/presentation/controllers/reconciliation"
 )
 
-func makeHealthController() *healthcontroller.Controller {
-	healthUseCase := healthcheck.NewUseCase()
+// makeHealthUseCase is kept apart from its controller, shutdown turns the
+// readiness it reports off.
+func makeHealthUseCase(
+	dependencies *dependencies,
+	workerPool contracts.WorkerPoolManager,
+) *healthcheck.UseCase {
+	return healthcheck.NewUseCase(
+		dependencies.healthMonitor,
+		dependencies.paymentStorage,
+		dependencies.circuitBreakers,
+		workerPool,
+		dependencies.paymentQueue,
+		dependencies.retryQueue,
+		constants.ReadinessMaxBacklog,
+	)
+}
 
+func makeHealthController(healthUseCase *healthcheck.UseCase) *healthcontroller.Controller {
 	return healthcontroller.NewController(healthUseCase)
 }
 
-func makePaymentController(config *config.Config, workerPool contracts.WorkerPoolManager) *paymentcontroller.Controller {
-	defaultPaymentProcessor := paymentprocessor.New(config.PaymentProcessorDefault, entities.Default)
-	secondaryPaymentProcessor := paymentprocessor.New(config.PaymentProcessorFallback, entities.Fallback)
+// dependencies are shared by several controllers: processors share a single
+// health monitor and an in-memory storage only works as a single instance.
+type dependencies struct {
+	paymentProcessors   map[entities.ProcessorProvider]contracts.PaymentProcessor
+	concurrencyLimiters map[entities.ProcessorProvider]contracts.ConcurrencyLimiter
+	paymentRouter       *paymentrouter.Router
+	healthMonitor       *healthmonitor.Monitor
+	metrics             *metrics.Registry
+	paymentStorage      contracts.Storage
+	paymentStatusStore  contracts.PaymentStatusStore
+	paymentQueue        contracts.PaymentQueue
+	retryQueue          contracts.RetryQueue
+	deadLetterStore     contracts.DeadLetterStore
+	circuitBreakers     map[string]contracts.CircuitBreakerObserver
+}
+
+func makeDependencies(config *config.Config) *dependencies {
+	paymentProcessors := map[entities.ProcessorProvider]contracts.PaymentProcessor{}
+	concurrencyLimiters := map[entities.ProcessorProvider]contracts.ConcurrencyLimiter{}
+
+	healthMonitor := makeHealthMonitor(config)
+
+	for _, processorConfig := range config.PaymentProcessors {
+		limiter := concurrencylimiter.New(config.ConcurrencyLimit)
+		processor := paymentprocessor.New(
+			processorConfig.URL, config.RinhaToken, processorConfig.Name, healthMonitor, limiter,
+		)
+
+		paymentProcessors[processorConfig.Name] = processor
+		concurrencyLimiters[processorConfig.Name] = limiter
+		healthMonitor.Register(processorConfig.Name, processor)
+	}
+
+	registry := metrics.New()
+	paymentCircuitBreakers := makePaymentCircuitBreakers(config)
+
+	circuitBreakers := make(map[string]contracts.CircuitBreakerObserver, len(paymentCircuitBreakers))
+
+	for name, circuitBreaker := range paymentCircuitBreakers {
+		circuitBreaker.Subscribe(logCircuitBreakerTransition(string(name)))
+		circuitBreakers[string(name)] = circuitBreaker
+	}
+
+	return &dependencies{
+		paymentProcessors:   paymentProcessors,
+		concurrencyLimiters: concurrencyLimiters,
+		paymentRouter:       makePaymentRouter(config, paymentProcessors, paymentCircuitBreakers, registry),
+		healthMonitor:       healthMonitor,
+		metrics:             registry,
+		paymentStorage:      makePaymentStorage(config),
+		paymentStatusStore:  makePaymentStatusStore(config),
+		paymentQueue:        makePaymentQueue(config),
+		retryQueue:          makeRetryQueue(config),
+		deadLetterStore:     makeDeadLetterStore(config),
+		circuitBreakers:     circuitBreakers,
+	}
+}
+
+// makeHealthMonitor elects a single instance to poll the processors health;
+// the lease holder is unique per process so equal hostnames do not collide.
+func makeHealthMonitor(config *config.Config) *healthmonitor.Monitor {
+	if inProcess(config) {
+		return healthmonitor.New(
+			memory.NewLeaderElector(),
+			memory.NewHealthSnapshotStore(constants.HealthSnapshotTTL),
+			constants.HealthCheckInterval,
+			constants.HealthSnapshotRefresh,
+		)
+	}
 
-	paymentCircuitBreaker := circuitbreaker.New[*entities.PaymentResponse](
-		constants.MaxAttemptsBeforeOpen, constants.RecoveryTimeout,
+	return healthmonitor.New(
+		redis.NewLeaderElector(constants.HealthLeaderKey, config.InstanceName+"-"+uuid.NewString(), constants.HealthLeaderLease),
+		redis.NewHealthSnapshotStore(constants.HealthSnapshotKey, constants.HealthSnapshotTTL),
+		constants.HealthCheckInterval,
+		constants.HealthSnapshotRefresh,
 	)
+}
+
+// makePaymentCircuitBreakers gives each processor its own breaker, named after
+// it. Distributed ones are shared through Redis, every other mode keeps the
+// breaker per instance.
+func makePaymentCircuitBreakers(
+	config *config.Config,
+) map[entities.ProcessorProvider]contracts.CircuitBreaker[*entities.PaymentResponse] {
+	circuitBreakers := make(
+		map[entities.ProcessorProvider]contracts.CircuitBreaker[*entities.PaymentResponse],
+		len(config.PaymentProcessors),
+	)
+
+	var store contracts.CircuitBreakerStore
+
+	for _, processorConfig := range config.PaymentProcessors {
+		policy := config.CircuitBreakers[processorConfig.Name]
+		policy.Uncounted = ownedElsewhere
+
+		if policy.Mode != constants.CircuitBreakerModeDistributed {
+			circuitBreakers[processorConfig.Name] = circuitbreaker.NewFromPolicy[*entities.PaymentResponse](policy)
+
+			continue
+		}
 
-	// paymentStorage := hazelcast.New("payments")
+		if store == nil {
+			store = redis.NewCircuitBreakerStore()
+		}
 
-	paymentStorage := redis.New()
+		circuitBreakers[processorConfig.Name] = circuitbreaker.NewDistributed[*entities.PaymentResponse](
+			string(processorConfig.Name), store, policy,
+		)
+	}
 
+	return circuitBreakers
+}
+
+// ownedElsewhere tells a call gave up because another processor took the
+// hedged payment, which says nothing of the one called.
+func ownedElsewhere(err error) bool {
+	return errors.Is(err, constants.ErrPaymentHedged)
+}
+
+// followConcurrencyLimits keeps as many workers busy as the processors take
+// calls at once, so payments wait in the queue rather than on a limiter.
+func followConcurrencyLimits(dependencies *dependencies, workerPool contracts.WorkerPoolManager) {
+	limits := dependencies.metrics.Gauge("processor_concurrency_limit",
+		"Calls a processor may have in flight.", "processor")
+
+	adjust := func() {
+		total := 0
+
+		for name, limiter := range dependencies.concurrencyLimiters {
+			limit := limiter.Limit()
+			total += limit
+
+			limits.Set(float64(limit), string(name))
+		}
+
+		workerPool.SetParallelism(total)
+	}
+
+	for _, limiter := range dependencies.concurrencyLimiters {
+		limiter.Subscribe(func(int) { adjust() })
+	}
+
+	adjust()
+}
+
+func logCircuitBreakerTransition(name string) func(*entities.CircuitBreakerTransition) {
+	return func(transition *entities.CircuitBreakerTransition) {
+		go log.Print(
+			map[string]interface{}{
+				"message":         "circuit breaker state changed",
+				"circuit_breaker": name,
+				"from":            constants.CircuitBreakerStateName(transition.From),
+				"to":              constants.CircuitBreakerStateName(transition.To),
+				"reason":          transition.Reason,
+				"failures":        transition.Failures,
+			},
+		)
+	}
+}
+
+func makePaymentRouter(
+	config *config.Config,
+	paymentProcessors map[entities.ProcessorProvider]contracts.PaymentProcessor,
+	circuitBreakers map[entities.ProcessorProvider]contracts.CircuitBreaker[*entities.PaymentResponse],
+	registry *metrics.Registry,
+) *paymentrouter.Router {
+	strategy, err := paymentrouter.NewStrategy(config.PaymentRoutingStrategy, paymentrouter.CostModel{
+		LatencyCost: config.PaymentRoutingLatencyCost,
+		WaitCost:    config.PaymentRoutingWaitCost,
+	})
+	if err != nil {
+		log.Fatal(
+			map[string]interface{}{
+				"message": "error building payment router",
+				"error":   err,
+			},
+		)
+	}
+
+	routes := make([]*paymentrouter.Route, 0, len(config.PaymentProcessors))
+	for _, processorConfig := range config.PaymentProcessors {
+		routes = append(routes, paymentrouter.NewRoute(
+			processorConfig,
+			paymentProcessors[processorConfig.Name],
+			circuitBreakers[processorConfig.Name],
+		))
+	}
+
+	return paymentrouter.New(strategy, registry, routes...)
+}
+
+func makePaymentController(
+	config *config.Config,
+	dependencies *dependencies,
+	workerPool contracts.WorkerPoolManager,
+) *paymentcontroller.Controller {
 	paymentUseCase := processpayment.NewUseCase(
-		defaultPaymentProcessor, secondaryPaymentProcessor,
-		paymentCircuitBreaker,
-		paymentStorage,
+		dependencies.paymentRouter,
+		dependencies.paymentStorage,
+		dependencies.paymentQueue,
+		dependencies.retryQueue,
+		dependencies.deadLetterStore,
+		dependencies.paymentStatusStore,
+		config.PaymentMaxAttempts,
+		config.PaymentRetryDelay,
+		dependencies.healthMonitor,
+		config.PaymentHedging,
 	)
 
-	paymentSummaryUseCase := retrievepaymentsummary.NewUseCase(
-		defaultPaymentProcessor, secondaryPaymentProcessor,
-		paymentStorage,
-	)
+	paymentSummaryUseCase := retrievepaymentsummary.NewUseCase(dependencies.paymentStorage)
+
+	paymentStatusUseCase := retrievepaymentstatus.NewUseCase(dependencies.paymentStatusStore)
 
 	return paymentcontroller.NewController(
 		paymentUseCase,
 		paymentSummaryUseCase,
+		paymentStatusUseCase,
 		workerPool,
+		config.WorkerPoolRejectStatus,
+	)
+}
+
+func makeMetricsController(
+	dependencies *dependencies,
+	workerPool contracts.WorkerPoolManager,
+) *metricscontroller.Controller {
+	dependencies.metrics.GaugeFunc("worker_pool_queue_depth",
+		"Tasks waiting for a worker.", func() float64 { return float64(workerPool.QueueDepth()) })
+	dependencies.metrics.GaugeFunc("worker_pool_capacity",
+		"Tasks the worker pool holds before overflowing.", func() float64 { return float64(workerPool.Capacity()) })
+	dependencies.metrics.GaugeFunc("worker_pool_parallelism",
+		"Workers taking tasks.", func() float64 { return float64(workerPool.Parallelism()) })
+
+	return metricscontroller.NewController(dependencies.metrics)
+}
+
+func makeCircuitBreakerController(dependencies *dependencies) *circuitbreakercontroller.Controller {
+	return circuitbreakercontroller.NewController(listcircuitbreakers.NewUseCase(dependencies.circuitBreakers))
+}
+
+func makeDeadLetterController(dependencies *dependencies) *deadlettercontroller.Controller {
+	manageDeadLettersUseCase := managedeadletters.NewUseCase(
+		dependencies.deadLetterStore,
+		dependencies.retryQueue,
+		dependencies.paymentStatusStore,
+	)
+
+	return deadlettercontroller.NewController(manageDeadLettersUseCase)
+}
+
+func makeReconciliationController(config *config.Config, dependencies *dependencies) *reconciliationcontroller.Controller {
+	reconcilePaymentsUseCase := reconcilepayments.NewUseCase(
+		dependencies.paymentProcessors,
+		dependencies.paymentStorage,
+		config.ReconciliationWindow,
+		config.ReconciliationBucket,
+	)
+
+	return reconciliationcontroller.NewController(reconcilePaymentsUseCase)
+}
+
+// makePaymentStorage picks where the payments and their claims are kept; the
+// status, retries and dead letters stay on redis for every backend but memory,
+// see inProcess.
+func makePaymentStorage(config *config.Config) contracts.Storage {
+	paymentStorage, err := makeStorageRegistry(config).Build(
+		config.StorageMode, config.StorageBackend, config.StorageSecondaryBackend,
+	)
+	if err != nil {
+		log.Fatal(
+			map[string]interface{}{
+				"message": "error building payment storage",
+				"error":   err,
+			},
+		)
+	}
+
+	return paymentStorage
+}
+
+func makeStorageRegistry(config *config.Config) *storage.Registry {
+	registry := storage.NewRegistry()
+
+	factories := map[string]storage.Factory{
+		constants.StorageBackendRedis: func() contracts.Storage {
+			paymentStorage := redis.New()
+
+			if config.RedisMigrateLegacyKeys {
+				go migrateLegacyKeys(paymentStorage)
+			}
+
+			return paymentStorage
+		},
+		constants.StorageBackendHazelcast: func() contracts.Storage {
+			return hazelcast.New(constants.HazelcastPaymentsMap)
+		},
+		constants.StorageBackendPostgres: func() contracts.Storage {
+			return postgres.New()
+		},
+		constants.StorageBackendMemory: func() contracts.Storage {
+			return memory.New()
+		},
+	}
+
+	for name, factory := range factories {
+		if err := registry.Register(name, factory); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	return registry
+}
+
+func makePaymentQueue(config *config.Config) contracts.PaymentQueue {
+	if config.PaymentQueueBackend == constants.PaymentQueueBackendFile {
+		return filequeue.New(config.PaymentQueuePath)
+	}
+
+	return redis.NewQueue(constants.PaymentQueueStreamPrefix + config.InstanceName)
+}
+
+// inProcess tells the instance runs on its own: with a memory storage nothing
+// is shared, so the stores the instances otherwise share through Redis live in
+// the process too.
+func inProcess(config *config.Config) bool {
+	return config.StorageBackend == constants.StorageBackendMemory
+}
+
+func makePaymentStatusStore(config *config.Config) contracts.PaymentStatusStore {
+	if inProcess(config) {
+		return memory.NewPaymentStatusStore()
+	}
+
+	return redis.NewPaymentStatusStore()
+}
+
+func makeRetryQueue(config *config.Config) contracts.RetryQueue {
+	if inProcess(config) {
+		return memory.NewRetryQueue()
+	}
+
+	return redis.NewRetryQueue(constants.PaymentRetryQueueKey)
+}
+
+func makeDeadLetterStore(config *config.Config) contracts.DeadLetterStore {
+	if inProcess(config) {
+		return memory.NewDeadLetterStore()
+	}
+
+	return redis.NewDeadLetterStore(constants.PaymentDeadLetterKey)
+}
+
+func migrateLegacyKeys(paymentStorage *redis.Client) {
+	migrated, err := paymentStorage.MigrateLegacyKeys()
+	if err != nil {
+		log.Print(
+			map[string]interface{}{
+				"message":  "error migrating legacy redis keys",
+				"migrated": migrated,
+				"error":    err,
+			},
+		)
+
+		return
+	}
+
+	log.Print(
+		map[string]interface{}{
+			"message":  "legacy redis keys migrated",
+			"migrated": migrated,
+		},
 	)
 }
@@ -1,51 +1,197 @@
 package helpers
 
 import (
-	"crypto/rand"
+	"context"
+	"fmt"
+	"math"
+	"math/rand/v2"
 	"time"
+
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/constants"
 )
 
 type CallbackFunc[T any] func() (T, error)
 
-func ExponentialBackoffRetry[T any](callback CallbackFunc[T], maxRetries int, initialDelay time.Duration, multiplier int, randomInt int) (T, error) {
-	var attempt int
+// ErrorClass tells a RetryPolicy what an attempt error means.
+type ErrorClass int
+
+const (
+	// ErrorRetryable may go away on another attempt.
+	ErrorRetryable ErrorClass = iota
+	// ErrorTerminal will not, the policy gives up right away.
+	ErrorTerminal
+	// ErrorAlreadyDone means an earlier attempt went through after all, the
+	// attempt result is returned as a success.
+	ErrorAlreadyDone
+)
+
+// RetryPolicy retries with an exponential backoff from BaseDelay up to
+// MaxDelay, growing by Multiplier, or doubling when it is not set, and spread
+// by one of the constants.RetryJitter* strategies. It gives up after
+// MaxAttempts, or rather than wait past MaxElapsed since the first attempt,
+// returning the last error; zero leaves either unbounded.
+type RetryPolicy struct {
+	// Classify tells what an attempt error means, every error is retryable
+	// when it is nil.
+	Classify func(err error) ErrorClass
+	// OnRetry is called with the failed attempt, from 1, before waiting for
+	// the next one.
+	OnRetry    func(attempt int, err error, delay time.Duration)
+	Jitter     string
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+	MaxElapsed time.Duration
+	// MaxJitter adds a random wait, in whole milliseconds under it, on top of
+	// every delay.
+	MaxJitter   time.Duration
+	Multiplier  int
+	MaxAttempts int
+}
+
+// ExponentialBackoffRetry makes at most maxRetries calls, waiting initialDelay
+// after the first one and multiplier times longer after each next one, plus a
+// random jitter under randomInt milliseconds.
+func ExponentialBackoffRetry[T any](
+	callback CallbackFunc[T],
+	maxRetries int,
+	initialDelay time.Duration,
+	multiplier int,
+	randomInt int,
+) (T, error) {
+	return Retry(context.Background(), &RetryPolicy{
+		BaseDelay:   initialDelay,
+		MaxJitter:   time.Duration(randomInt) * time.Millisecond,
+		Multiplier:  multiplier,
+		MaxAttempts: max(maxRetries, 1),
+	}, callback)
+}
+
+// random variation expected between attempts.
+func generateJitter(randomInt int) time.Duration {
+	if randomInt <= 0 {
+		return 0
+	}
+
+	return time.Duration(rand.IntN(randomInt)) * time.Millisecond
+}
+
+// Retry calls the callback until it succeeds or the policy gives up. Once the
+// context is done no attempt is made anymore, the error then wraps its cause.
+func Retry[T any](ctx context.Context, policy *RetryPolicy, callback CallbackFunc[T]) (T, error) {
+	var zero T
+
+	startedAt := time.Now()
+	delay := policy.BaseDelay
+
+	for attempt := 1; ; attempt++ {
+		if ctx.Err() != nil {
+			return zero, context.Cause(ctx)
+		}
 
-	for {
 		result, err := callback()
 		if err == nil {
 			return result, nil
 		}
 
-		if attempt >= maxRetries-1 {
-			var zero T
+		switch policy.classify(err) {
+		case ErrorAlreadyDone:
+			return result, nil
+		case ErrorTerminal:
+			return zero, err
+		case ErrorRetryable:
+		}
+
+		if policy.MaxAttempts > 0 && attempt >= policy.MaxAttempts {
+			return zero, err
+		}
+
+		delay = policy.backoff(attempt, delay)
+		wait := delay + generateJitter(int(policy.MaxJitter/time.Millisecond))
 
+		if policy.MaxElapsed > 0 && time.Since(startedAt)+wait > policy.MaxElapsed {
 			return zero, err
 		}
 
-		delay := initialDelay * time.Duration(multiplier<<attempt) // 2^attempt
+		if policy.OnRetry != nil {
+			policy.OnRetry(attempt, err, wait)
+		}
 
-		jitter := generateJitter(randomInt)
+		timer := time.NewTimer(wait)
 
-		totalDelay := delay + jitter
+		select {
+		case <-ctx.Done():
+			timer.Stop()
 
-		time.Sleep(totalDelay)
+			return zero, fmt.Errorf("%w: %w", context.Cause(ctx), err)
+		case <-timer.C:
+		}
+	}
+}
 
-		attempt++
+func (policy *RetryPolicy) classify(err error) ErrorClass {
+	if policy.Classify == nil {
+		return ErrorRetryable
 	}
+
+	return policy.Classify(err)
 }
 
-// random variation expected between attempts.
-func generateJitter(randomInt int) time.Duration {
-	necessaryAmountOfBytes := 1
-	randomValue := make([]byte, necessaryAmountOfBytes)
-	randomByte := randomInt
+// backoff is the wait after the attempt, previous being the last wait.
+func (policy *RetryPolicy) backoff(attempt int, previous time.Duration) time.Duration {
+	exponential := policy.exponential(attempt)
+
+	switch policy.Jitter {
+	case constants.RetryJitterFull:
+		return randomDuration(0, exponential)
+	case constants.RetryJitterEqual:
+		return exponential/2 + randomDuration(0, exponential-exponential/2)
+	case constants.RetryJitterDecorrelated:
+		return policy.bound(randomDuration(policy.BaseDelay, 3*min(previous, math.MaxInt64/3)))
+	default:
+		return exponential
+	}
+}
+
+// exponential is BaseDelay grown by the multiplier after every attempt, capped
+// at MaxDelay, or at the longest duration without one, before it could overflow.
+func (policy *RetryPolicy) exponential(attempt int) time.Duration {
+	limit := time.Duration(math.MaxInt64)
+	if policy.MaxDelay > 0 {
+		limit = policy.MaxDelay
+	}
 
-	if _, err := rand.Read(randomValue); err == nil {
-		randomByte = int(randomValue[0])
+	multiplier := time.Duration(policy.Multiplier)
+	if multiplier < 1 {
+		multiplier = 2
 	}
 
-	// limit bytes between 0 and randomInt -1 (because of % operator)
-	jitter := time.Duration(randomByte%randomInt) * time.Second
+	delay := min(policy.BaseDelay, limit)
+
+	for range attempt - 1 {
+		if delay > limit/multiplier {
+			return limit
+		}
+
+		delay *= multiplier
+	}
+
+	return delay
+}
+
+// bound caps the delay at MaxDelay.
+func (policy *RetryPolicy) bound(delay time.Duration) time.Duration {
+	if policy.MaxDelay > 0 && delay > policy.MaxDelay {
+		return policy.MaxDelay
+	}
+
+	return delay
+}
+
+// randomDuration is uniform in [low, high), low when the range is empty.
+func randomDuration(low, high time.Duration) time.Duration {
+	if high <= low {
+		return low
+	}
 
-	return jitter
+	return low + rand.N(high-low)
 }
@@ -2,11 +2,16 @@
 package helpers
 
 import (
+	"context"
 	"errors"
 	"testing"
 	"time"
+
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/constants"
 )
 
+var errAlreadyDone = errors.New("already done")
+
 func TestExponentialBackoffRetrySuccessImmediately(t *testing.T) {
 	callCount := 0
 	callback := func() (string, error) {
@@ -75,8 +80,215 @@ func TestGenerateJitterReturnsWithinExpectedRange(t *testing.T) {
 	//nolint:intrange // false positive
 	for i := 0; i < 100; i++ {
 		j := generateJitter(maxNumber)
-		if j < 0 || j > time.Duration(maxNumber-1)*time.Second {
+		if j < 0 || j > time.Duration(maxNumber-1)*time.Millisecond {
 			t.Errorf("jitter fora do intervalo esperado: %s", j)
 		}
 	}
 }
+
+func TestRetrySuccessImmediately(t *testing.T) {
+	callCount := 0
+	callback := func() (string, error) {
+		callCount++
+		return "success", nil
+	}
+
+	result, err := Retry(context.Background(), &RetryPolicy{MaxAttempts: 3, BaseDelay: 10 * time.Millisecond}, callback)
+	if err != nil {
+		t.Errorf("esperava sucesso, mas retornou erro: %v", err)
+	}
+
+	if result != "success" {
+		t.Errorf("resultado inesperado: %s", result)
+	}
+
+	if callCount != 1 {
+		t.Errorf("esperado 1 chamada, obtido %d", callCount)
+	}
+}
+
+func TestRetrySuccessAfterRetries(t *testing.T) {
+	callCount := 0
+	callback := func() (string, error) {
+		callCount++
+		if callCount < 3 {
+			return "", errors.ErrUnsupported
+		}
+		return "ok", nil
+	}
+
+	var hooked []int
+
+	policy := &RetryPolicy{
+		MaxAttempts: 5,
+		BaseDelay:   5 * time.Millisecond,
+		Jitter:      constants.RetryJitterFull,
+		OnRetry: func(attempt int, err error, delay time.Duration) {
+			hooked = append(hooked, attempt)
+		},
+	}
+
+	result, err := Retry(context.Background(), policy, callback)
+	if err != nil {
+		t.Errorf("esperado sucesso, obteve erro: %v", err)
+	}
+
+	if result != "ok" {
+		t.Errorf("esperado 'ok', obteve '%s'", result)
+	}
+
+	if callCount != 3 {
+		t.Errorf("esperado 3 tentativas, obteve %d", callCount)
+	}
+
+	if len(hooked) != 2 || hooked[0] != 1 || hooked[1] != 2 {
+		t.Errorf("hook chamado nas tentativas erradas: %v", hooked)
+	}
+}
+
+func TestRetryFailureAfterMaxAttempts(t *testing.T) {
+	callback := func() (int, error) {
+		return 0, errors.ErrUnsupported
+	}
+
+	start := time.Now()
+	_, err := Retry(context.Background(), &RetryPolicy{MaxAttempts: 3, BaseDelay: 5 * time.Millisecond}, callback)
+	elapsed := time.Since(start)
+
+	if err == nil {
+		t.Error("esperado erro, mas foi nil")
+	}
+
+	if elapsed < 15*time.Millisecond {
+		t.Error("parece que não esperou entre tentativas")
+	}
+}
+
+func TestRetryFollowsTheErrorClass(t *testing.T) {
+	policy := &RetryPolicy{
+		MaxAttempts: 5,
+		BaseDelay:   time.Millisecond,
+		Classify: func(err error) ErrorClass {
+			if errors.Is(err, errAlreadyDone) {
+				return ErrorAlreadyDone
+			}
+
+			return ErrorTerminal
+		},
+	}
+
+	callCount := 0
+	_, err := Retry(context.Background(), policy, func() (int, error) {
+		callCount++
+		return 0, errors.ErrUnsupported
+	})
+
+	if !errors.Is(err, errors.ErrUnsupported) || callCount != 1 {
+		t.Errorf("erro terminal não deveria ser repetido: %v após %d tentativas", err, callCount)
+	}
+
+	result, err := Retry(context.Background(), policy, func() (int, error) {
+		return 422, errAlreadyDone
+	})
+
+	if err != nil || result != 422 {
+		t.Errorf("esperado sucesso com o resultado da tentativa, obteve %d, %v", result, err)
+	}
+}
+
+func TestRetryStopsOnCancellationAndElapsedBudget(t *testing.T) {
+	errCancelled := errors.New("cancelled")
+	ctx, cancel := context.WithCancelCause(context.Background())
+
+	time.AfterFunc(10*time.Millisecond, func() { cancel(errCancelled) })
+
+	start := time.Now()
+	_, err := Retry(ctx, &RetryPolicy{BaseDelay: time.Second}, func() (int, error) {
+		return 0, errors.ErrUnsupported
+	})
+
+	if !errors.Is(err, errCancelled) || !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("esperado erro com a causa do cancelamento, obteve %v", err)
+	}
+
+	if time.Since(start) > 500*time.Millisecond {
+		t.Error("não parou de esperar ao ser cancelado")
+	}
+
+	callCount := 0
+	_, err = Retry(context.Background(), &RetryPolicy{BaseDelay: 10 * time.Millisecond, MaxElapsed: 25 * time.Millisecond}, func() (int, error) {
+		callCount++
+		return 0, errors.ErrUnsupported
+	})
+
+	// waits 10ms then 20ms, which would go past the budget
+	if err == nil || callCount != 2 {
+		t.Errorf("esperado desistir após 2 tentativas, obteve %d", callCount)
+	}
+}
+
+func TestBackoffJitterStaysInMilliseconds(t *testing.T) {
+	base, maxDelay := 5*time.Millisecond, 100*time.Millisecond
+
+	for _, jitter := range []string{constants.RetryJitterFull, constants.RetryJitterEqual, constants.RetryJitterDecorrelated} {
+		policy := &RetryPolicy{Jitter: jitter, BaseDelay: base, MaxDelay: maxDelay}
+		previous := base
+
+		//nolint:intrange // false positive
+		for attempt := 1; attempt <= 100; attempt++ {
+			delay := policy.backoff(attempt, previous)
+			if delay < 0 || delay > maxDelay {
+				t.Errorf("jitter %s fora do intervalo esperado: %s", jitter, delay)
+			}
+
+			if jitter == constants.RetryJitterEqual && attempt == 1 && delay < base/2 {
+				t.Errorf("jitter equal abaixo da metade do atraso: %s", delay)
+			}
+
+			previous = delay
+		}
+	}
+}
+
+func TestBackoffDoesNotOverflowWithoutMaxDelay(t *testing.T) {
+	for _, jitter := range []string{"", constants.RetryJitterEqual, constants.RetryJitterDecorrelated} {
+		policy := &RetryPolicy{Jitter: jitter, BaseDelay: time.Second}
+		previous := policy.BaseDelay
+
+		for _, attempt := range []int{34, 35, 64, 100} {
+			delay := policy.backoff(attempt, previous)
+			if delay < time.Second {
+				t.Errorf("jitter %s: atraso %s na tentativa %d, o deslocamento estourou", jitter, delay, attempt)
+			}
+
+			previous = delay
+		}
+	}
+}
+
+func TestRetryGrowsByTheMultiplierAndAddsTheJitter(t *testing.T) {
+	var waits []time.Duration
+
+	policy := &RetryPolicy{
+		MaxAttempts: 4,
+		BaseDelay:   time.Millisecond,
+		Multiplier:  3,
+		MaxJitter:   2 * time.Millisecond,
+		OnRetry: func(attempt int, err error, delay time.Duration) {
+			waits = append(waits, delay)
+		},
+	}
+
+	_, err := Retry(context.Background(), policy, func() (int, error) {
+		return 0, errors.ErrUnsupported
+	})
+	if err == nil {
+		t.Error("esperado erro, mas foi nil")
+	}
+
+	for i, expected := range []time.Duration{time.Millisecond, 3 * time.Millisecond, 9 * time.Millisecond} {
+		if waits[i] < expected || waits[i] > expected+time.Millisecond {
+			t.Errorf("espera %d fora do intervalo esperado: %s", i+1, waits[i])
+		}
+	}
+}
@@ -1,7 +1,19 @@
-CREATE UNLOGGED TABLE payments (
+CREATE UNLOGGED TABLE IF NOT EXISTS payments (
     correlationId UUID PRIMARY KEY,
+    processor TEXT NOT NULL,
     amount DECIMAL NOT NULL,
     requested_at TIMESTAMP NOT NULL
 );
 
-CREATE INDEX payments_requested_at ON payments (requested_at);
\ No newline at end of file
+-- tables from before the processor column keep their rows out of summaries
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS processor TEXT;
+ALTER TABLE payments ALTER COLUMN processor TYPE TEXT;
+
+CREATE INDEX IF NOT EXISTS payments_requested_at ON payments (requested_at);
+
+CREATE UNLOGGED TABLE IF NOT EXISTS payment_claims (
+    correlationId UUID PRIMARY KEY,
+    claimed_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS payment_claims_claimed_at ON payment_claims (claimed_at);
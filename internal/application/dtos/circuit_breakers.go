@@ -0,0 +1,18 @@
+package dtos
+
+import "time"
+
+type CircuitBreaker struct {
+	Name        string                      `json:"name"`
+	State       string                      `json:"state"`
+	Transitions []*CircuitBreakerTransition `json:"transitions"`
+	Failures    int32                       `json:"failures"`
+}
+
+type CircuitBreakerTransition struct {
+	At       time.Time `json:"at"`
+	From     string    `json:"from"`
+	To       string    `json:"to"`
+	Reason   string    `json:"reason"`
+	Failures int32     `json:"failures"`
+}
@@ -0,0 +1,13 @@
+package dtos
+
+// DrainReport tells how the payments in flight wound down on shutdown.
+type DrainReport struct {
+	// Persisted payments were handed to the retry queue before any worker
+	// took them.
+	Persisted int `json:"persisted"`
+	// Failed payments could not be handed over; those accepted by this
+	// instance are still in its payment queue for the next boot.
+	Failed int `json:"failed"`
+	// Drained is false when the deadline passed with payments still running.
+	Drained bool `json:"drained"`
+}
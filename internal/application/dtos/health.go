@@ -1,7 +1,41 @@
 package dtos
 
-import "time"
+import (
+	"time"
+
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/entities"
+)
 
 type Health struct {
-	Sync *time.Time `json:"sync"`
+	Sync            *time.Time                                              `json:"sync"`
+	Processors      map[entities.ProcessorProvider]entities.ProcessorHealth `json:"processors"`
+	Storage         *DependencyHealth                                       `json:"storage,omitempty"`
+	CircuitBreakers map[string]*CircuitBreakerHealth                        `json:"circuitBreakers,omitempty"`
+	WorkerPool      *WorkerPoolHealth                                       `json:"workerPool,omitempty"`
+	Backlog         *BacklogHealth                                          `json:"backlog,omitempty"`
+	Status          string                                                  `json:"status"`
+	// Reasons lists why the instance is not ready, empty when it is.
+	Reasons []string `json:"reasons,omitempty"`
+}
+
+type DependencyHealth struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type CircuitBreakerHealth struct {
+	State    string `json:"state"`
+	Failures int32  `json:"failures"`
+}
+
+type WorkerPoolHealth struct {
+	QueueDepth int `json:"queueDepth"`
+	Capacity   int `json:"capacity"`
+}
+
+// BacklogHealth counts the payments accepted but not processed yet.
+type BacklogHealth struct {
+	Error   string `json:"error,omitempty"`
+	Intake  int    `json:"intake"`
+	Retries int    `json:"retries"`
 }
@@ -4,14 +4,21 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/entities"
 )
 
 type PaymentPayload struct {
-	CorrelationID uuid.UUID `json:"correlationId"`
-	Amount        float64   `json:"amount"`
+	CorrelationID uuid.UUID      `json:"correlationId"`
+	Amount        entities.Money `json:"amount"`
 }
 
 type PaymentSummaryFilters struct {
 	From *time.Time `query:"from"`
 	To   *time.Time `query:"to"`
 }
+
+type ReconciliationRequest struct {
+	From   *time.Time `query:"from"`
+	To     *time.Time `query:"to"`
+	Repair bool       `query:"repair"`
+}
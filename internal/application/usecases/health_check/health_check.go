@@ -1,23 +1,136 @@
 package healthcheck
 
 import (
+	"sync/atomic"
 	"time"
 
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/constants"
 	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/application/dtos"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/contracts"
 )
 
-// var errOutOfSync = errors.New("out of sync")
+const (
+	reasonStorageUnavailable = "storage unavailable"
+	reasonQueueUnavailable   = "payment queue unavailable"
+	reasonWorkerPoolFull     = "worker pool saturated"
+	reasonBacklogTooLarge    = "payment backlog too large"
+	reasonShuttingDown       = "shutting down"
+)
 
-type UseCase struct{}
+// UseCase reports the instance health. An instance is ready when it can still
+// take payments in: processors failing or an open breaker do not make it
+// unready, accepted payments wait in the queues until a processor is back.
+type UseCase struct {
+	processorHealthReader contracts.ProcessorHealthReader
+	paymentStorage        contracts.Storage
+	circuitBreakers       map[string]contracts.CircuitBreakerObserver
+	workerPool            contracts.WorkerPoolManager
+	paymentQueue          contracts.PaymentQueue
+	retryQueue            contracts.RetryQueue
+	// shared with the copies the controllers keep
+	draining   *atomic.Bool
+	maxBacklog int
+}
 
-func NewUseCase() *UseCase {
-	return &UseCase{}
+func NewUseCase(
+	processorHealthReader contracts.ProcessorHealthReader,
+	paymentStorage contracts.Storage,
+	circuitBreakers map[string]contracts.CircuitBreakerObserver,
+	workerPool contracts.WorkerPoolManager,
+	paymentQueue contracts.PaymentQueue,
+	retryQueue contracts.RetryQueue,
+	maxBacklog int,
+) *UseCase {
+	return &UseCase{
+		processorHealthReader: processorHealthReader,
+		paymentStorage:        paymentStorage,
+		circuitBreakers:       circuitBreakers,
+		workerPool:            workerPool,
+		paymentQueue:          paymentQueue,
+		retryQueue:            retryQueue,
+		draining:              &atomic.Bool{},
+		maxBacklog:            maxBacklog,
+	}
 }
 
-func (usecase *UseCase) Execute() (*dtos.Health, error) {
+// Drain makes the instance unready for good, it is shutting down.
+func (usecase *UseCase) Drain() {
+	usecase.draining.Store(true)
+}
+
+// Live only tells the process still answers.
+func (usecase *UseCase) Live() *dtos.Health {
 	now := time.Now()
 
 	return &dtos.Health{
-		Sync: &now,
-	}, nil
+		Sync:   &now,
+		Status: constants.HealthStatusUp,
+	}
+}
+
+// Execute checks every dependency; the status is down when the instance is
+// not ready, with the reasons listed. It is only advisory: nginx does not
+// poll it, it moves a payment to the other instance when this one answers
+// it with the reject status.
+func (usecase *UseCase) Execute() (*dtos.Health, error) {
+	now := time.Now()
+
+	health := &dtos.Health{
+		Sync:            &now,
+		Processors:      usecase.processorHealthReader.ProcessorsHealth(),
+		Storage:         &dtos.DependencyHealth{Status: constants.HealthStatusUp},
+		CircuitBreakers: make(map[string]*dtos.CircuitBreakerHealth, len(usecase.circuitBreakers)),
+		WorkerPool: &dtos.WorkerPoolHealth{
+			QueueDepth: usecase.workerPool.QueueDepth(),
+			Capacity:   usecase.workerPool.Capacity(),
+		},
+		Backlog: &dtos.BacklogHealth{},
+		Status:  constants.HealthStatusUp,
+	}
+
+	if usecase.draining.Load() {
+		health.Reasons = append(health.Reasons, reasonShuttingDown)
+	}
+
+	for name, circuitBreaker := range usecase.circuitBreakers {
+		health.CircuitBreakers[name] = &dtos.CircuitBreakerHealth{
+			State:    constants.CircuitBreakerStateName(circuitBreaker.GetState()),
+			Failures: circuitBreaker.GetCountFailure(),
+		}
+	}
+
+	if err := usecase.paymentStorage.Ping(); err != nil {
+		health.Storage = &dtos.DependencyHealth{Status: constants.HealthStatusDown, Error: err.Error()}
+		health.Reasons = append(health.Reasons, reasonStorageUnavailable)
+	}
+
+	// only the reject policy turns payments away while the pool is full, the
+	// others still accept them
+	if usecase.workerPool.Overflow() == constants.WorkerPoolOverflowReject &&
+		health.WorkerPool.QueueDepth >= health.WorkerPool.Capacity {
+		health.Reasons = append(health.Reasons, reasonWorkerPoolFull)
+	}
+
+	intake, err := usecase.paymentQueue.Len()
+	if err != nil {
+		health.Backlog.Error = err.Error()
+		health.Reasons = append(health.Reasons, reasonQueueUnavailable)
+	}
+
+	health.Backlog.Intake = intake
+
+	if intake >= usecase.maxBacklog {
+		health.Reasons = append(health.Reasons, reasonBacklogTooLarge)
+	}
+
+	// retries live in a shared queue, they say nothing about this instance
+	if retries, err := usecase.retryQueue.Len(); err == nil {
+		health.Backlog.Retries = retries
+	}
+
+	if len(health.Reasons) > 0 {
+		health.Status = constants.HealthStatusDown
+	}
+
+	return health, nil
 }
@@ -0,0 +1,149 @@
+package healthcheck_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/constants"
+	healthcheck "github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/application/usecases/health_check"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/contracts"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/entities"
+	circuitbreaker "github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/infra/circuit_breaker"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/infra/clients/memory"
+	filequeue "github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/infra/file_queue"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errConnectionRefused = errors.New("connection refused")
+
+type failingProcessors struct{}
+
+func (failingProcessors) ProcessorHealth(entities.ProcessorProvider) (entities.ProcessorHealth, bool) {
+	return entities.ProcessorHealth{Failing: true}, true
+}
+
+func (failingProcessors) ProcessorsHealth() map[entities.ProcessorProvider]entities.ProcessorHealth {
+	return map[entities.ProcessorProvider]entities.ProcessorHealth{
+		entities.Default:  {Failing: true},
+		entities.Fallback: {Failing: true},
+	}
+}
+
+type downStorage struct {
+	*memory.Client
+}
+
+func (downStorage) Ping() error { return errConnectionRefused }
+
+type fakePool struct {
+	overflow string
+	depth    int
+	capacity int
+}
+
+func (fakePool) Submit(func()) error { return nil }
+
+func (fakePool) TrySubmit(func()) error { return nil }
+
+func (fakePool) Wait() {}
+
+func (fakePool) Shutdown(time.Time) bool { return true }
+
+func (p fakePool) QueueDepth() int { return p.depth }
+
+func (p fakePool) Capacity() int { return p.capacity }
+
+func (fakePool) SetParallelism(int) {}
+
+func (fakePool) Parallelism() int { return 1 }
+
+func (p fakePool) Overflow() string { return p.overflow }
+
+type emptyRetryQueue struct{}
+
+func (emptyRetryQueue) Schedule(*entities.QueuedPayment, time.Time) error { return nil }
+
+func (emptyRetryQueue) Claim(time.Time, int) ([]*entities.QueuedPayment, error) { return nil, nil }
+
+func (emptyRetryQueue) Extend(*entities.QueuedPayment, time.Time) (bool, error) { return true, nil }
+
+func (emptyRetryQueue) Ack(*entities.QueuedPayment) error { return nil }
+
+func (emptyRetryQueue) Len() (int, error) { return 0, nil }
+
+func newUseCase(t *testing.T, storage contracts.Storage, pool contracts.WorkerPoolManager) *healthcheck.UseCase {
+	t.Helper()
+
+	queue := filequeue.New(filepath.Join(t.TempDir(), "queue.log"))
+	t.Cleanup(func() { _ = queue.Close() })
+
+	return healthcheck.NewUseCase(
+		failingProcessors{},
+		storage,
+		map[string]contracts.CircuitBreakerObserver{
+			string(entities.Default): circuitbreaker.New[*entities.PaymentResponse](
+				constants.MaxAttemptsBeforeOpen, constants.RecoveryTimeout,
+			),
+		},
+		pool,
+		queue,
+		emptyRetryQueue{},
+		constants.ReadinessMaxBacklog,
+	)
+}
+
+func TestFailingProcessorsDoNotMakeTheInstanceUnready(t *testing.T) {
+	t.Parallel()
+
+	health, err := newUseCase(t, memory.New(), fakePool{capacity: 20}).Execute()
+	require.NoError(t, err)
+
+	assert.Equal(t, constants.HealthStatusUp, health.Status)
+	assert.Empty(t, health.Reasons)
+	assert.Len(t, health.Processors, 2)
+	assert.Equal(t, "closed", health.CircuitBreakers[string(entities.Default)].State)
+}
+
+func TestUnreachableStorageAndFullPoolMakeTheInstanceUnready(t *testing.T) {
+	t.Parallel()
+
+	pool := fakePool{overflow: constants.WorkerPoolOverflowReject, depth: 20, capacity: 20}
+
+	health, err := newUseCase(t, downStorage{memory.New()}, pool).Execute()
+	require.NoError(t, err)
+
+	assert.Equal(t, constants.HealthStatusDown, health.Status)
+	assert.Equal(t, []string{"storage unavailable", "worker pool saturated"}, health.Reasons)
+	assert.Equal(t, errConnectionRefused.Error(), health.Storage.Error)
+}
+
+func TestFullPoolThatStillAcceptsPaymentsKeepsTheInstanceReady(t *testing.T) {
+	t.Parallel()
+
+	for _, overflow := range []string{constants.WorkerPoolOverflowSpill, constants.WorkerPoolOverflowBlock} {
+		pool := fakePool{overflow: overflow, depth: 20, capacity: 20}
+
+		health, err := newUseCase(t, memory.New(), pool).Execute()
+		require.NoError(t, err)
+
+		assert.Equal(t, constants.HealthStatusUp, health.Status, overflow)
+		assert.Empty(t, health.Reasons, overflow)
+	}
+}
+
+func TestDrainingMakesTheInstanceUnready(t *testing.T) {
+	t.Parallel()
+
+	usecase := newUseCase(t, memory.New(), fakePool{capacity: 20})
+	usecase.Drain()
+
+	health, err := usecase.Execute()
+	require.NoError(t, err)
+
+	assert.Equal(t, constants.HealthStatusDown, health.Status)
+	assert.Equal(t, []string{"shutting down"}, health.Reasons)
+	assert.Equal(t, constants.HealthStatusUp, usecase.Live().Status)
+}
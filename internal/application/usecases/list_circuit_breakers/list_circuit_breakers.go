@@ -0,0 +1,58 @@
+package listcircuitbreakers
+
+import (
+	"sort"
+
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/constants"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/application/dtos"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/contracts"
+)
+
+type UseCase struct {
+	circuitBreakers map[string]contracts.CircuitBreakerObserver
+}
+
+func NewUseCase(circuitBreakers map[string]contracts.CircuitBreakerObserver) *UseCase {
+	return &UseCase{
+		circuitBreakers: circuitBreakers,
+	}
+}
+
+// Execute lists the breakers by name with their recent transitions, oldest
+// first.
+func (usecase *UseCase) Execute() []*dtos.CircuitBreaker {
+	names := make([]string, 0, len(usecase.circuitBreakers))
+	for name := range usecase.circuitBreakers {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	circuitBreakers := make([]*dtos.CircuitBreaker, 0, len(names))
+
+	for _, name := range names {
+		circuitBreaker := usecase.circuitBreakers[name]
+		transitions := circuitBreaker.Transitions()
+
+		response := &dtos.CircuitBreaker{
+			Name:        name,
+			State:       constants.CircuitBreakerStateName(circuitBreaker.GetState()),
+			Failures:    circuitBreaker.GetCountFailure(),
+			Transitions: make([]*dtos.CircuitBreakerTransition, 0, len(transitions)),
+		}
+
+		for _, transition := range transitions {
+			response.Transitions = append(response.Transitions, &dtos.CircuitBreakerTransition{
+				At:       transition.At,
+				From:     constants.CircuitBreakerStateName(transition.From),
+				To:       constants.CircuitBreakerStateName(transition.To),
+				Reason:   transition.Reason,
+				Failures: transition.Failures,
+			})
+		}
+
+		circuitBreakers = append(circuitBreakers, response)
+	}
+
+	return circuitBreakers
+}
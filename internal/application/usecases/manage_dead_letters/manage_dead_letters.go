@@ -0,0 +1,94 @@
+package managedeadletters
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/constants"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/contracts"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/entities"
+)
+
+type UseCase struct {
+	deadLetterStore    contracts.DeadLetterStore
+	retryQueue         contracts.RetryQueue
+	paymentStatusStore contracts.PaymentStatusStore
+}
+
+func NewUseCase(
+	deadLetterStore contracts.DeadLetterStore,
+	retryQueue contracts.RetryQueue,
+	paymentStatusStore contracts.PaymentStatusStore,
+) *UseCase {
+	return &UseCase{
+		deadLetterStore:    deadLetterStore,
+		retryQueue:         retryQueue,
+		paymentStatusStore: paymentStatusStore,
+	}
+}
+
+func (usecase *UseCase) List() ([]*entities.DeadLetterPayment, error) {
+	return usecase.deadLetterStore.List()
+}
+
+func (usecase *UseCase) Find(correlationID string) (*entities.DeadLetterPayment, error) {
+	return usecase.deadLetterStore.Get(correlationID)
+}
+
+// Requeue gives a dead lettered payment a fresh set of attempts, starting now.
+func (usecase *UseCase) Requeue(correlationID string) error {
+	payment, err := usecase.deadLetterStore.Get(correlationID)
+	if err != nil {
+		return err
+	}
+
+	removed, err := usecase.deadLetterStore.Remove(correlationID)
+	if err != nil {
+		return err
+	}
+
+	if !removed {
+		return constants.ErrDeadLetterNotFound
+	}
+
+	if err := usecase.retryQueue.Schedule(&entities.QueuedPayment{
+		CorrelationID: payment.CorrelationID,
+		LastError:     payment.LastError,
+		Amount:        payment.Amount,
+	}, time.Now()); err != nil {
+		if restoreErr := usecase.deadLetterStore.Add(payment); restoreErr != nil {
+			return fmt.Errorf("error restoring dead letter after failed requeue: %w", restoreErr)
+		}
+
+		return fmt.Errorf("error requeueing payment: %w", err)
+	}
+
+	if err := usecase.paymentStatusStore.Record(correlationID, &entities.PaymentTransition{
+		At:    time.Now().UTC(),
+		State: entities.PaymentQueued,
+	}); err != nil {
+		log.Print(
+			map[string]interface{}{
+				"correlation_id": correlationID,
+				"action":         "error recording payment transition",
+				"error":          err,
+			},
+		)
+	}
+
+	return nil
+}
+
+func (usecase *UseCase) Discard(correlationID string) error {
+	removed, err := usecase.deadLetterStore.Remove(correlationID)
+	if err != nil {
+		return err
+	}
+
+	if !removed {
+		return constants.ErrDeadLetterNotFound
+	}
+
+	return nil
+}
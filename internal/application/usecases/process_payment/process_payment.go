@@ -1,6 +1,8 @@
 package processpayment
 
 import (
+	"errors"
+	"fmt"
 	"log"
 	"sync"
 	"time"
@@ -21,29 +23,145 @@ var (
 	timeStringCache = sync.Map{}
 )
 
+// configuredProcessor is a route that knows which processor it calls.
+type configuredProcessor interface {
+	Config() entities.ProcessorConfig
+}
+
+type processOutcome struct {
+	response *entities.PaymentResponse
+	err      error
+}
+
 type UseCase struct {
-	defaultPaymentProcessor   contracts.PaymentProcessor
-	secondaryPaymentProcessor contracts.PaymentProcessor
-	paymentCircuitBreaker     contracts.CircuitBreaker[*entities.PaymentResponse]
-	paymentStorage            contracts.Storage
+	paymentRouter         contracts.PaymentRouter
+	paymentStorage        contracts.Storage
+	paymentQueue          contracts.PaymentQueue
+	retryQueue            contracts.RetryQueue
+	deadLetterStore       contracts.DeadLetterStore
+	paymentStatusStore    contracts.PaymentStatusStore
+	processorHealthReader contracts.ProcessorHealthReader
+	hedging               entities.HedgingPolicy
+	retryDelay            time.Duration
+	maxAttempts           int
 }
 
+// NewUseCase hedges payments to the next processor as the policy says, reading
+// the budget from the processors health.
 func NewUseCase(
-	defaultPaymentProcessor contracts.PaymentProcessor,
-	secondaryPaymentProcessor contracts.PaymentProcessor,
-	paymentCircuitBreaker contracts.CircuitBreaker[*entities.PaymentResponse],
+	paymentRouter contracts.PaymentRouter,
 	paymentStorage contracts.Storage,
+	paymentQueue contracts.PaymentQueue,
+	retryQueue contracts.RetryQueue,
+	deadLetterStore contracts.DeadLetterStore,
+	paymentStatusStore contracts.PaymentStatusStore,
+	maxAttempts int,
+	retryDelay time.Duration,
+	processorHealthReader contracts.ProcessorHealthReader,
+	hedging entities.HedgingPolicy,
 ) *UseCase {
 	return &UseCase{
-		defaultPaymentProcessor:   defaultPaymentProcessor,
-		secondaryPaymentProcessor: secondaryPaymentProcessor,
-		paymentCircuitBreaker:     paymentCircuitBreaker,
-		paymentStorage:            paymentStorage,
+		paymentRouter:         paymentRouter,
+		paymentStorage:        paymentStorage,
+		paymentQueue:          paymentQueue,
+		retryQueue:            retryQueue,
+		deadLetterStore:       deadLetterStore,
+		paymentStatusStore:    paymentStatusStore,
+		processorHealthReader: processorHealthReader,
+		hedging:               hedging,
+		maxAttempts:           maxAttempts,
+		retryDelay:            retryDelay,
 	}
 }
 
+// Enqueue persists the payment before it is acknowledged to the client. A
+// correlationId that was already accepted, by any instance, returns
+// constants.ErrDuplicatePayment and is not enqueued again.
+func (usecase *UseCase) Enqueue(paymentRequest *dtos.PaymentPayload) (*entities.QueuedPayment, error) {
+	receivedAt := time.Now().UTC()
+
+	queuedPayment := &entities.QueuedPayment{
+		CorrelationID: paymentRequest.CorrelationID.String(),
+		Amount:        paymentRequest.Amount,
+	}
+
+	claimed, err := usecase.paymentStorage.Claim(queuedPayment.CorrelationID)
+	if err != nil {
+		return nil, fmt.Errorf("error claiming payment: %w", err)
+	}
+
+	if !claimed {
+		return nil, constants.ErrDuplicatePayment
+	}
+
+	if err := usecase.paymentQueue.Enqueue(queuedPayment); err != nil {
+		// let the client retry reach an instance that can take it
+		if releaseErr := usecase.paymentStorage.Release(queuedPayment.CorrelationID); releaseErr != nil {
+			go log.Print(
+				map[string]interface{}{
+					"correlation_id": queuedPayment.CorrelationID,
+					"action":         "error releasing claim",
+					"error":          releaseErr,
+				},
+			)
+		}
+
+		return nil, fmt.Errorf("error enqueueing payment: %w", err)
+	}
+
+	usecase.recordTransitions(queuedPayment.CorrelationID,
+		&entities.PaymentTransition{At: receivedAt, State: entities.PaymentReceived},
+		&entities.PaymentTransition{At: time.Now().UTC(), State: entities.PaymentQueued},
+	)
+
+	return queuedPayment, nil
+}
+
+// Spill hands a payment the worker pool had no room for to the retry queue,
+// due right away, so it is picked up as soon as workers are free, and releases
+// the queue entry it came from. When scheduling fails nothing is released and
+// the error is returned for the caller to keep the payment: an accepted one is
+// otherwise only replayed from the intake queue on the next boot, while a
+// claimed retry comes due again once its lease runs out.
+func (usecase *UseCase) Spill(queuedPayment *entities.QueuedPayment) error {
+	if err := usecase.retryQueue.Schedule(queuedPayment, time.Now()); err != nil {
+		return fmt.Errorf("error spilling payment: %w", err)
+	}
+
+	usecase.ack(queuedPayment)
+
+	return nil
+}
+
+// Pending lists the payments accepted but never acknowledged, e.g. before a crash.
+func (usecase *UseCase) Pending() ([]*entities.QueuedPayment, error) {
+	payments, err := usecase.paymentQueue.Pending()
+	if err != nil {
+		return nil, fmt.Errorf("error listing pending payments: %w", err)
+	}
+
+	return payments, nil
+}
+
+// DueRetries claims the failed payments whose next attempt is due.
+func (usecase *UseCase) DueRetries(limit int) ([]*entities.QueuedPayment, error) {
+	payments, err := usecase.retryQueue.Claim(time.Now(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("error claiming due retries: %w", err)
+	}
+
+	return payments, nil
+}
+
+// Execute pays a queued payment. A claimed retry is only paid while its lease
+// holds, returning constants.ErrRetryLeaseLost otherwise.
+//
 //nolint:funlen // long but necessary
-func (usecase *UseCase) Execute(paymentRequest *dtos.PaymentPayload) (*entities.PaymentResponse, error) {
+func (usecase *UseCase) Execute(queuedPayment *entities.QueuedPayment) (*entities.PaymentResponse, error) {
+	if !usecase.renewLease(queuedPayment) {
+		return nil, constants.ErrRetryLeaseLost
+	}
+
 	payload, ok := paymentRequestPool.Get().(*entities.PaymentRequest)
 	if !ok {
 		return nil, constants.ErrGettingPaymentRequestFromPool
@@ -52,11 +170,18 @@ func (usecase *UseCase) Execute(paymentRequest *dtos.PaymentPayload) (*entities.
 	defer paymentRequestPool.Put(payload)
 
 	*payload = entities.PaymentRequest{
-		CorrelationID: paymentRequest.CorrelationID.String(),
-		Amount:        paymentRequest.Amount,
+		CorrelationID: queuedPayment.CorrelationID,
+		Amount:        queuedPayment.Amount,
 		RequestedAt:   usecase.getTimeString(),
 	}
 
+	// recorded along with the outcome, a single status write per attempt
+	processing := &entities.PaymentTransition{
+		At:       time.Now().UTC(),
+		State:    entities.PaymentProcessing,
+		Attempts: queuedPayment.Attempts + 1,
+	}
+
 	response, err := usecase.processPayment(payload)
 	if err == nil {
 		func(currentResponse *entities.PaymentResponse, currentPayload *entities.PaymentRequest) {
@@ -78,21 +203,346 @@ func (usecase *UseCase) Execute(paymentRequest *dtos.PaymentPayload) (*entities.
 				)
 			}
 		}(response, payload)
+
+		usecase.recordTransitions(queuedPayment.CorrelationID, processing, &entities.PaymentTransition{
+			At:        time.Now().UTC(),
+			State:     entities.ProcessedState(response.ProcessorProvider),
+			Processor: response.ProcessorProvider,
+			Attempts:  queuedPayment.Attempts + 1,
+		})
+
+		usecase.ack(queuedPayment)
+
+		return response, nil
 	}
 
+	if errors.Is(err, constants.ErrPaymentDeferred) || errors.Is(err, constants.ErrCircuitBreakerOpen) {
+		usecase.postpone(queuedPayment, err, processing)
+
+		return response, err
+	}
+
+	usecase.handleFailure(queuedPayment, err, processing)
+
 	return response, err
 }
 
+// postpone puts off a payment no processor was worth paying for yet, or that
+// every processor breaker turned away, without spending one of its attempts.
+// After constants.MaxPaymentDeferrals it goes to the dead letter store instead.
+// The processing transition is recorded along.
+func (usecase *UseCase) postpone(
+	queuedPayment *entities.QueuedPayment,
+	deferErr error,
+	processing *entities.PaymentTransition,
+) {
+	queuedPayment.Deferrals++
+
+	transition := &entities.PaymentTransition{
+		At:       time.Now().UTC(),
+		State:    entities.PaymentDeferred,
+		Attempts: queuedPayment.Attempts,
+	}
+
+	var err error
+
+	if queuedPayment.Deferrals > constants.MaxPaymentDeferrals {
+		queuedPayment.LastError = fmt.Errorf("%w: %w", constants.ErrPaymentDeferredTooLong, deferErr).Error()
+
+		transition.State = entities.PaymentDeadLettered
+		transition.Error = queuedPayment.LastError
+
+		err = usecase.deadLetter(queuedPayment, transition.At)
+	} else {
+		err = usecase.retryQueue.Schedule(queuedPayment, time.Now().Add(usecase.retryDelay))
+	}
+
+	usecase.recordTransitions(queuedPayment.CorrelationID, processing, transition)
+
+	if err != nil {
+		go log.Print(
+			map[string]interface{}{
+				"correlation_id": queuedPayment.CorrelationID,
+				"action":         "error deferring payment",
+				"error":          err,
+			},
+		)
+
+		return
+	}
+
+	usecase.ack(queuedPayment)
+}
+
+// handleFailure moves a payment that failed on both processors to the retry
+// queue, or to the dead letter store once it has no attempts left. The
+// processing transition is recorded along.
+func (usecase *UseCase) handleFailure(
+	queuedPayment *entities.QueuedPayment,
+	processErr error,
+	processing *entities.PaymentTransition,
+) {
+	queuedPayment.Attempts++
+	queuedPayment.LastError = processErr.Error()
+
+	transition := &entities.PaymentTransition{
+		At:       time.Now().UTC(),
+		State:    entities.PaymentFailed,
+		Error:    queuedPayment.LastError,
+		Attempts: queuedPayment.Attempts,
+	}
+
+	var err error
+
+	if queuedPayment.Attempts >= usecase.maxAttempts {
+		transition.State = entities.PaymentDeadLettered
+
+		err = usecase.deadLetter(queuedPayment, transition.At)
+	} else {
+		err = usecase.retryQueue.Schedule(queuedPayment, time.Now().Add(usecase.retryBackoff(queuedPayment.Attempts)))
+	}
+
+	usecase.recordTransitions(queuedPayment.CorrelationID, processing, transition)
+
+	if err != nil {
+		// keep it in the intake queue, it will be replayed on the next boot
+		go log.Print(
+			map[string]interface{}{
+				"correlation_id": queuedPayment.CorrelationID,
+				"attempts":       queuedPayment.Attempts,
+				"action":         "error scheduling retry",
+				"error":          err,
+			},
+		)
+
+		return
+	}
+
+	usecase.ack(queuedPayment)
+}
+
+func (usecase *UseCase) deadLetter(queuedPayment *entities.QueuedPayment, failedAt time.Time) error {
+	if err := usecase.deadLetterStore.Add(&entities.DeadLetterPayment{
+		FailedAt:      failedAt,
+		CorrelationID: queuedPayment.CorrelationID,
+		LastError:     queuedPayment.LastError,
+		Amount:        queuedPayment.Amount,
+		Attempts:      queuedPayment.Attempts,
+	}); err != nil {
+		return fmt.Errorf("error dead-lettering payment: %w", err)
+	}
+
+	return nil
+}
+
+func (usecase *UseCase) recordTransitions(correlationID string, transitions ...*entities.PaymentTransition) {
+	if err := usecase.paymentStatusStore.Record(correlationID, transitions...); err != nil {
+		go log.Print(
+			map[string]interface{}{
+				"correlation_id": correlationID,
+				"action":         "error recording payment transition",
+				"error":          err,
+			},
+		)
+	}
+}
+
+func (usecase *UseCase) retryBackoff(attempts int) time.Duration {
+	delay := usecase.retryDelay << (attempts - 1)
+	if delay <= 0 || delay > constants.MaxPaymentRetryDelay {
+		return constants.MaxPaymentRetryDelay
+	}
+
+	return delay
+}
+
+// renewLease restarts the lease of a claimed retry once a worker took it, the
+// time it waited in the pool no longer counts. A retry whose lease ran out is
+// left alone, it may have been claimed again and it is due again otherwise.
+func (usecase *UseCase) renewLease(queuedPayment *entities.QueuedPayment) bool {
+	if queuedPayment.RetryID == "" {
+		return true
+	}
+
+	extended, err := usecase.retryQueue.Extend(queuedPayment, time.Now())
+	if err != nil {
+		go log.Print(
+			map[string]interface{}{
+				"correlation_id": queuedPayment.CorrelationID,
+				"action":         "error extending retry lease",
+				"error":          err,
+			},
+		)
+
+		return false
+	}
+
+	return extended
+}
+
+// ack releases the queue entry the payment came from, the intake queue one or
+// the claimed retry.
+func (usecase *UseCase) ack(queuedPayment *entities.QueuedPayment) {
+	if queuedPayment.ID != "" {
+		if err := usecase.paymentQueue.Ack(queuedPayment); err != nil {
+			go log.Print(
+				map[string]interface{}{
+					"correlation_id": queuedPayment.CorrelationID,
+					"action":         "error acknowledging",
+					"error":          err,
+				},
+			)
+		}
+
+		queuedPayment.ID = ""
+	}
+
+	if queuedPayment.RetryID != "" {
+		// left claimed, it is due again once its lease runs out
+		if err := usecase.retryQueue.Ack(queuedPayment); err != nil {
+			go log.Print(
+				map[string]interface{}{
+					"correlation_id": queuedPayment.CorrelationID,
+					"action":         "error acknowledging retry",
+					"error":          err,
+				},
+			)
+		}
+	}
+}
+
+// processPayment tries the processors down the ranking until one takes the
+// payment, hedging it to the next one when hedging is on. It returns
+// constants.ErrCircuitBreakerOpen only when every breaker turned it away, the
+// error of the last processor tried otherwise.
 func (usecase *UseCase) processPayment(payload *entities.PaymentRequest) (*entities.PaymentResponse, error) {
-	primaryPayment := func() (*entities.PaymentResponse, error) {
-		return usecase.defaultPaymentProcessor.ProcessPayment(payload)
+	routes := usecase.paymentRouter.Routes()
+	if len(routes) == 0 {
+		return nil, constants.ErrPaymentDeferred
 	}
 
-	fallback := func() (*entities.PaymentResponse, error) {
-		return usecase.secondaryPaymentProcessor.ProcessPayment(payload)
+	var (
+		response *entities.PaymentResponse
+		lastErr  = constants.ErrCircuitBreakerOpen
+	)
+
+	for index := 0; index < len(routes); index++ {
+		var (
+			result *entities.PaymentResponse
+			err    error
+		)
+
+		if budget, ok := usecase.hedgeBudget(routes[index]); ok && index < len(routes)-1 {
+			var hedged bool
+
+			result, hedged, err = usecase.hedge(routes[index], routes[index+1], payload, budget)
+			if hedged {
+				// the next one was tried already
+				index++
+			}
+		} else {
+			result, err = routes[index].ProcessPayment(payload)
+		}
+
+		if err == nil {
+			return result, nil
+		}
+
+		if !errors.Is(err, constants.ErrCircuitBreakerOpen) && !errors.Is(err, constants.ErrPaymentHedged) {
+			response, lastErr = result, err
+		}
+	}
+
+	return response, lastErr
+}
+
+// hedgeBudget is how long the processor gets before the payment is hedged to
+// the next one, false when hedging is off.
+func (usecase *UseCase) hedgeBudget(route contracts.PaymentProcessor) (time.Duration, bool) {
+	if !usecase.hedging.Enabled {
+		return 0, false
+	}
+
+	configured, ok := route.(configuredProcessor)
+	if !ok {
+		return 0, false
+	}
+
+	budget := usecase.hedging.MinBudget
+
+	health, ok := usecase.processorHealthReader.ProcessorHealth(configured.Config().Name)
+	if ok {
+		expected := time.Duration(float64(health.MinResponseTime)*usecase.hedging.Factor) * time.Millisecond
+		budget = max(budget, expected)
+	}
+
+	return budget, true
+}
+
+// hedge sends the payment to the processor and, when it did not answer within
+// the budget, to the next one too while the first call is still pending, both
+// with the same correlationId. The first processor to take it, by a 200 or a
+// 422 telling it already had it, owns the payment, see entities.PaymentHedge:
+// the response names the owner whichever call answered first, so the payment
+// is counted under exactly one processor. It tells whether the next processor
+// was sent the payment.
+func (usecase *UseCase) hedge(
+	route contracts.PaymentProcessor,
+	next contracts.PaymentProcessor,
+	payload *entities.PaymentRequest,
+	budget time.Duration,
+) (*entities.PaymentResponse, bool, error) {
+	hedge := entities.NewPaymentHedge()
+
+	// the processor calls may outlive this one, they get their own copy
+	hedged := *payload
+	hedged.Hedge = hedge
+
+	outcomes := make(chan processOutcome, 2)
+
+	send := func(processor contracts.PaymentProcessor) {
+		go func() {
+			response, err := processor.ProcessPayment(&hedged)
+			outcomes <- processOutcome{response: response, err: err}
+		}()
+	}
+
+	send(route)
+
+	timer := time.NewTimer(budget)
+	defer timer.Stop()
+
+	var (
+		response *entities.PaymentResponse
+		lastErr  error
+		sentNext bool
+	)
+
+	for pending := 1; pending > 0; {
+		select {
+		case <-timer.C:
+			sentNext = true
+			pending++
+
+			send(next)
+		case outcome := <-outcomes:
+			pending--
+
+			if outcome.err == nil {
+				if owner, ok := hedge.Owner(); ok {
+					outcome.response.ProcessorProvider = owner
+				}
+
+				return outcome.response, sentNext, nil
+			}
+
+			if lastErr == nil || !errors.Is(outcome.err, constants.ErrPaymentHedged) {
+				response, lastErr = outcome.response, outcome.err
+			}
+		}
 	}
 
-	return usecase.paymentCircuitBreaker.Execute(primaryPayment, fallback)
+	return response, sentNext, lastErr
 }
 
 func (usecase *UseCase) getTimeString() string {
@@ -0,0 +1,509 @@
+package processpayment_test
+
+import (
+	"errors"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/constants"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/application/dtos"
+	processpayment "github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/application/usecases/process_payment"
+	retrievepaymentsummary "github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/application/usecases/retrieve_payment_summary"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/contracts"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/entities"
+	circuitbreaker "github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/infra/circuit_breaker"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/infra/clients/memory"
+	filequeue "github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/infra/file_queue"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/infra/metrics"
+	paymentrouter "github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/infra/payment_router"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errProcessorDown = errors.New("processor down")
+
+type fakeProcessor struct {
+	err      error
+	provider entities.ProcessorProvider
+}
+
+func (p *fakeProcessor) ProcessPayment(*entities.PaymentRequest) (*entities.PaymentResponse, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+
+	return &entities.PaymentResponse{Message: "ok", ProcessorProvider: p.provider}, nil
+}
+
+func (p *fakeProcessor) PaymentsSummary(*entities.PaymentSummaryFilters) (*entities.PaymentSummaryResponse, error) {
+	return &entities.PaymentSummaryResponse{}, nil
+}
+
+type fakeRetryQueue struct {
+	scheduled []*entities.QueuedPayment
+	mutex     sync.Mutex
+	leaseLost bool
+}
+
+func (q *fakeRetryQueue) Schedule(payment *entities.QueuedPayment, _ time.Time) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.scheduled = append(q.scheduled, payment)
+
+	return nil
+}
+
+func (q *fakeRetryQueue) Claim(time.Time, int) ([]*entities.QueuedPayment, error) {
+	return nil, nil
+}
+
+func (q *fakeRetryQueue) Extend(*entities.QueuedPayment, time.Time) (bool, error) {
+	return !q.leaseLost, nil
+}
+
+func (q *fakeRetryQueue) Ack(payment *entities.QueuedPayment) error {
+	payment.RetryID = ""
+
+	return nil
+}
+
+func (q *fakeRetryQueue) Len() (int, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	return len(q.scheduled), nil
+}
+
+type fakeDeadLetterStore struct {
+	added []*entities.DeadLetterPayment
+	mutex sync.Mutex
+}
+
+func (s *fakeDeadLetterStore) Add(payment *entities.DeadLetterPayment) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.added = append(s.added, payment)
+
+	return nil
+}
+
+func (*fakeDeadLetterStore) List() ([]*entities.DeadLetterPayment, error) { return nil, nil }
+
+func (*fakeDeadLetterStore) Get(string) (*entities.DeadLetterPayment, error) {
+	return nil, constants.ErrDeadLetterNotFound
+}
+
+func (*fakeDeadLetterStore) Remove(string) (bool, error) { return false, nil }
+
+type fakeStatusStore struct{}
+
+func (fakeStatusStore) Record(string, ...*entities.PaymentTransition) error { return nil }
+
+func (fakeStatusStore) Get(string) (*entities.PaymentStatus, error) {
+	return nil, constants.ErrPaymentNotFound
+}
+
+// unknownHealth never got a health snapshot.
+type unknownHealth struct{}
+
+func (unknownHealth) ProcessorHealth(entities.ProcessorProvider) (entities.ProcessorHealth, bool) {
+	return entities.ProcessorHealth{}, false
+}
+
+func (unknownHealth) ProcessorsHealth() map[entities.ProcessorProvider]entities.ProcessorHealth {
+	return nil
+}
+
+// hedgedProcessor answers after latency with status, 200 when unset, and
+// follows the payment hedge like the processor client does: nothing is sent
+// once another processor owns the payment, and a 200 or a 422 settles it.
+type hedgedProcessor struct {
+	provider entities.ProcessorProvider
+	latency  time.Duration
+	status   int
+	sent     atomic.Int32
+}
+
+func (p *hedgedProcessor) ProcessPayment(paymentRequest *entities.PaymentRequest) (*entities.PaymentResponse, error) {
+	if paymentRequest.Hedge.Lost(p.provider) {
+		return nil, constants.ErrPaymentHedged
+	}
+
+	p.sent.Add(1)
+	time.Sleep(p.latency)
+
+	if p.status >= constants.HTTPStatusInternalServerError {
+		return nil, errProcessorDown
+	}
+
+	paymentRequest.Hedge.Settle(p.provider)
+
+	return &entities.PaymentResponse{Message: "ok", ProcessorProvider: p.provider}, nil
+}
+
+func (p *hedgedProcessor) PaymentsSummary(*entities.PaymentSummaryFilters) (*entities.PaymentSummaryResponse, error) {
+	return &entities.PaymentSummaryResponse{}, nil
+}
+
+type fixture struct {
+	useCase        *processpayment.UseCase
+	summaryUseCase *retrievepaymentsummary.UseCase
+	queue          *filequeue.Queue
+	retryQueue     *fakeRetryQueue
+	deadLetters    *fakeDeadLetterStore
+}
+
+// deferringRouter never finds a processor worth paying.
+type deferringRouter struct{}
+
+func (deferringRouter) Routes() []contracts.PaymentProcessor {
+	return nil
+}
+
+func newFixture(t *testing.T, defaultErr, fallbackErr error) *fixture {
+	t.Helper()
+
+	return newGuardedFixture(t, constants.MaxAttemptsBeforeOpen, defaultErr, fallbackErr)
+}
+
+// newGuardedFixture opens each processor breaker after failureThreshold
+// failures in a row.
+func newGuardedFixture(t *testing.T, failureThreshold int32, defaultErr, fallbackErr error) *fixture {
+	t.Helper()
+
+	defaultProcessor := &fakeProcessor{err: defaultErr, provider: entities.Default}
+	fallbackProcessor := &fakeProcessor{err: fallbackErr, provider: entities.Fallback}
+
+	return newRoutedFixture(t, newPriorityRouter(failureThreshold, defaultProcessor, fallbackProcessor), entities.HedgingPolicy{})
+}
+
+func newPriorityRouter(failureThreshold int32, defaultProcessor, fallbackProcessor contracts.PaymentProcessor) *paymentrouter.Router {
+	policy := entities.CircuitBreakerPolicy{
+		FailureThreshold: failureThreshold,
+		RecoveryTimeout:  constants.RecoveryTimeout,
+		Uncounted: func(err error) bool {
+			return errors.Is(err, constants.ErrPaymentHedged)
+		},
+	}
+
+	return paymentrouter.New(paymentrouter.PriorityStrategy{}, metrics.New(),
+		paymentrouter.NewRoute(
+			entities.ProcessorConfig{Name: entities.Default, Priority: 1},
+			defaultProcessor,
+			circuitbreaker.NewFromPolicy[*entities.PaymentResponse](policy),
+		),
+		paymentrouter.NewRoute(
+			entities.ProcessorConfig{Name: entities.Fallback, Priority: 2},
+			fallbackProcessor,
+			circuitbreaker.NewFromPolicy[*entities.PaymentResponse](policy),
+		),
+	)
+}
+
+func newRoutedFixture(t *testing.T, paymentRouter contracts.PaymentRouter, hedging entities.HedgingPolicy) *fixture {
+	t.Helper()
+
+	storage := memory.New()
+	queue := filequeue.New(filepath.Join(t.TempDir(), "queue.log"))
+	retryQueue := &fakeRetryQueue{}
+	deadLetters := &fakeDeadLetterStore{}
+
+	t.Cleanup(func() { _ = queue.Close() })
+
+	return &fixture{
+		useCase: processpayment.NewUseCase(
+			paymentRouter,
+			storage,
+			queue,
+			retryQueue,
+			deadLetters,
+			fakeStatusStore{},
+			constants.DefaultPaymentMaxAttempts,
+			constants.DefaultPaymentRetryDelay,
+			unknownHealth{},
+			hedging,
+		),
+		summaryUseCase: retrievepaymentsummary.NewUseCase(storage),
+		queue:          queue,
+		retryQueue:     retryQueue,
+		deadLetters:    deadLetters,
+	}
+}
+
+func TestExecuteSavesProcessedPayments(t *testing.T) {
+	t.Parallel()
+
+	fixture := newFixture(t, nil, nil)
+
+	for range 3 {
+		queued, err := fixture.useCase.Enqueue(&dtos.PaymentPayload{
+			CorrelationID: uuid.New(),
+			Amount:        entities.NewMoneyFromCents(1990),
+		})
+		require.NoError(t, err)
+
+		response, err := fixture.useCase.Execute(queued)
+		require.NoError(t, err)
+		assert.Equal(t, entities.Default, response.ProcessorProvider)
+	}
+
+	summary, err := fixture.summaryUseCase.Execute(&dtos.PaymentSummaryFilters{})
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, summary.Default.TotalRequests)
+	assert.Equal(t, "59.70", summary.Default.TotalAmount.String())
+	assert.Equal(t, 0, summary.Fallback.TotalRequests)
+
+	pending, err := fixture.queue.Pending()
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+}
+
+func TestExecuteLeavesARetryWhoseLeaseRanOut(t *testing.T) {
+	t.Parallel()
+
+	fixture := newFixture(t, nil, nil)
+	fixture.retryQueue.leaseLost = true
+
+	_, err := fixture.useCase.Execute(&entities.QueuedPayment{
+		RetryID:       "claimed",
+		CorrelationID: uuid.NewString(),
+		Amount:        entities.NewMoneyFromCents(1990),
+	})
+	require.ErrorIs(t, err, constants.ErrRetryLeaseLost)
+
+	summary, err := fixture.summaryUseCase.Execute(&dtos.PaymentSummaryFilters{})
+	require.NoError(t, err)
+	assert.Equal(t, 0, summary.Default.TotalRequests+summary.Fallback.TotalRequests)
+}
+
+func TestEnqueueRejectsDuplicates(t *testing.T) {
+	t.Parallel()
+
+	fixture := newFixture(t, nil, nil)
+
+	payment := &dtos.PaymentPayload{CorrelationID: uuid.New(), Amount: entities.NewMoneyFromCents(100)}
+
+	_, err := fixture.useCase.Enqueue(payment)
+	require.NoError(t, err)
+
+	_, err = fixture.useCase.Enqueue(payment)
+	assert.ErrorIs(t, err, constants.ErrDuplicatePayment)
+}
+
+func TestExecuteSchedulesRetryWhenBothProcessorsFail(t *testing.T) {
+	t.Parallel()
+
+	fixture := newFixture(t, errProcessorDown, errProcessorDown)
+
+	queued, err := fixture.useCase.Enqueue(&dtos.PaymentPayload{
+		CorrelationID: uuid.New(),
+		Amount:        entities.NewMoneyFromCents(1990),
+	})
+	require.NoError(t, err)
+
+	_, err = fixture.useCase.Execute(queued)
+	require.Error(t, err)
+
+	require.Len(t, fixture.retryQueue.scheduled, 1)
+	assert.Equal(t, 1, fixture.retryQueue.scheduled[0].Attempts)
+
+	summary, err := fixture.summaryUseCase.Execute(&dtos.PaymentSummaryFilters{})
+	require.NoError(t, err)
+	assert.Equal(t, 0, summary.Default.TotalRequests+summary.Fallback.TotalRequests)
+
+	pending, err := fixture.queue.Pending()
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+}
+
+func TestExecuteDefersWithoutSpendingAttempts(t *testing.T) {
+	t.Parallel()
+
+	fixture := newRoutedFixture(t, deferringRouter{}, entities.HedgingPolicy{})
+
+	queued, err := fixture.useCase.Enqueue(&dtos.PaymentPayload{
+		CorrelationID: uuid.New(),
+		Amount:        entities.NewMoneyFromCents(1990),
+	})
+	require.NoError(t, err)
+
+	_, err = fixture.useCase.Execute(queued)
+	require.ErrorIs(t, err, constants.ErrPaymentDeferred)
+
+	require.Len(t, fixture.retryQueue.scheduled, 1)
+	assert.Equal(t, 0, fixture.retryQueue.scheduled[0].Attempts)
+	assert.Equal(t, 1, fixture.retryQueue.scheduled[0].Deferrals)
+
+	pending, err := fixture.queue.Pending()
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+}
+
+func TestExecuteDeadLettersPaymentsDeferredTooOften(t *testing.T) {
+	t.Parallel()
+
+	fixture := newRoutedFixture(t, deferringRouter{}, entities.HedgingPolicy{})
+
+	claimed := &entities.QueuedPayment{
+		RetryID:       "claimed",
+		CorrelationID: uuid.NewString(),
+		Amount:        entities.NewMoneyFromCents(1990),
+		Deferrals:     constants.MaxPaymentDeferrals,
+	}
+
+	_, err := fixture.useCase.Execute(claimed)
+	require.ErrorIs(t, err, constants.ErrPaymentDeferred)
+
+	assert.Empty(t, fixture.retryQueue.scheduled)
+	require.Len(t, fixture.deadLetters.added, 1)
+	assert.Equal(t, claimed.CorrelationID, fixture.deadLetters.added[0].CorrelationID)
+	assert.Contains(t, fixture.deadLetters.added[0].LastError, constants.ErrPaymentDeferredTooLong.Error())
+	assert.Empty(t, claimed.RetryID)
+}
+
+func TestExecuteQueuesPaymentsWhileEveryBreakerIsOpen(t *testing.T) {
+	t.Parallel()
+
+	fixture := newGuardedFixture(t, 1, errProcessorDown, errProcessorDown)
+
+	for range 2 {
+		queued, err := fixture.useCase.Enqueue(&dtos.PaymentPayload{
+			CorrelationID: uuid.New(),
+			Amount:        entities.NewMoneyFromCents(1990),
+		})
+		require.NoError(t, err)
+
+		_, _ = fixture.useCase.Execute(queued)
+	}
+
+	// the first payment failed on both processors and opened their breakers,
+	// the second one waits for them without spending an attempt
+	require.Len(t, fixture.retryQueue.scheduled, 2)
+	assert.Equal(t, 1, fixture.retryQueue.scheduled[0].Attempts)
+	assert.Equal(t, 0, fixture.retryQueue.scheduled[1].Attempts)
+	assert.Empty(t, fixture.retryQueue.scheduled[1].LastError)
+
+	pending, err := fixture.queue.Pending()
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+}
+
+// hedgedPayment runs a payment through the processors with a 10ms budget and
+// gives the slower call time to answer too.
+func hedgedPayment(t *testing.T, defaultProcessor, fallbackProcessor *hedgedProcessor) (*entities.PaymentResponse, *entities.PaymentResultStorage) {
+	t.Helper()
+
+	fixture := newRoutedFixture(t,
+		newPriorityRouter(constants.MaxAttemptsBeforeOpen, defaultProcessor, fallbackProcessor),
+		entities.HedgingPolicy{Enabled: true, MinBudget: 10 * time.Millisecond},
+	)
+
+	queued, err := fixture.useCase.Enqueue(&dtos.PaymentPayload{
+		CorrelationID: uuid.New(),
+		Amount:        entities.NewMoneyFromCents(1990),
+	})
+	require.NoError(t, err)
+
+	response, err := fixture.useCase.Execute(queued)
+	require.NoError(t, err)
+
+	time.Sleep(max(defaultProcessor.latency, fallbackProcessor.latency))
+
+	summary, err := fixture.summaryUseCase.Execute(&dtos.PaymentSummaryFilters{})
+	require.NoError(t, err)
+
+	return response, summary
+}
+
+func TestHedgingSendsToTheFallbackWhileTheDefaultIsPending(t *testing.T) {
+	t.Parallel()
+
+	defaultProcessor := &hedgedProcessor{provider: entities.Default, latency: 60 * time.Millisecond}
+	fallbackProcessor := &hedgedProcessor{provider: entities.Fallback}
+
+	response, summary := hedgedPayment(t, defaultProcessor, fallbackProcessor)
+
+	assert.Equal(t, entities.Fallback, response.ProcessorProvider)
+	assert.Equal(t, int32(1), defaultProcessor.sent.Load())
+	assert.Equal(t, int32(1), fallbackProcessor.sent.Load())
+
+	// the default answering later does not count it a second time
+	assert.Equal(t, 0, summary.Default.TotalRequests)
+	assert.Equal(t, 1, summary.Fallback.TotalRequests)
+}
+
+func TestHedgingSettlesOwnershipOnADuplicate(t *testing.T) {
+	t.Parallel()
+
+	// the default had the payment from an earlier attempt and answers 422
+	// while the fallback is still busy with it
+	defaultProcessor := &hedgedProcessor{
+		provider: entities.Default,
+		latency:  30 * time.Millisecond,
+		status:   constants.HTTPStatusUnprocessableEntity,
+	}
+	fallbackProcessor := &hedgedProcessor{provider: entities.Fallback, latency: 80 * time.Millisecond}
+
+	response, summary := hedgedPayment(t, defaultProcessor, fallbackProcessor)
+
+	assert.Equal(t, entities.Default, response.ProcessorProvider)
+	assert.Equal(t, int32(1), fallbackProcessor.sent.Load())
+	assert.Equal(t, 1, summary.Default.TotalRequests)
+	assert.Equal(t, 0, summary.Fallback.TotalRequests)
+}
+
+func TestHedgingWaitsForTheFallbackWhenTheDefaultFails(t *testing.T) {
+	t.Parallel()
+
+	defaultProcessor := &hedgedProcessor{
+		provider: entities.Default,
+		latency:  20 * time.Millisecond,
+		status:   constants.HTTPStatusInternalServerError,
+	}
+	fallbackProcessor := &hedgedProcessor{provider: entities.Fallback, latency: 40 * time.Millisecond}
+
+	response, summary := hedgedPayment(t, defaultProcessor, fallbackProcessor)
+
+	assert.Equal(t, entities.Fallback, response.ProcessorProvider)
+	assert.Equal(t, int32(1), fallbackProcessor.sent.Load(), "tried once, not again after the hedge")
+	assert.Equal(t, 1, summary.Fallback.TotalRequests)
+}
+
+func TestHedgingLeavesFastDefaultsAlone(t *testing.T) {
+	t.Parallel()
+
+	defaultProcessor := &hedgedProcessor{provider: entities.Default, latency: time.Millisecond}
+	fallbackProcessor := &hedgedProcessor{provider: entities.Fallback}
+
+	response, summary := hedgedPayment(t, defaultProcessor, fallbackProcessor)
+
+	assert.Equal(t, entities.Default, response.ProcessorProvider)
+	assert.Zero(t, fallbackProcessor.sent.Load())
+	assert.Equal(t, 1, summary.Default.TotalRequests)
+}
+
+func TestExecuteAcknowledgesClaimedRetries(t *testing.T) {
+	t.Parallel()
+
+	for _, processorErr := range []error{nil, errProcessorDown} {
+		fixture := newFixture(t, processorErr, processorErr)
+
+		claimed := &entities.QueuedPayment{
+			RetryID:       "claimed",
+			CorrelationID: uuid.NewString(),
+			Amount:        entities.NewMoneyFromCents(1990),
+			Attempts:      1,
+		}
+
+		_, _ = fixture.useCase.Execute(claimed)
+
+		assert.Empty(t, claimed.RetryID, "processed or scheduled again, the claim is released")
+	}
+}
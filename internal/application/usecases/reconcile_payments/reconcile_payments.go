@@ -0,0 +1,246 @@
+package reconcilepayments
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/constants"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/application/dtos"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/contracts"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/entities"
+)
+
+// repairNamespace makes repair entries deterministic, so repairing the same
+// bucket twice, from one instance or several, never counts it twice.
+var repairNamespace = uuid.MustParse("6f1c5a8e-5d4b-4c1a-9a57-2f3e0b7c9d10")
+
+type processorTarget struct {
+	processor         contracts.PaymentProcessor
+	processorProvider entities.ProcessorProvider
+}
+
+type UseCase struct {
+	paymentStorage contracts.Storage
+	lastReport     *entities.ReconciliationReport
+	processors     []processorTarget
+	window         time.Duration
+	bucketSize     time.Duration
+	mutex          sync.RWMutex
+	running        sync.Mutex
+}
+
+func NewUseCase(
+	paymentProcessors map[entities.ProcessorProvider]contracts.PaymentProcessor,
+	paymentStorage contracts.Storage,
+	window time.Duration,
+	bucketSize time.Duration,
+) *UseCase {
+	processors := make([]processorTarget, 0, len(paymentProcessors))
+	for processorProvider, processor := range paymentProcessors {
+		processors = append(processors, processorTarget{processor: processor, processorProvider: processorProvider})
+	}
+
+	slices.SortFunc(processors, func(a, b processorTarget) int {
+		return strings.Compare(string(a.processorProvider), string(b.processorProvider))
+	})
+
+	return &UseCase{
+		paymentStorage: paymentStorage,
+		processors:     processors,
+		window:         window,
+		bucketSize:     bucketSize,
+	}
+}
+
+// Execute compares the local summary of each processor with the one the
+// processor reports for the same window. Ranges that disagree are halved until
+// they are a single bucket, so a consistent window costs one request per
+// processor. With repair, payments the processor has but the local storage
+// misses are saved locally; local surpluses are only reported.
+func (usecase *UseCase) Execute(request *dtos.ReconciliationRequest, trigger string) (*entities.ReconciliationReport, error) {
+	to := time.Now().UTC().Add(-constants.ReconciliationSettleDelay)
+	if request.To != nil {
+		to = request.To.UTC()
+	}
+
+	from := to.Add(-usecase.window)
+	if request.From != nil {
+		from = request.From.UTC()
+	}
+
+	if !from.Before(to) {
+		return nil, constants.ErrInvalidReconciliationWindow
+	}
+
+	// one run at a time, concurrent repairs would only race each other
+	usecase.running.Lock()
+	defer usecase.running.Unlock()
+
+	report := &entities.ReconciliationReport{
+		StartedAt:  time.Now().UTC(),
+		From:       from,
+		To:         to,
+		Totals:     map[entities.ProcessorProvider]entities.ReconciliationTotals{},
+		Trigger:    trigger,
+		Mismatches: []entities.ReconciliationMismatch{},
+		Errors:     []string{},
+		Repair:     request.Repair,
+	}
+
+	for _, target := range usecase.processors {
+		local, remote, err := usecase.compare(target, from.UnixMilli(), to.UnixMilli(), report)
+		if err != nil {
+			report.Errors = append(report.Errors, err.Error())
+
+			continue
+		}
+
+		report.Totals[target.processorProvider] = entities.ReconciliationTotals{Local: local, Remote: remote}
+	}
+
+	if request.Repair {
+		usecase.repair(report)
+	}
+
+	report.Consistent = len(report.Errors) == 0
+	for _, mismatch := range report.Mismatches {
+		report.Consistent = report.Consistent && mismatch.Repaired
+	}
+
+	report.FinishedAt = time.Now().UTC()
+
+	usecase.mutex.Lock()
+	usecase.lastReport = report
+	usecase.mutex.Unlock()
+
+	return report, nil
+}
+
+func (usecase *UseCase) LastReport() (*entities.ReconciliationReport, error) {
+	usecase.mutex.RLock()
+	defer usecase.mutex.RUnlock()
+
+	if usecase.lastReport == nil {
+		return nil, constants.ErrReconciliationNotFound
+	}
+
+	return usecase.lastReport, nil
+}
+
+// compare checks the inclusive millisecond range [from, to] and bisects it while it disagrees.
+func (usecase *UseCase) compare(
+	target processorTarget,
+	from, to int64,
+	report *entities.ReconciliationReport,
+) (entities.Summary, entities.Summary, error) {
+	local, remote, err := usecase.summaries(target, from, to)
+	if err != nil {
+		return local, remote, err
+	}
+
+	if local == remote {
+		return local, remote, nil
+	}
+
+	if to-from+1 <= usecase.bucketSize.Milliseconds() {
+		report.Mismatches = append(report.Mismatches, entities.ReconciliationMismatch{
+			From:      time.UnixMilli(from).UTC(),
+			To:        time.UnixMilli(to).UTC(),
+			Processor: target.processorProvider,
+			Local:     local,
+			Remote:    remote,
+		})
+
+		return local, remote, nil
+	}
+
+	middle := from + (to-from)/2
+
+	if _, _, err := usecase.compare(target, from, middle, report); err != nil {
+		return local, remote, err
+	}
+
+	if _, _, err := usecase.compare(target, middle+1, to, report); err != nil {
+		return local, remote, err
+	}
+
+	return local, remote, nil
+}
+
+func (usecase *UseCase) summaries(target processorTarget, from, to int64) (entities.Summary, entities.Summary, error) {
+	fromTime := time.UnixMilli(from).UTC()
+	toTime := time.UnixMilli(to).UTC()
+
+	filters := &entities.PaymentSummaryFilters{From: &fromTime, To: &toTime}
+
+	localResult, err := usecase.paymentStorage.Retrieve(filters)
+	if err != nil {
+		return entities.Summary{}, entities.Summary{}, fmt.Errorf("error retrieving local summary: %w", err)
+	}
+
+	remoteResult, err := target.processor.PaymentsSummary(filters)
+	if err != nil {
+		return entities.Summary{}, entities.Summary{}, fmt.Errorf("error retrieving %s summary: %w", target.processorProvider, err)
+	}
+
+	return localResult.Get(target.processorProvider), remoteResult.Get(target.processorProvider), nil
+}
+
+// repair saves the payments missing from each bucket, splitting the missing
+// amount between them; which correlationIds they were is unknown locally.
+func (usecase *UseCase) repair(report *entities.ReconciliationReport) {
+	for i := range report.Mismatches {
+		mismatch := &report.Mismatches[i]
+
+		missing := mismatch.Remote.TotalRequests - mismatch.Local.TotalRequests
+		missingAmount := mismatch.Remote.TotalAmount - mismatch.Local.TotalAmount
+
+		if missing <= 0 || missingAmount < 0 {
+			continue
+		}
+
+		share := missingAmount.Cents() / int64(missing)
+		remainder := missingAmount.Cents() % int64(missing)
+
+		requestedAt := mismatch.From.Format(constants.DefaultTimeFormat)
+		repaired := true
+
+		for entry := range missing {
+			amount := share
+			if entry == 0 {
+				amount += remainder
+			}
+
+			key := string(mismatch.Processor) + ":" + strconv.FormatInt(mismatch.From.UnixMilli(), 10) + ":" +
+				strconv.Itoa(mismatch.Local.TotalRequests+entry)
+
+			if err := usecase.paymentStorage.Save(&entities.PaymentPayloadStorage{
+				ID:                uuid.NewSHA1(repairNamespace, []byte(key)).String(),
+				ProcessorProvider: mismatch.Processor,
+				RequestedAt:       requestedAt,
+				Amount:            entities.NewMoneyFromCents(amount),
+			}); err != nil {
+				repaired = false
+
+				go log.Print(
+					map[string]interface{}{
+						"processor": mismatch.Processor,
+						"from":      mismatch.From,
+						"action":    "error repairing reconciliation bucket",
+						"error":     err,
+					},
+				)
+
+				break
+			}
+		}
+
+		mismatch.Repaired = repaired
+	}
+}
@@ -0,0 +1,117 @@
+package reconcilepayments_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/constants"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/application/dtos"
+	reconcilepayments "github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/application/usecases/reconcile_payments"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/contracts"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/entities"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/infra/clients/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeProcessor answers its admin summary from a storage holding what it processed.
+type fakeProcessor struct {
+	processed         *memory.Client
+	processorProvider entities.ProcessorProvider
+}
+
+func (p *fakeProcessor) ProcessPayment(*entities.PaymentRequest) (*entities.PaymentResponse, error) {
+	return &entities.PaymentResponse{Message: "ok", ProcessorProvider: p.processorProvider}, nil
+}
+
+func (p *fakeProcessor) PaymentsSummary(filters *entities.PaymentSummaryFilters) (*entities.PaymentSummaryResponse, error) {
+	result, err := p.processed.Retrieve(filters)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &entities.PaymentSummaryResponse{}
+	summary.Add(p.processorProvider, result.Get(p.processorProvider))
+
+	return summary, nil
+}
+
+func TestExecuteFindsAndRepairsMissingPayments(t *testing.T) {
+	t.Parallel()
+
+	local := memory.New()
+	processed := memory.New()
+
+	start := time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC)
+	end := start.Add(time.Minute)
+
+	for i := range 60 {
+		payload := &entities.PaymentPayloadStorage{
+			ID:                uuid.NewString(),
+			ProcessorProvider: entities.Default,
+			RequestedAt:       start.Add(time.Duration(i) * time.Second).Format(constants.DefaultTimeFormat),
+			Amount:            entities.NewMoneyFromCents(1990),
+		}
+
+		require.NoError(t, processed.Save(payload))
+
+		// the save after processing failed for these two
+		if i != 42 && i != 43 {
+			require.NoError(t, local.Save(payload))
+		}
+	}
+
+	useCase := reconcilepayments.NewUseCase(
+		map[entities.ProcessorProvider]contracts.PaymentProcessor{
+			entities.Default:  &fakeProcessor{processed: processed, processorProvider: entities.Default},
+			entities.Fallback: &fakeProcessor{processed: processed, processorProvider: entities.Fallback},
+		},
+		local,
+		time.Minute,
+		5*time.Second,
+	)
+
+	_, err := useCase.LastReport()
+	require.ErrorIs(t, err, constants.ErrReconciliationNotFound)
+
+	report, err := useCase.Execute(&dtos.ReconciliationRequest{From: &start, To: &end}, constants.ReconciliationTriggerManual)
+	require.NoError(t, err)
+
+	assert.False(t, report.Consistent)
+	assert.Empty(t, report.Errors)
+	require.NotEmpty(t, report.Mismatches)
+
+	missing := 0
+
+	for _, mismatch := range report.Mismatches {
+		assert.Equal(t, entities.Default, mismatch.Processor)
+		assert.LessOrEqual(t, mismatch.To.Sub(mismatch.From), 5*time.Second)
+		assert.False(t, mismatch.To.Before(start.Add(42*time.Second)))
+		assert.False(t, mismatch.From.After(start.Add(43*time.Second)))
+
+		missing += mismatch.Remote.TotalRequests - mismatch.Local.TotalRequests
+	}
+
+	assert.Equal(t, 2, missing)
+	assert.Equal(t, 58, report.Totals[entities.Default].Local.TotalRequests)
+	assert.Equal(t, 60, report.Totals[entities.Default].Remote.TotalRequests)
+
+	report, err = useCase.Execute(&dtos.ReconciliationRequest{From: &start, To: &end, Repair: true}, constants.ReconciliationTriggerManual)
+	require.NoError(t, err)
+	assert.True(t, report.Consistent)
+
+	report, err = useCase.Execute(&dtos.ReconciliationRequest{From: &start, To: &end}, constants.ReconciliationTriggerManual)
+	require.NoError(t, err)
+
+	assert.True(t, report.Consistent)
+	assert.Empty(t, report.Mismatches)
+	assert.Equal(t, "1194.00", report.Totals[entities.Default].Local.TotalAmount.String())
+
+	lastReport, err := useCase.LastReport()
+	require.NoError(t, err)
+	assert.Same(t, report, lastReport)
+
+	_, err = useCase.Execute(&dtos.ReconciliationRequest{From: &end, To: &start}, constants.ReconciliationTriggerManual)
+	require.ErrorIs(t, err, constants.ErrInvalidReconciliationWindow)
+}
@@ -0,0 +1,20 @@
+package retrievepaymentstatus
+
+import (
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/contracts"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/entities"
+)
+
+type UseCase struct {
+	paymentStatusStore contracts.PaymentStatusStore
+}
+
+func NewUseCase(paymentStatusStore contracts.PaymentStatusStore) *UseCase {
+	return &UseCase{
+		paymentStatusStore: paymentStatusStore,
+	}
+}
+
+func (usecase *UseCase) Execute(correlationID string) (*entities.PaymentStatus, error) {
+	return usecase.paymentStatusStore.Get(correlationID)
+}
@@ -7,20 +7,12 @@ import (
 )
 
 type UseCase struct {
-	defaultPaymentProcessor   contracts.PaymentProcessor
-	secondaryPaymentProcessor contracts.PaymentProcessor
-	paymentStorage            contracts.Storage
+	paymentStorage contracts.Storage
 }
 
-func NewUseCase(
-	defaultPaymentProcessor contracts.PaymentProcessor,
-	secondaryPaymentProcessor contracts.PaymentProcessor,
-	paymentStorage contracts.Storage,
-) *UseCase {
+func NewUseCase(paymentStorage contracts.Storage) *UseCase {
 	return &UseCase{
-		defaultPaymentProcessor:   defaultPaymentProcessor,
-		secondaryPaymentProcessor: secondaryPaymentProcessor,
-		paymentStorage:            paymentStorage,
+		paymentStorage: paymentStorage,
 	}
 }
 
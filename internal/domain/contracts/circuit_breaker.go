@@ -1,7 +1,19 @@
 package contracts
 
+import "github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/entities"
+
 type CircuitBreaker[T any] interface {
+	CircuitBreakerObserver
 	Execute(operation func() (T, error), fallback func() (T, error)) (T, error)
+}
+
+// CircuitBreakerObserver exposes a breaker state whatever the type it returns.
+type CircuitBreakerObserver interface {
 	GetState() int32
 	GetCountFailure() int32
+	// Subscribe calls subscriber on every state change, on the goroutine that
+	// made it, so it must not block.
+	Subscribe(subscriber func(*entities.CircuitBreakerTransition))
+	// Transitions lists the last state changes, oldest first.
+	Transitions() []*entities.CircuitBreakerTransition
 }
@@ -0,0 +1,21 @@
+package contracts
+
+import (
+	"time"
+
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/entities"
+)
+
+// CircuitBreakerStore keeps breaker states where every instance sees them;
+// each call applies atomically.
+type CircuitBreakerStore interface {
+	Load(name string) (entities.CircuitBreakerStatus, error)
+	// Acquire half-opens an open breaker once recoveryTimeout passed and takes
+	// one of the probe slots while half-open, telling whether the call may go.
+	// Probe slots nobody reported back on within recoveryTimeout are freed.
+	Acquire(name string, recoveryTimeout time.Duration, probes int) (entities.CircuitBreakerStatus, bool, error)
+	// Record counts a call outcome, opening the breaker after failureThreshold
+	// failures in a row or a failed probe, and closing it on a success. Calls
+	// let through under another generation than the current one are ignored.
+	Record(name string, failed bool, failureThreshold int32, generation int64) (entities.CircuitBreakerStatus, error)
+}
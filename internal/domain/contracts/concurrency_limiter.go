@@ -0,0 +1,22 @@
+package contracts
+
+import (
+	"context"
+	"time"
+)
+
+type ConcurrencyLimiter interface {
+	// Acquire waits until one more call fits under the limit, or returns the
+	// context error once it is done.
+	Acquire(ctx context.Context) error
+	// Release ends a call, telling how long it took and whether it failed in
+	// a way that hints at overload.
+	Release(latency time.Duration, dropped bool)
+	// Cancel gives back a slot acquired for a call that was not made.
+	Cancel()
+	Limit() int
+	InFlight() int
+	// Subscribe calls subscriber with every new limit, on the goroutine that
+	// changed it, so it must not block.
+	Subscribe(subscriber func(limit int))
+}
@@ -0,0 +1,10 @@
+package contracts
+
+import "github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/entities"
+
+type DeadLetterStore interface {
+	Add(payment *entities.DeadLetterPayment) error
+	List() ([]*entities.DeadLetterPayment, error)
+	Get(correlationID string) (*entities.DeadLetterPayment, error)
+	Remove(correlationID string) (bool, error)
+}
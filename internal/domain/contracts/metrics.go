@@ -0,0 +1,6 @@
+package contracts
+
+type MetricsExporter interface {
+	// Render returns every metric in the Prometheus text exposition format.
+	Render() []byte
+}
@@ -0,0 +1,11 @@
+package contracts
+
+import "github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/entities"
+
+type PaymentQueue interface {
+	Enqueue(payment *entities.QueuedPayment) error
+	Ack(payment *entities.QueuedPayment) error
+	Pending() ([]*entities.QueuedPayment, error)
+	// Len counts the payments not acknowledged yet.
+	Len() (int, error)
+}
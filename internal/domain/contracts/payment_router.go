@@ -0,0 +1,7 @@
+package contracts
+
+type PaymentRouter interface {
+	// Routes lists the processors to try for the next payment, best first. An
+	// empty list means the payment is better off waiting for a while.
+	Routes() []PaymentProcessor
+}
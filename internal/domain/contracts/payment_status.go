@@ -0,0 +1,8 @@
+package contracts
+
+import "github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/entities"
+
+type PaymentStatusStore interface {
+	Record(correlationID string, transitions ...*entities.PaymentTransition) error
+	Get(correlationID string) (*entities.PaymentStatus, error)
+}
@@ -0,0 +1,29 @@
+package contracts
+
+import "github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/entities"
+
+type ProcessorHealthChecker interface {
+	// CheckHealth calls the processor health endpoint, which is rate limited.
+	CheckHealth() (*entities.ProcessorHealth, error)
+}
+
+type ProcessorHealthReader interface {
+	// ProcessorHealth returns the latest shared snapshot of a processor, false
+	// when nobody published one yet.
+	ProcessorHealth(processorProvider entities.ProcessorProvider) (entities.ProcessorHealth, bool)
+	// ProcessorsHealth returns the whole snapshot, which callers must not modify.
+	ProcessorsHealth() map[entities.ProcessorProvider]entities.ProcessorHealth
+}
+
+type HealthSnapshotStore interface {
+	Publish(snapshot map[entities.ProcessorProvider]entities.ProcessorHealth) error
+	// Load returns an empty snapshot once the published one expired.
+	Load() (map[entities.ProcessorProvider]entities.ProcessorHealth, error)
+}
+
+type LeaderElector interface {
+	// TryLead acquires the lease or renews it if this instance already holds it.
+	TryLead() (bool, error)
+	// Resign gives the lease up so another instance takes over right away.
+	Resign() error
+}
@@ -0,0 +1,22 @@
+package contracts
+
+import (
+	"time"
+
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/entities"
+)
+
+type RetryQueue interface {
+	Schedule(payment *entities.QueuedPayment, at time.Time) error
+	// Claim returns up to limit payments due at the given time, which stay
+	// claimed until acknowledged; a claim not acknowledged within
+	// constants.PaymentRetryLease is due again.
+	Claim(now time.Time, limit int) ([]*entities.QueuedPayment, error)
+	// Extend restarts the lease of a claimed payment at now, false when its
+	// lease ran out and it may have been claimed again since.
+	Extend(payment *entities.QueuedPayment, now time.Time) (bool, error)
+	// Ack releases a claimed payment once it was processed or scheduled again.
+	Ack(payment *entities.QueuedPayment) error
+	// Len counts the scheduled payments, due or not, and the claimed ones.
+	Len() (int, error)
+}
@@ -5,4 +5,9 @@ import "github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domai
 type Storage interface {
 	Save(payload *entities.PaymentPayloadStorage) error
 	Retrieve(payloadFilters *entities.PaymentSummaryFilters) (*entities.PaymentResultStorage, error)
+	// Claim atomically marks a correlationId as taken, returning false when it already was.
+	Claim(correlationID string) (bool, error)
+	Release(correlationID string) error
+	// Ping reports whether the backend can take writes right now.
+	Ping() error
 }
@@ -1,6 +1,24 @@
 package contracts
 
+import "time"
+
 type WorkerPoolManager interface {
-	Submit(callback func())
+	// Submit queues the callback as the pool overflow policy says, failing
+	// with constants.ErrWorkerPoolSaturated when it could not.
+	Submit(callback func()) error
+	// TrySubmit never waits for room in the queue.
+	TrySubmit(callback func()) error
 	Wait()
+	// Shutdown stops taking tasks, later submissions fail with
+	// constants.ErrWorkerPoolClosed, and waits for the submitted ones until the
+	// deadline, telling whether they all ended.
+	Shutdown(deadline time.Time) bool
+	// QueueDepth is how many submitted tasks wait for a worker.
+	QueueDepth() int
+	Capacity() int
+	// SetParallelism changes how many tasks run at once, growing the pool to fit.
+	SetParallelism(parallelism int)
+	Parallelism() int
+	// Overflow is one of the constants.WorkerPoolOverflow* policies.
+	Overflow() string
 }
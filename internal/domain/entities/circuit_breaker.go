@@ -0,0 +1,29 @@
+package entities
+
+import "time"
+
+// CircuitBreakerPolicy configures a breaker. The consecutive mode only reads
+// FailureThreshold and RecoveryTimeout, the distributed mode adds
+// HalfOpenProbes and CacheTTL, the sliding window mode reads everything else.
+type CircuitBreakerPolicy struct {
+	// Uncounted tells the errors that say nothing of the protected operation,
+	// counted neither as failures nor as successes. Nil counts every error.
+	Uncounted  func(err error) bool
+	Mode       string
+	WindowType string
+	// WindowSize counts calls for a count window and seconds for a time window.
+	WindowSize int
+	// MinimumCalls the window needs before its rates can open the breaker.
+	MinimumCalls     int
+	FailureThreshold int32
+	// rates in percent of the calls in the window.
+	FailureRateThreshold  float64
+	SlowCallRateThreshold float64
+	SlowCallDuration      time.Duration
+	RecoveryTimeout       time.Duration
+	// HalfOpenProbes is how many calls are let through at once to find out
+	// whether the protected operation recovered.
+	HalfOpenProbes int
+	// CacheTTL is how long a distributed breaker trusts the state it last read.
+	CacheTTL time.Duration
+}
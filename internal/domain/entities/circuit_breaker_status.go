@@ -0,0 +1,13 @@
+package entities
+
+import "time"
+
+// CircuitBreakerStatus is the shared state of a distributed breaker.
+// Generation goes up on every state change, so a call can tell whether the
+// breaker it was let through by is still the current one.
+type CircuitBreakerStatus struct {
+	OpenedAt   time.Time
+	Generation int64
+	State      int32
+	Failures   int32
+}
@@ -0,0 +1,13 @@
+package entities
+
+import "time"
+
+// CircuitBreakerTransition records a breaker changing state; From and To hold
+// constants.CircuitBreaker* values and Failures the count that led to it.
+type CircuitBreakerTransition struct {
+	At       time.Time
+	Reason   string
+	From     int32
+	To       int32
+	Failures int32
+}
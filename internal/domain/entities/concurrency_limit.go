@@ -0,0 +1,16 @@
+package entities
+
+import "time"
+
+// ConcurrencyLimitPolicy bounds the calls in flight to a processor. The limit
+// starts at InitialLimit and moves between MinLimit and MaxLimit as the mode
+// says; AIMD reads SlowCallDuration and Backoff, gradient reads Tolerance.
+type ConcurrencyLimitPolicy struct {
+	Mode             string
+	InitialLimit     int
+	MinLimit         int
+	MaxLimit         int
+	SlowCallDuration time.Duration
+	Backoff          float64
+	Tolerance        float64
+}
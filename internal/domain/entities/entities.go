@@ -1,11 +1,16 @@
 package entities
 
-import "time"
+import (
+	"maps"
+	"time"
+)
 
 type PaymentRequest struct {
+	// Hedge is set while the payment may also be sent to another processor.
+	Hedge         *PaymentHedge
 	CorrelationID string
 	RequestedAt   string
-	Amount        float64
+	Amount        Money
 }
 
 type PaymentResponse struct {
@@ -19,11 +24,64 @@ type PaymentSummaryFilters struct {
 }
 
 type Summary struct {
-	TotalRequests int     `json:"totalRequests"`
-	TotalAmount   float64 `json:"totalAmount"`
+	TotalRequests int   `json:"totalRequests"`
+	TotalAmount   Money `json:"totalAmount"`
 }
 
+// PaymentSummaryResponse has a summary for every processor in Processors and
+// mirrors the default and fallback ones in their own fields, the original shape.
 type PaymentSummaryResponse struct {
-	Default  Summary `json:"default"`
-	Fallback Summary `json:"fallback"`
+	Processors map[ProcessorProvider]Summary `json:"processors,omitempty"`
+	Default    Summary                       `json:"default"`
+	Fallback   Summary                       `json:"fallback"`
+}
+
+func NewPaymentSummaryResponse() PaymentSummaryResponse {
+	return PaymentSummaryResponse{
+		Processors: map[ProcessorProvider]Summary{
+			Default:  {TotalRequests: 0, TotalAmount: 0},
+			Fallback: {TotalRequests: 0, TotalAmount: 0},
+		},
+		Default:  Summary{TotalRequests: 0, TotalAmount: 0},
+		Fallback: Summary{TotalRequests: 0, TotalAmount: 0},
+	}
+}
+
+// Add accumulates summary into the processor totals.
+func (r *PaymentSummaryResponse) Add(processorProvider ProcessorProvider, summary Summary) {
+	if r.Processors == nil {
+		r.Processors = map[ProcessorProvider]Summary{}
+	}
+
+	total := r.Processors[processorProvider]
+	total.TotalRequests += summary.TotalRequests
+	total.TotalAmount += summary.TotalAmount
+
+	r.Processors[processorProvider] = total
+
+	switch processorProvider {
+	case Default:
+		r.Default = total
+	case Fallback:
+		r.Fallback = total
+	}
+}
+
+func (r *PaymentSummaryResponse) Get(processorProvider ProcessorProvider) Summary {
+	if summary, ok := r.Processors[processorProvider]; ok {
+		return summary
+	}
+
+	switch processorProvider {
+	case Default:
+		return r.Default
+	case Fallback:
+		return r.Fallback
+	default:
+		return Summary{TotalRequests: 0, TotalAmount: 0}
+	}
+}
+
+func (r *PaymentSummaryResponse) Equal(other *PaymentSummaryResponse) bool {
+	return r.Default == other.Default && r.Fallback == other.Fallback && maps.Equal(r.Processors, other.Processors)
 }
@@ -0,0 +1,12 @@
+package entities
+
+import "time"
+
+// HedgingPolicy lets a payment the best processor did not answer in time go to
+// the next one too, PaymentHedge settling which one owns it. The budget is the processor minimum response time, from
+// the shared health snapshot, times Factor and never under MinBudget.
+type HedgingPolicy struct {
+	MinBudget time.Duration
+	Factor    float64
+	Enabled   bool
+}
@@ -0,0 +1,128 @@
+package entities
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/constants"
+)
+
+const (
+	centsDigits   = 2
+	centsPerUnit  = 100
+	maxMoneyScale = 18
+)
+
+// Money is an amount in integer cents. It is read from and written to JSON as
+// a plain decimal number, without ever going through a float.
+type Money int64
+
+func NewMoneyFromCents(cents int64) Money {
+	return Money(cents)
+}
+
+// ParseMoney reads a decimal number such as "19.90", "19.9", "1990e-2" or "20"
+// exactly. Anything more precise than a cent is rejected instead of rounded.
+//
+//nolint:cyclop // a small hand written decimal parser
+func ParseMoney(value string) (Money, error) {
+	raw := strings.TrimSpace(value)
+
+	negative := strings.HasPrefix(raw, "-")
+	if negative || strings.HasPrefix(raw, "+") {
+		raw = raw[1:]
+	}
+
+	mantissa, exponent := raw, 0
+
+	if index := strings.IndexAny(raw, "eE"); index >= 0 {
+		parsedExponent, err := strconv.Atoi(raw[index+1:])
+		if err != nil {
+			return 0, constants.NewErrorWrapper(constants.ErrInvalidAmount, value)
+		}
+
+		mantissa, exponent = raw[:index], parsedExponent
+	}
+
+	integerPart, fractionPart, _ := strings.Cut(mantissa, ".")
+	digits := integerPart + fractionPart
+
+	if digits == "" || strings.ContainsFunc(digits, func(r rune) bool { return r < '0' || r > '9' }) {
+		return 0, constants.NewErrorWrapper(constants.ErrInvalidAmount, value)
+	}
+
+	shift := exponent - len(fractionPart) + centsDigits
+
+	switch {
+	case shift > maxMoneyScale:
+		return 0, constants.NewErrorWrapper(constants.ErrInvalidAmount, value)
+	case shift > 0:
+		digits += strings.Repeat("0", shift)
+	case shift < 0:
+		cut := max(len(digits)+shift, 0)
+
+		if strings.Trim(digits[cut:], "0") != "" {
+			return 0, constants.NewErrorWrapper(constants.ErrAmountTooPrecise, value)
+		}
+
+		digits = digits[:cut]
+	}
+
+	digits = strings.TrimLeft(digits, "0")
+	if digits == "" {
+		return 0, nil
+	}
+
+	cents, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return 0, constants.NewErrorWrapper(constants.ErrInvalidAmount, value)
+	}
+
+	if negative {
+		cents = -cents
+	}
+
+	return Money(cents), nil
+}
+
+func (m Money) Cents() int64 {
+	return int64(m)
+}
+
+func (m Money) String() string {
+	cents := int64(m)
+
+	sign := ""
+	if cents < 0 {
+		sign = "-"
+		cents = -cents
+	}
+
+	fraction := strconv.FormatInt(cents%centsPerUnit, 10)
+	if len(fraction) < centsDigits {
+		fraction = "0" + fraction
+	}
+
+	return sign + strconv.FormatInt(cents/centsPerUnit, 10) + "." + fraction
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON accepts both a JSON number and a quoted decimal string.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	raw := string(data)
+	if raw == "null" {
+		return nil
+	}
+
+	parsed, err := ParseMoney(strings.Trim(raw, `"`))
+	if err != nil {
+		return err
+	}
+
+	*m = parsed
+
+	return nil
+}
@@ -0,0 +1,83 @@
+package entities_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/constants"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/helpers"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMoney(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]int64{
+		"19.90":   1990,
+		"19.9":    1990,
+		"19":      1900,
+		"0.01":    1,
+		".5":      50,
+		"1990e-2": 1990,
+		"1.5E3":   150000,
+		"-3.10":   -310,
+		"+5":      500,
+		"19.900":  1990,
+		"0":       0,
+		" 7.00 ":  700,
+	}
+
+	for raw, cents := range cases {
+		money, err := entities.ParseMoney(raw)
+		require.NoError(t, err, raw)
+		assert.Equal(t, cents, money.Cents(), raw)
+	}
+
+	for _, raw := range []string{"", "-", "abc", "1.2.3", "1e", "1x", "--5", "+-5", "-+5", "++5"} {
+		_, err := entities.ParseMoney(raw)
+		assert.ErrorIs(t, err, constants.ErrInvalidAmount, raw)
+	}
+
+	_, err := entities.ParseMoney("19.999")
+	assert.True(t, errors.Is(err, constants.ErrAmountTooPrecise))
+}
+
+func TestMoneyString(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "19.90", entities.NewMoneyFromCents(1990).String())
+	assert.Equal(t, "0.05", entities.NewMoneyFromCents(5).String())
+	assert.Equal(t, "-1.01", entities.NewMoneyFromCents(-101).String())
+}
+
+func TestMoneyJSONIsExact(t *testing.T) {
+	t.Parallel()
+
+	summary := entities.Summary{}
+
+	// 0.1 added ten thousand times drifts as a float64
+	for range 10_000 {
+		summary.TotalAmount += entities.NewMoneyFromCents(10)
+	}
+
+	body, err := helpers.Marshal(summary)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"totalRequests":0,"totalAmount":1000.00}`, string(body))
+
+	requestBody, err := helpers.Marshal(map[string]any{"amount": entities.NewMoneyFromCents(1990)})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"amount":19.90}`, string(requestBody))
+
+	var decoded struct {
+		Number entities.Money `json:"number"`
+		Quoted entities.Money `json:"quoted"`
+	}
+
+	require.NoError(t, helpers.Unmarshal([]byte(`{"number":19.9,"quoted":"0.30"}`), &decoded))
+	assert.Equal(t, int64(1990), decoded.Number.Cents())
+	assert.Equal(t, int64(30), decoded.Quoted.Cents())
+
+	require.Error(t, helpers.Unmarshal([]byte(`{"number":1.001}`), &decoded))
+}
@@ -0,0 +1,63 @@
+package entities
+
+import "sync"
+
+// PaymentHedge settles which processor owns a payment sent to two of them at
+// once, with the same correlationId, so it is counted under exactly one.
+//
+// The first processor to answer it took the payment owns it: a 200, or a 422
+// telling an earlier request for this correlationId already went through
+// there. The other one gives up its retries from then on; should it answer
+// it took the payment too, the payment is still counted under the owner only.
+type PaymentHedge struct {
+	settled chan struct{}
+	owner   ProcessorProvider
+	mutex   sync.Mutex
+}
+
+func NewPaymentHedge() *PaymentHedge {
+	return &PaymentHedge{settled: make(chan struct{})}
+}
+
+// Settle tells the processor took the payment, true when it owns it. Without
+// a hedge every processor owns what it took.
+func (h *PaymentHedge) Settle(processorProvider ProcessorProvider) bool {
+	if h == nil {
+		return true
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if h.owner == "" {
+		h.owner = processorProvider
+		close(h.settled)
+	}
+
+	return h.owner == processorProvider
+}
+
+// Lost tells another processor owns the payment, so nothing must be sent to
+// this one anymore.
+func (h *PaymentHedge) Lost(processorProvider ProcessorProvider) bool {
+	owner, ok := h.Owner()
+
+	return ok && owner != processorProvider
+}
+
+// Owner is the processor that took the payment first, false while none did.
+func (h *PaymentHedge) Owner() (ProcessorProvider, bool) {
+	if h == nil {
+		return "", false
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	return h.owner, h.owner != ""
+}
+
+// Settled is closed once a processor owns the payment.
+func (h *PaymentHedge) Settled() <-chan struct{} {
+	return h.settled
+}
@@ -0,0 +1,41 @@
+package entities
+
+import "time"
+
+type PaymentState string
+
+const processedStatePrefix = "processed-"
+
+const (
+	PaymentReceived          PaymentState = "received"
+	PaymentQueued            PaymentState = "queued"
+	PaymentProcessing        PaymentState = "processing"
+	PaymentProcessedDefault  PaymentState = PaymentState(processedStatePrefix + Default)
+	PaymentProcessedFallback PaymentState = PaymentState(processedStatePrefix + Fallback)
+	PaymentFailed            PaymentState = "failed"
+	PaymentDeferred          PaymentState = "deferred"
+	PaymentDeadLettered      PaymentState = "dead-lettered"
+)
+
+// ProcessedState maps the processor that settled a payment to its final state.
+func ProcessedState(processorProvider ProcessorProvider) PaymentState {
+	return PaymentState(processedStatePrefix + processorProvider)
+}
+
+type PaymentTransition struct {
+	At        time.Time         `json:"at"`
+	State     PaymentState      `json:"state"`
+	Processor ProcessorProvider `json:"processor,omitempty"`
+	Error     string            `json:"error,omitempty"`
+	Attempts  int               `json:"attempts"`
+}
+
+type PaymentStatus struct {
+	UpdatedAt     time.Time           `json:"updatedAt"`
+	CorrelationID string              `json:"correlationId"`
+	State         PaymentState        `json:"state"`
+	Processor     ProcessorProvider   `json:"processor,omitempty"`
+	LastError     string              `json:"lastError,omitempty"`
+	History       []PaymentTransition `json:"history"`
+	Attempts      int                 `json:"attempts"`
+}
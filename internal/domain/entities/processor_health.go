@@ -0,0 +1,26 @@
+package entities
+
+import "time"
+
+// HealthSource tells how the leader came up with a processor health.
+type HealthSource string
+
+const (
+	// HealthSourceEndpoint is a fresh answer from the processor health endpoint.
+	HealthSourceEndpoint HealthSource = "health-endpoint"
+	// HealthSourceCached is the previous answer, carried over while the health
+	// endpoint was rate limited.
+	HealthSourceCached HealthSource = "cached"
+	// HealthSourceUnreachable means the health endpoint could not be reached,
+	// and the processor is assumed to be failing.
+	HealthSourceUnreachable HealthSource = "unreachable"
+)
+
+// ProcessorHealth is what the leader last knew about a processor health.
+type ProcessorHealth struct {
+	LastChecked     time.Time    `json:"lastChecked"`
+	LastError       string       `json:"lastError,omitempty"`
+	Source          HealthSource `json:"source"`
+	MinResponseTime int          `json:"minResponseTime"`
+	Failing         bool         `json:"failing"`
+}
@@ -0,0 +1,26 @@
+package entities
+
+import "time"
+
+type QueuedPayment struct {
+	// LeaseUntil is when the claim on the retry queue entry runs out.
+	LeaseUntil time.Time
+	// ID is the intake queue entry, empty once acknowledged.
+	ID string
+	// RetryID is the claimed retry queue entry, empty once acknowledged.
+	RetryID       string
+	CorrelationID string
+	LastError     string
+	Amount        Money
+	Attempts      int
+	// Deferrals counts the times the payment was put off without an attempt.
+	Deferrals int
+}
+
+type DeadLetterPayment struct {
+	FailedAt      time.Time `json:"failedAt"`
+	CorrelationID string    `json:"correlationId"`
+	LastError     string    `json:"lastError"`
+	Amount        Money     `json:"amount"`
+	Attempts      int       `json:"attempts"`
+}
@@ -0,0 +1,32 @@
+package entities
+
+import "time"
+
+// ReconciliationMismatch is a bucket where the local summary of a processor
+// payments differs from what the processor itself reports.
+type ReconciliationMismatch struct {
+	From      time.Time         `json:"from"`
+	To        time.Time         `json:"to"`
+	Processor ProcessorProvider `json:"processor"`
+	Local     Summary           `json:"local"`
+	Remote    Summary           `json:"remote"`
+	Repaired  bool              `json:"repaired"`
+}
+
+type ReconciliationTotals struct {
+	Local  Summary `json:"local"`
+	Remote Summary `json:"remote"`
+}
+
+type ReconciliationReport struct {
+	StartedAt  time.Time                                  `json:"startedAt"`
+	FinishedAt time.Time                                  `json:"finishedAt"`
+	From       time.Time                                  `json:"from"`
+	To         time.Time                                  `json:"to"`
+	Totals     map[ProcessorProvider]ReconciliationTotals `json:"totals"`
+	Trigger    string                                     `json:"trigger"`
+	Mismatches []ReconciliationMismatch                   `json:"mismatches"`
+	Errors     []string                                   `json:"errors"`
+	Consistent bool                                       `json:"consistent"`
+	Repair     bool                                       `json:"repair"`
+}
@@ -7,11 +7,22 @@ const (
 	Fallback ProcessorProvider = "fallback"
 )
 
+// ProcessorConfig describes a payment processor to route payments to. Fee is
+// the rate it charges per payment, e.g. 0.05; Weight is only used for
+// weighted random routing.
+type ProcessorConfig struct {
+	Name     ProcessorProvider `json:"name"`
+	URL      string            `json:"url"`
+	Fee      float64           `json:"fee"`
+	Priority int               `json:"priority"`
+	Weight   int               `json:"weight"`
+}
+
 type PaymentPayloadStorage struct {
 	ID                string
 	ProcessorProvider ProcessorProvider
 	RequestedAt       string
-	Amount            float64
+	Amount            Money
 }
 
 type PaymentResultStorage struct {
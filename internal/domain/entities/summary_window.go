@@ -0,0 +1,69 @@
+package entities
+
+const millisecondsPerSecond = 1000
+
+type MillisecondRange struct {
+	From int64
+	To   int64
+}
+
+// SecondWindow splits a summary range into the whole seconds it covers, which
+// can be answered from per second buckets, and the partial edges around them,
+// which still need the individual entries.
+type SecondWindow struct {
+	Edges       []MillisecondRange
+	FirstSecond int64
+	LastSecond  int64
+	Unbounded   bool
+}
+
+func NewSecondWindow(filters *PaymentSummaryFilters) *SecondWindow {
+	if filters == nil || filters.From == nil || filters.To == nil {
+		return &SecondWindow{Unbounded: true}
+	}
+
+	from := filters.From.UnixMilli()
+	to := filters.To.UnixMilli()
+
+	window := &SecondWindow{
+		FirstSecond: (from + millisecondsPerSecond - 1) / millisecondsPerSecond,
+		LastSecond:  (to+1)/millisecondsPerSecond - 1,
+	}
+
+	if from > to {
+		return window
+	}
+
+	if !window.HasFullSeconds() {
+		window.Edges = []MillisecondRange{{From: from, To: to}}
+
+		return window
+	}
+
+	if firstFullMillisecond := window.FirstSecond * millisecondsPerSecond; from < firstFullMillisecond {
+		window.Edges = append(window.Edges, MillisecondRange{From: from, To: firstFullMillisecond - 1})
+	}
+
+	if afterLastFullMillisecond := (window.LastSecond + 1) * millisecondsPerSecond; to >= afterLastFullMillisecond {
+		window.Edges = append(window.Edges, MillisecondRange{From: afterLastFullMillisecond, To: to})
+	}
+
+	return window
+}
+
+func (w *SecondWindow) HasFullSeconds() bool {
+	return w.Unbounded || w.FirstSecond <= w.LastSecond
+}
+
+// Seconds returns the whole seconds of the window between earliest and latest,
+// so reading buckets costs the range asked for rather than everything stored.
+func (w *SecondWindow) Seconds(earliest, latest int64) (int64, int64, bool) {
+	first, last := earliest, latest
+
+	if !w.Unbounded {
+		first = max(first, w.FirstSecond)
+		last = min(last, w.LastSecond)
+	}
+
+	return first, last, first <= last
+}
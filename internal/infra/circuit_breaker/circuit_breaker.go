@@ -4,17 +4,22 @@ import (
 	"reflect"
 	"sync/atomic"
 	"time"
+
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/constants"
 )
 
 const (
-	Closed int32 = iota
-	Open
-	HalfOpen
+	Closed   = constants.CircuitBreakerClosed
+	Open     = constants.CircuitBreakerOpen
+	HalfOpen = constants.CircuitBreakerHalfOpen
 )
 
 type CircuitBreaker[T any] struct {
-	lastFailureTime  atomic.Value
-	typeName         string
+	lastFailureTime atomic.Value
+	// uncounted is the policy Uncounted, see NewFromPolicy.
+	uncounted func(err error) bool
+	typeName  string
+	events
 	state            atomic.Int32
 	failureCount     atomic.Int32
 	failureThreshold int32
@@ -47,13 +52,19 @@ func (cb *CircuitBreaker[T]) Execute(
 	if cb.state.Load() == Open {
 		lastFailureTime, ok := cb.lastFailureTime.Load().(time.Time)
 		if ok && time.Since(lastFailureTime) > cb.recoveryTimeout {
-			cb.state.CompareAndSwap(Open, HalfOpen)
+			if cb.state.CompareAndSwap(Open, HalfOpen) {
+				cb.publish(Open, HalfOpen, reasonFor(Open, HalfOpen), cb.failureCount.Load())
+			}
 		} else {
 			return fallback()
 		}
 	}
 
 	result, err := operation()
+	if err != nil && cb.uncounted != nil && cb.uncounted(err) {
+		return fallback()
+	}
+
 	if err != nil {
 		cb.handleFailure()
 
@@ -70,8 +81,11 @@ func (cb *CircuitBreaker[T]) handleFailure() {
 	currentState := cb.state.Load()
 
 	if currentFailures >= cb.failureThreshold || currentState == HalfOpen {
-		cb.state.CompareAndSwap(currentState, Open)
 		cb.lastFailureTime.Store(time.Now())
+
+		if currentState != Open && cb.state.CompareAndSwap(currentState, Open) {
+			cb.publish(currentState, Open, reasonFor(currentState, Open), currentFailures)
+		}
 	}
 }
 
@@ -84,10 +98,12 @@ func (cb *CircuitBreaker[T]) GetCountFailure() int32 {
 }
 
 func (cb *CircuitBreaker[T]) reset() {
-	cb.failureCount.Store(0)
+	failures := cb.failureCount.Swap(0)
 	currentState := cb.state.Load()
 
-	cb.state.CompareAndSwap(currentState, Closed)
+	if currentState != Closed && cb.state.CompareAndSwap(currentState, Closed) {
+		cb.publish(currentState, Closed, reasonFor(currentState, Closed), failures)
+	}
 
 	cb.lastFailureTime.Store(time.Time{})
 }
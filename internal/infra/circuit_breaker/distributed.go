@@ -0,0 +1,176 @@
+package circuitbreaker
+
+import (
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/contracts"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/entities"
+)
+
+type cachedStatus struct {
+	fetchedAt time.Time
+	status    entities.CircuitBreakerStatus
+}
+
+// Distributed is a consecutive failures breaker whose state lives in a store
+// shared by every instance, so one opening it spares the others the calls.
+// The last state read is trusted for the policy CacheTTL: while it says closed,
+// calls go through without asking the store and only failures are written to it.
+type Distributed[T any] struct {
+	events
+	store  contracts.CircuitBreakerStore
+	cached atomic.Pointer[cachedStatus]
+	name   string
+	policy entities.CircuitBreakerPolicy
+}
+
+func NewDistributed[T any](
+	name string,
+	store contracts.CircuitBreakerStore,
+	policy entities.CircuitBreakerPolicy,
+) *Distributed[T] {
+	cb := &Distributed[T]{
+		store:  store,
+		name:   name,
+		policy: withDefaults(policy),
+	}
+
+	cb.cached.Store(&cachedStatus{})
+
+	return cb
+}
+
+func (cb *Distributed[T]) Execute(
+	operation func() (T, error),
+	fallback func() (T, error),
+) (T, error) {
+	generation, permitted := cb.acquire(time.Now())
+	if !permitted {
+		return fallback()
+	}
+
+	result, err := operation()
+	if uncounted(cb.policy, err) {
+		// a probe slot taken for it is freed with the store lease
+		return fallback()
+	}
+
+	cb.record(err != nil, generation)
+
+	if err != nil {
+		return fallback()
+	}
+
+	return result, nil
+}
+
+func (cb *Distributed[T]) GetState() int32 {
+	return cb.status().State
+}
+
+func (cb *Distributed[T]) GetCountFailure() int32 {
+	return cb.status().Failures
+}
+
+// status returns the cached state, reading it again once stale.
+func (cb *Distributed[T]) status() entities.CircuitBreakerStatus {
+	cached := cb.cached.Load()
+	if time.Since(cached.fetchedAt) < cb.policy.CacheTTL {
+		return cached.status
+	}
+
+	status, err := cb.store.Load(cb.name)
+	if err != nil {
+		cb.logError("error loading circuit breaker", err)
+
+		return cached.status
+	}
+
+	cb.cache(status)
+
+	return cb.cached.Load().status
+}
+
+// acquire tells whether the call may go and the breaker generation it goes
+// under, which its outcome is recorded against.
+func (cb *Distributed[T]) acquire(now time.Time) (int64, bool) {
+	cached := cb.cached.Load()
+
+	if now.Sub(cached.fetchedAt) < cb.policy.CacheTTL {
+		switch cached.status.State {
+		case Closed:
+			return cached.status.Generation, true
+		case Open:
+			if now.Sub(cached.status.OpenedAt) < cb.policy.RecoveryTimeout {
+				return cached.status.Generation, false
+			}
+		}
+	}
+
+	status, permitted, err := cb.store.Acquire(cb.name, cb.policy.RecoveryTimeout, cb.policy.HalfOpenProbes)
+	if err != nil {
+		// without the store, keep going on what was seen last
+		cb.logError("error acquiring circuit breaker", err)
+
+		return cached.status.Generation, cached.status.State != Open
+	}
+
+	cb.cache(status)
+
+	return status.Generation, permitted
+}
+
+func (cb *Distributed[T]) record(failed bool, generation int64) {
+	cached := cb.cached.Load()
+
+	// nothing to reset after a success on a breaker that was clean
+	if !failed && cached.status.State == Closed && cached.status.Failures == 0 {
+		return
+	}
+
+	status, err := cb.store.Record(cb.name, failed, cb.policy.FailureThreshold, generation)
+	if err != nil {
+		cb.logError("error recording circuit breaker call", err)
+
+		return
+	}
+
+	cb.cache(status)
+}
+
+// cache keeps the status read and publishes the state changes this instance
+// sees, whichever instance made them. Reads answered out of order are told
+// apart by their generation: an older one is dropped rather than rolling the
+// cache back, and only a newer one publishes a transition.
+func (cb *Distributed[T]) cache(status entities.CircuitBreakerStatus) {
+	next := &cachedStatus{fetchedAt: time.Now(), status: status}
+
+	for {
+		previous := cb.cached.Load()
+		if status.Generation < previous.status.Generation {
+			return
+		}
+
+		if !cb.cached.CompareAndSwap(previous, next) {
+			continue
+		}
+
+		if status.Generation > previous.status.Generation && previous.status.State != status.State {
+			cb.publish(previous.status.State, status.State, reasonFor(previous.status.State, status.State), status.Failures)
+		}
+
+		return
+	}
+}
+
+func (cb *Distributed[T]) logError(action string, err error) {
+	go log.Print(
+		map[string]interface{}{
+			"circuit_breaker": cb.name,
+			"action":          action,
+			"error":           err,
+		},
+	)
+}
@@ -0,0 +1,194 @@
+package circuitbreaker_test
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/constants"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/entities"
+	circuitbreaker "github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/infra/circuit_breaker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sharedStore plays the Redis scripts for a single breaker.
+type sharedStore struct {
+	status entities.CircuitBreakerStatus
+	calls  atomic.Int32
+	probes int
+	mutex  sync.Mutex
+}
+
+func (s *sharedStore) Load(string) (entities.CircuitBreakerStatus, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.calls.Add(1)
+
+	return s.status, nil
+}
+
+func (s *sharedStore) Acquire(_ string, recoveryTimeout time.Duration, probes int) (entities.CircuitBreakerStatus, bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.calls.Add(1)
+
+	if s.status.State == constants.CircuitBreakerOpen {
+		if time.Since(s.status.OpenedAt) < recoveryTimeout {
+			return s.status, false, nil
+		}
+
+		s.status.State = constants.CircuitBreakerHalfOpen
+		s.status.Generation++
+		s.probes = 0
+	}
+
+	if s.status.State == constants.CircuitBreakerHalfOpen {
+		s.probes++
+
+		return s.status, s.probes <= probes, nil
+	}
+
+	return s.status, true, nil
+}
+
+func (s *sharedStore) Record(
+	_ string,
+	failed bool,
+	failureThreshold int32,
+	generation int64,
+) (entities.CircuitBreakerStatus, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.calls.Add(1)
+
+	switch {
+	case generation != s.status.Generation:
+	case failed:
+		s.status.Failures++
+
+		if s.status.State == constants.CircuitBreakerHalfOpen || s.status.Failures >= failureThreshold {
+			s.status.State = constants.CircuitBreakerOpen
+			s.status.OpenedAt = time.Now()
+			s.status.Generation++
+		}
+	default:
+		if s.status.State != constants.CircuitBreakerClosed {
+			s.status.Generation++
+		}
+
+		s.status = entities.CircuitBreakerStatus{Generation: s.status.Generation}
+	}
+
+	return s.status, nil
+}
+
+func distributedPolicy() entities.CircuitBreakerPolicy {
+	return entities.CircuitBreakerPolicy{
+		Mode:             constants.CircuitBreakerModeDistributed,
+		FailureThreshold: 2,
+		RecoveryTimeout:  100 * time.Millisecond,
+		HalfOpenProbes:   1,
+		CacheTTL:         10 * time.Millisecond,
+	}
+}
+
+func TestDistributedStateIsSharedBetweenInstances(t *testing.T) {
+	t.Parallel()
+
+	store := &sharedStore{}
+
+	api01 := circuitbreaker.NewDistributed[int]("payments", store, distributedPolicy())
+	api02 := circuitbreaker.NewDistributed[int]("payments", store, distributedPolicy())
+
+	_, _ = api01.Execute(fail, fallbackValue)
+	_, _ = api01.Execute(fail, fallbackValue)
+
+	assert.Equal(t, circuitbreaker.Open, api01.GetState())
+
+	// api02 never failed itself but stops calling once its cache expires
+	time.Sleep(distributedPolicy().CacheTTL)
+
+	result, err := api02.Execute(func() (int, error) {
+		t.Fatal("should not call operation when another instance opened the breaker")
+
+		return 0, nil
+	}, fallbackValue)
+	require.NoError(t, err)
+	assert.Equal(t, -1, result)
+	assert.Equal(t, int32(2), api02.GetCountFailure())
+
+	// after the recovery timeout a single probe closes it for both
+	time.Sleep(distributedPolicy().RecoveryTimeout)
+
+	result, err = api02.Execute(succeed, fallbackValue)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result)
+
+	time.Sleep(distributedPolicy().CacheTTL)
+
+	assert.Equal(t, circuitbreaker.Closed, api01.GetState())
+}
+
+func TestDistributedClosedSuccessesSkipTheStore(t *testing.T) {
+	t.Parallel()
+
+	store := &sharedStore{}
+
+	cb := circuitbreaker.NewDistributed[int]("payments", store, distributedPolicy())
+
+	_, _ = cb.Execute(succeed, fallbackValue)
+
+	calls := store.calls.Load()
+
+	for range 50 {
+		_, _ = cb.Execute(succeed, fallbackValue)
+	}
+
+	assert.LessOrEqual(t, store.calls.Load()-calls, int32(1), "cached closed state serves the calls")
+}
+
+// replayedStore answers loads with the given statuses in turn, as concurrent
+// reads landing out of order would.
+type replayedStore struct {
+	loads []entities.CircuitBreakerStatus
+	sharedStore
+}
+
+func (s *replayedStore) Load(string) (entities.CircuitBreakerStatus, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	status := s.loads[0]
+	s.loads = s.loads[1:]
+
+	return status, nil
+}
+
+func TestDistributedPublishesOnlyNewerGenerations(t *testing.T) {
+	t.Parallel()
+
+	opened := time.Now()
+	store := &replayedStore{loads: []entities.CircuitBreakerStatus{
+		{State: constants.CircuitBreakerOpen, Failures: 2, OpenedAt: opened, Generation: 1},
+		{State: constants.CircuitBreakerClosed, Failures: 1},
+		{State: constants.CircuitBreakerOpen, Failures: 2, OpenedAt: opened, Generation: 1},
+	}}
+
+	cb := circuitbreaker.NewDistributed[int]("payments", store, distributedPolicy())
+
+	for range store.loads {
+		assert.Equal(t, circuitbreaker.Open, cb.GetState())
+
+		time.Sleep(distributedPolicy().CacheTTL)
+	}
+
+	transitions := cb.Transitions()
+	require.Len(t, transitions, 1)
+	assert.Equal(t, circuitbreaker.Closed, transitions[0].From)
+	assert.Equal(t, circuitbreaker.Open, transitions[0].To)
+}
@@ -0,0 +1,80 @@
+package circuitbreaker
+
+import (
+	"sync"
+	"time"
+
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/constants"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/entities"
+)
+
+// events keeps the last constants.CircuitBreakerHistorySize transitions of a
+// breaker in a ring and hands each new one to the subscribers. Its zero value
+// is ready to use.
+type events struct {
+	subscribers []func(*entities.CircuitBreakerTransition)
+	history     []*entities.CircuitBreakerTransition
+	next        int
+	mutex       sync.RWMutex
+}
+
+func (e *events) Subscribe(subscriber func(*entities.CircuitBreakerTransition)) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.subscribers = append(e.subscribers, subscriber)
+}
+
+func (e *events) Transitions() []*entities.CircuitBreakerTransition {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	transitions := make([]*entities.CircuitBreakerTransition, 0, len(e.history))
+	transitions = append(transitions, e.history[e.next:]...)
+
+	return append(transitions, e.history[:e.next]...)
+}
+
+func (e *events) publish(from, to int32, reason string, failures int32) {
+	transition := &entities.CircuitBreakerTransition{
+		At:       time.Now(),
+		Reason:   reason,
+		From:     from,
+		To:       to,
+		Failures: failures,
+	}
+
+	e.mutex.Lock()
+
+	if len(e.history) < constants.CircuitBreakerHistorySize {
+		e.history = append(e.history, transition)
+	} else {
+		e.history[e.next] = transition
+		e.next = (e.next + 1) % len(e.history)
+	}
+
+	subscribers := e.subscribers
+
+	e.mutex.Unlock()
+
+	for _, subscriber := range subscribers {
+		subscriber(transition)
+	}
+}
+
+// reasonFor names a transition from its states alone, for breakers that only
+// count consecutive failures.
+func reasonFor(from, to int32) string {
+	switch {
+	case to == Open && from == HalfOpen:
+		return constants.CircuitBreakerReasonProbeFailed
+	case to == Open:
+		return constants.CircuitBreakerReasonFailureThreshold
+	case to == HalfOpen:
+		return constants.CircuitBreakerReasonRecoveryTimeout
+	case from == HalfOpen:
+		return constants.CircuitBreakerReasonProbesSucceeded
+	default:
+		return constants.CircuitBreakerReasonCallSucceeded
+	}
+}
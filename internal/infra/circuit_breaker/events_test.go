@@ -0,0 +1,105 @@
+package circuitbreaker_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/constants"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/entities"
+	circuitbreaker "github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/infra/circuit_breaker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransitionsArePublishedAndKept(t *testing.T) {
+	t.Parallel()
+
+	cb := circuitbreaker.New[int](2, 20*time.Millisecond)
+
+	var (
+		published []*entities.CircuitBreakerTransition
+		mutex     sync.Mutex
+	)
+
+	cb.Subscribe(func(transition *entities.CircuitBreakerTransition) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		published = append(published, transition)
+	})
+
+	_, _ = cb.Execute(fail, fallbackValue)
+	_, _ = cb.Execute(fail, fallbackValue)
+
+	time.Sleep(30 * time.Millisecond)
+
+	_, _ = cb.Execute(succeed, fallbackValue)
+
+	transitions := cb.Transitions()
+	require.Len(t, transitions, 3)
+
+	assert.Equal(t, circuitbreaker.Closed, transitions[0].From)
+	assert.Equal(t, circuitbreaker.Open, transitions[0].To)
+	assert.Equal(t, constants.CircuitBreakerReasonFailureThreshold, transitions[0].Reason)
+	assert.Equal(t, int32(2), transitions[0].Failures)
+
+	assert.Equal(t, circuitbreaker.HalfOpen, transitions[1].To)
+	assert.Equal(t, constants.CircuitBreakerReasonRecoveryTimeout, transitions[1].Reason)
+
+	assert.Equal(t, circuitbreaker.Closed, transitions[2].To)
+	assert.Equal(t, constants.CircuitBreakerReasonProbesSucceeded, transitions[2].Reason)
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	assert.Equal(t, transitions, published)
+}
+
+func TestTransitionsHistoryIsBounded(t *testing.T) {
+	t.Parallel()
+
+	cb := circuitbreaker.New[int](1, 0)
+
+	// every failure opens, every success after the timeout half-opens and closes
+	for range constants.CircuitBreakerHistorySize {
+		_, _ = cb.Execute(fail, fallbackValue)
+		time.Sleep(time.Millisecond)
+		_, _ = cb.Execute(succeed, fallbackValue)
+	}
+
+	transitions := cb.Transitions()
+	require.Len(t, transitions, constants.CircuitBreakerHistorySize)
+
+	for index := 1; index < len(transitions); index++ {
+		assert.False(t, transitions[index].At.Before(transitions[index-1].At), "kept oldest first")
+	}
+
+	assert.Equal(t, circuitbreaker.Closed, transitions[len(transitions)-1].To)
+}
+
+func TestSlidingWindowTransitionTellsWhichRateWasCrossed(t *testing.T) {
+	t.Parallel()
+
+	cb := circuitbreaker.NewSlidingWindow[int](entities.CircuitBreakerPolicy{
+		WindowSize:            4,
+		MinimumCalls:          4,
+		SlowCallRateThreshold: 50,
+		SlowCallDuration:      time.Millisecond,
+	})
+
+	slow := func() (int, error) {
+		time.Sleep(2 * time.Millisecond)
+
+		return 1, nil
+	}
+
+	for range 4 {
+		_, _ = cb.Execute(slow, fallbackValue)
+	}
+
+	transitions := cb.Transitions()
+	require.Len(t, transitions, 1)
+	assert.Equal(t, circuitbreaker.Open, transitions[0].To)
+	assert.Equal(t, constants.CircuitBreakerReasonSlowCallRate, transitions[0].Reason)
+}
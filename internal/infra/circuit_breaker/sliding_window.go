@@ -0,0 +1,244 @@
+package circuitbreaker
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/constants"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/contracts"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/entities"
+)
+
+const percent = 100
+
+// NewFromPolicy builds the breaker the policy mode asks for.
+func NewFromPolicy[T any](policy entities.CircuitBreakerPolicy) contracts.CircuitBreaker[T] {
+	if policy.Mode == constants.CircuitBreakerModeSlidingWindow {
+		return NewSlidingWindow[T](policy)
+	}
+
+	circuitBreaker := New[T](policy.FailureThreshold, policy.RecoveryTimeout)
+	circuitBreaker.uncounted = policy.Uncounted
+
+	return circuitBreaker
+}
+
+// SlidingWindow opens when the share of failed or slow calls among the last
+// ones crosses its thresholds, once the window saw enough calls to tell. After
+// the recovery timeout it lets a limited number of probe calls through and
+// closes again only if those went well, every other caller getting the
+// fallback meanwhile.
+type SlidingWindow[T any] struct {
+	events
+	window     window
+	openedAt   time.Time
+	policy     entities.CircuitBreakerPolicy
+	generation uint64
+	probes     int
+	probed     outcomes
+	mutex      sync.Mutex
+	state      atomic.Int32
+	failures   atomic.Int32
+}
+
+func NewSlidingWindow[T any](policy entities.CircuitBreakerPolicy) *SlidingWindow[T] {
+	policy = withDefaults(policy)
+
+	var callWindow window = newCountWindow(policy.WindowSize)
+	if policy.WindowType == constants.CircuitBreakerWindowTime {
+		callWindow = newTimeWindow(policy.WindowSize)
+	}
+
+	return &SlidingWindow[T]{
+		window: callWindow,
+		policy: policy,
+	}
+}
+
+func (cb *SlidingWindow[T]) Execute(
+	operation func() (T, error),
+	fallback func() (T, error),
+) (T, error) {
+	generation, permitted := cb.acquire(time.Now())
+	if !permitted {
+		return fallback()
+	}
+
+	startedAt := time.Now()
+
+	result, err := operation()
+	if uncounted(cb.policy, err) {
+		cb.cancel(generation)
+
+		return fallback()
+	}
+
+	cb.record(generation, time.Now(), err != nil, time.Since(startedAt) >= cb.policy.SlowCallDuration)
+
+	if err != nil {
+		return fallback()
+	}
+
+	return result, nil
+}
+
+func (cb *SlidingWindow[T]) GetState() int32 {
+	return cb.state.Load()
+}
+
+// GetCountFailure counts the failures in the window, or among the probes
+// while half-open.
+func (cb *SlidingWindow[T]) GetCountFailure() int32 {
+	return cb.failures.Load()
+}
+
+// acquire tells whether a call may go through, and under which generation of
+// the state so its outcome is dropped if the state changed meanwhile.
+func (cb *SlidingWindow[T]) acquire(now time.Time) (uint64, bool) {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	state := cb.state.Load()
+
+	if state == Open {
+		if now.Sub(cb.openedAt) < cb.policy.RecoveryTimeout {
+			return cb.generation, false
+		}
+
+		cb.transition(HalfOpen, now, constants.CircuitBreakerReasonRecoveryTimeout)
+
+		state = HalfOpen
+	}
+
+	if state == HalfOpen {
+		if cb.probes >= cb.policy.HalfOpenProbes {
+			return cb.generation, false
+		}
+
+		cb.probes++
+	}
+
+	return cb.generation, true
+}
+
+func (cb *SlidingWindow[T]) record(generation uint64, now time.Time, failure, slow bool) {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	if generation != cb.generation {
+		return
+	}
+
+	switch cb.state.Load() {
+	case Closed:
+		cb.window.record(now, failure, slow)
+
+		totals := cb.window.totals(now)
+		cb.failures.Store(int32(totals.failures))
+
+		if totals.calls < cb.policy.MinimumCalls {
+			return
+		}
+
+		if reason := cb.exceeded(totals, totals.calls); reason != "" {
+			cb.transition(Open, now, reason)
+		}
+	case HalfOpen:
+		cb.probed.add(failure, slow)
+		cb.failures.Store(int32(cb.probed.failures))
+
+		// rates are taken over every probe, open as soon as the remaining
+		// ones cannot bring them back under the thresholds
+		if reason := cb.exceeded(cb.probed, cb.policy.HalfOpenProbes); reason != "" {
+			cb.transition(Open, now, reason)
+		} else if cb.probed.calls >= cb.policy.HalfOpenProbes {
+			cb.transition(Closed, now, constants.CircuitBreakerReasonProbesSucceeded)
+		}
+	}
+}
+
+// cancel gives back the probe slot of a call that was not made.
+func (cb *SlidingWindow[T]) cancel(generation uint64) {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	if generation == cb.generation && cb.state.Load() == HalfOpen {
+		cb.probes--
+	}
+}
+
+// exceeded compares the failed and slow calls to the thresholds as a share of
+// calls, a zero threshold being disabled, and tells which one was crossed.
+func (cb *SlidingWindow[T]) exceeded(totals outcomes, calls int) string {
+	switch {
+	case exceeds(totals.failures, calls, cb.policy.FailureRateThreshold):
+		return constants.CircuitBreakerReasonFailureRate
+	case exceeds(totals.slow, calls, cb.policy.SlowCallRateThreshold):
+		return constants.CircuitBreakerReasonSlowCallRate
+	default:
+		return ""
+	}
+}
+
+// transition runs under the mutex, subscribers included.
+func (cb *SlidingWindow[T]) transition(to int32, now time.Time, reason string) {
+	from, failures := cb.state.Load(), cb.failures.Load()
+
+	cb.state.Store(to)
+	cb.generation++
+	cb.probes = 0
+	cb.probed = outcomes{}
+
+	switch to {
+	case Open:
+		cb.openedAt = now
+	case HalfOpen:
+		cb.failures.Store(0)
+	case Closed:
+		cb.window.reset()
+		cb.failures.Store(0)
+	}
+
+	cb.publish(from, to, reason, failures)
+}
+
+func uncounted(policy entities.CircuitBreakerPolicy, err error) bool {
+	return err != nil && policy.Uncounted != nil && policy.Uncounted(err)
+}
+
+func exceeds(count, calls int, threshold float64) bool {
+	return threshold > 0 && calls > 0 && float64(count*percent)/float64(calls) >= threshold
+}
+
+func withDefaults(policy entities.CircuitBreakerPolicy) entities.CircuitBreakerPolicy {
+	if policy.WindowSize <= 0 {
+		policy.WindowSize = constants.DefaultCircuitBreakerWindowSize
+	}
+
+	if policy.FailureThreshold <= 0 {
+		policy.FailureThreshold = constants.MaxAttemptsBeforeOpen
+	}
+
+	if policy.MinimumCalls <= 0 {
+		policy.MinimumCalls = constants.DefaultCircuitBreakerMinimumCalls
+	}
+
+	if policy.SlowCallDuration <= 0 {
+		policy.SlowCallDuration = constants.DefaultCircuitBreakerSlowCall
+	}
+
+	if policy.RecoveryTimeout <= 0 {
+		policy.RecoveryTimeout = constants.RecoveryTimeout
+	}
+
+	if policy.HalfOpenProbes <= 0 {
+		policy.HalfOpenProbes = constants.DefaultCircuitBreakerProbes
+	}
+
+	if policy.CacheTTL <= 0 {
+		policy.CacheTTL = constants.DefaultCircuitBreakerCacheTTL
+	}
+
+	return policy
+}
@@ -0,0 +1,256 @@
+package circuitbreaker_test
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/constants"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/entities"
+	circuitbreaker "github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/infra/circuit_breaker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func slidingWindowPolicy() entities.CircuitBreakerPolicy {
+	return entities.CircuitBreakerPolicy{
+		Mode:                  constants.CircuitBreakerModeSlidingWindow,
+		WindowType:            constants.CircuitBreakerWindowCount,
+		WindowSize:            10,
+		MinimumCalls:          4,
+		FailureRateThreshold:  50,
+		SlowCallRateThreshold: 100,
+		SlowCallDuration:      20 * time.Millisecond,
+		RecoveryTimeout:       100 * time.Millisecond,
+		HalfOpenProbes:        2,
+	}
+}
+
+func succeed() (int, error) { return 1, nil }
+
+func fail() (int, error) { return 0, errOperation }
+
+func fallbackValue() (int, error) { return -1, nil }
+
+func TestSlidingWindowNeedsMinimumCallsBeforeOpening(t *testing.T) {
+	t.Parallel()
+
+	cb := circuitbreaker.NewSlidingWindow[int](slidingWindowPolicy())
+
+	for range 3 {
+		_, _ = cb.Execute(fail, fallbackValue)
+	}
+
+	assert.Equal(t, circuitbreaker.Closed, cb.GetState())
+	assert.Equal(t, int32(3), cb.GetCountFailure())
+
+	_, _ = cb.Execute(fail, fallbackValue)
+
+	assert.Equal(t, circuitbreaker.Open, cb.GetState())
+
+	result, err := cb.Execute(func() (int, error) {
+		t.Fatal("should not call operation when in an open state")
+
+		return 0, nil
+	}, fallbackValue)
+	require.NoError(t, err)
+	assert.Equal(t, -1, result)
+}
+
+func TestSlidingWindowFailureRateForgetsOldCalls(t *testing.T) {
+	t.Parallel()
+
+	policy := slidingWindowPolicy()
+	policy.WindowSize = 4
+
+	cb := circuitbreaker.NewSlidingWindow[int](policy)
+
+	// 1 failure in 4 calls, then successes push it out of the window
+	_, _ = cb.Execute(fail, fallbackValue)
+
+	for range 5 {
+		_, _ = cb.Execute(succeed, fallbackValue)
+	}
+
+	assert.Equal(t, int32(0), cb.GetCountFailure())
+
+	// 2 failures out of the last 4 calls is 50%
+	_, _ = cb.Execute(fail, fallbackValue)
+	assert.Equal(t, circuitbreaker.Closed, cb.GetState())
+
+	_, _ = cb.Execute(fail, fallbackValue)
+	assert.Equal(t, circuitbreaker.Open, cb.GetState())
+}
+
+func TestSlidingWindowOpensOnSlowCalls(t *testing.T) {
+	t.Parallel()
+
+	cb := circuitbreaker.NewSlidingWindow[int](slidingWindowPolicy())
+
+	slow := func() (int, error) {
+		time.Sleep(25 * time.Millisecond)
+
+		return 1, nil
+	}
+
+	for range 4 {
+		result, err := cb.Execute(slow, fallbackValue)
+		require.NoError(t, err)
+		assert.Equal(t, 1, result, "slow calls still return their result")
+	}
+
+	assert.Equal(t, circuitbreaker.Open, cb.GetState())
+	assert.Equal(t, int32(0), cb.GetCountFailure())
+}
+
+func TestSlidingWindowTimeWindowExpiresOldCalls(t *testing.T) {
+	t.Parallel()
+
+	policy := slidingWindowPolicy()
+	policy.WindowType = constants.CircuitBreakerWindowTime
+	policy.WindowSize = 1
+	policy.MinimumCalls = 2
+
+	cb := circuitbreaker.NewSlidingWindow[int](policy)
+
+	_, _ = cb.Execute(fail, fallbackValue)
+
+	time.Sleep(1100 * time.Millisecond)
+
+	_, _ = cb.Execute(fail, fallbackValue)
+	assert.Equal(t, circuitbreaker.Closed, cb.GetState())
+
+	_, _ = cb.Execute(fail, fallbackValue)
+	assert.Equal(t, circuitbreaker.Open, cb.GetState())
+}
+
+func TestSlidingWindowLimitsHalfOpenProbes(t *testing.T) {
+	t.Parallel()
+
+	policy := slidingWindowPolicy()
+	policy.MinimumCalls = 1
+
+	cb := circuitbreaker.NewSlidingWindow[int](policy)
+
+	_, _ = cb.Execute(fail, fallbackValue)
+	require.Equal(t, circuitbreaker.Open, cb.GetState())
+
+	time.Sleep(policy.RecoveryTimeout + 10*time.Millisecond)
+
+	var (
+		probes    atomic.Int32
+		fallbacks atomic.Int32
+		release   = make(chan struct{})
+		waitGroup sync.WaitGroup
+	)
+
+	for range 20 {
+		waitGroup.Add(1)
+
+		go func() {
+			defer waitGroup.Done()
+
+			_, _ = cb.Execute(
+				func() (int, error) {
+					probes.Add(1)
+					<-release
+
+					return 1, nil
+				},
+				func() (int, error) {
+					fallbacks.Add(1)
+
+					return -1, nil
+				},
+			)
+		}()
+	}
+
+	assert.Eventually(t, func() bool { return fallbacks.Load() == 18 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, circuitbreaker.HalfOpen, cb.GetState())
+
+	close(release)
+	waitGroup.Wait()
+
+	assert.Equal(t, int32(2), probes.Load())
+	assert.Equal(t, circuitbreaker.Closed, cb.GetState())
+}
+
+func TestSlidingWindowFailedProbeReopens(t *testing.T) {
+	t.Parallel()
+
+	policy := slidingWindowPolicy()
+	policy.MinimumCalls = 1
+
+	cb := circuitbreaker.NewSlidingWindow[int](policy)
+
+	_, _ = cb.Execute(fail, fallbackValue)
+
+	time.Sleep(policy.RecoveryTimeout + 10*time.Millisecond)
+
+	// one failed probe out of two already reaches the 50% failure rate
+	_, _ = cb.Execute(fail, fallbackValue)
+	assert.Equal(t, circuitbreaker.Open, cb.GetState())
+
+	result, err := cb.Execute(succeed, fallbackValue)
+	require.NoError(t, err)
+	assert.Equal(t, -1, result)
+}
+
+func TestNewFromPolicy(t *testing.T) {
+	t.Parallel()
+
+	_, isSlidingWindow := circuitbreaker.NewFromPolicy[int](slidingWindowPolicy()).(*circuitbreaker.SlidingWindow[int])
+	assert.True(t, isSlidingWindow)
+
+	_, isConsecutive := circuitbreaker.NewFromPolicy[int](entities.CircuitBreakerPolicy{
+		Mode:             constants.CircuitBreakerModeConsecutive,
+		FailureThreshold: 3,
+		RecoveryTimeout:  time.Second,
+	}).(*circuitbreaker.CircuitBreaker[int])
+	assert.True(t, isConsecutive)
+}
+
+var errHandedOver = errors.New("handed over")
+
+func handedOver() (int, error) { return 0, errHandedOver }
+
+func TestUncountedErrorsAreNotCounted(t *testing.T) {
+	t.Parallel()
+
+	policy := slidingWindowPolicy()
+	policy.MinimumCalls = 1
+	policy.Uncounted = func(err error) bool { return errors.Is(err, errHandedOver) }
+
+	consecutivePolicy := policy
+	consecutivePolicy.Mode = ""
+	consecutivePolicy.FailureThreshold = 1
+
+	consecutive := circuitbreaker.NewFromPolicy[int](consecutivePolicy)
+
+	result, err := consecutive.Execute(handedOver, fallbackValue)
+	require.NoError(t, err)
+	assert.Equal(t, -1, result)
+	assert.Equal(t, circuitbreaker.Closed, consecutive.GetState())
+	assert.Zero(t, consecutive.GetCountFailure())
+
+	window := circuitbreaker.NewSlidingWindow[int](policy)
+
+	_, _ = window.Execute(fail, fallbackValue)
+
+	time.Sleep(policy.RecoveryTimeout + 10*time.Millisecond)
+
+	// the uncounted probe gives its slot back, two more calls still decide
+	_, _ = window.Execute(handedOver, fallbackValue)
+	assert.Equal(t, circuitbreaker.HalfOpen, window.GetState())
+
+	for range policy.HalfOpenProbes {
+		result, err = window.Execute(succeed, fallbackValue)
+		require.NoError(t, err)
+		assert.Equal(t, 1, result)
+	}
+
+	assert.Equal(t, circuitbreaker.Closed, window.GetState())
+}
@@ -0,0 +1,127 @@
+package circuitbreaker
+
+import "time"
+
+// outcomes adds up calls and how many of them failed or were slow.
+type outcomes struct {
+	calls    int
+	failures int
+	slow     int
+}
+
+func (o *outcomes) add(failure, slow bool) {
+	o.calls++
+
+	if failure {
+		o.failures++
+	}
+
+	if slow {
+		o.slow++
+	}
+}
+
+// window keeps the outcomes of the recent calls; callers serialize access.
+type window interface {
+	record(now time.Time, failure, slow bool)
+	totals(now time.Time) outcomes
+	reset()
+}
+
+type call struct {
+	failure bool
+	slow    bool
+}
+
+// countWindow keeps the last size calls in a ring.
+type countWindow struct {
+	calls   []call
+	current outcomes
+	next    int
+}
+
+func newCountWindow(size int) *countWindow {
+	return &countWindow{calls: make([]call, 0, size)}
+}
+
+func (w *countWindow) record(_ time.Time, failure, slow bool) {
+	if len(w.calls) < cap(w.calls) {
+		w.calls = append(w.calls, call{failure: failure, slow: slow})
+		w.current.add(failure, slow)
+
+		return
+	}
+
+	evicted := w.calls[w.next]
+	w.current.calls--
+
+	if evicted.failure {
+		w.current.failures--
+	}
+
+	if evicted.slow {
+		w.current.slow--
+	}
+
+	w.calls[w.next] = call{failure: failure, slow: slow}
+	w.current.add(failure, slow)
+	w.next = (w.next + 1) % len(w.calls)
+}
+
+func (w *countWindow) totals(time.Time) outcomes {
+	return w.current
+}
+
+func (w *countWindow) reset() {
+	w.calls = w.calls[:0]
+	w.current = outcomes{}
+	w.next = 0
+}
+
+type bucket struct {
+	outcomes
+
+	second int64
+}
+
+// timeWindow keeps one bucket per second for the last size seconds.
+type timeWindow struct {
+	buckets []bucket
+}
+
+func newTimeWindow(seconds int) *timeWindow {
+	return &timeWindow{buckets: make([]bucket, seconds)}
+}
+
+func (w *timeWindow) record(now time.Time, failure, slow bool) {
+	second := now.Unix()
+
+	current := &w.buckets[second%int64(len(w.buckets))]
+	if current.second != second {
+		*current = bucket{second: second}
+	}
+
+	current.add(failure, slow)
+}
+
+func (w *timeWindow) totals(now time.Time) outcomes {
+	oldest := now.Unix() - int64(len(w.buckets))
+
+	var total outcomes
+
+	for _, current := range w.buckets {
+		if current.second <= oldest {
+			continue
+		}
+
+		total.calls += current.calls
+		total.failures += current.failures
+		total.slow += current.slow
+	}
+
+	return total
+}
+
+func (w *timeWindow) reset() {
+	clear(w.buckets)
+}
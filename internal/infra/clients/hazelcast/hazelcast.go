@@ -3,28 +3,63 @@ package hazelcast
 import (
 	"context"
 	"encoding/gob"
+	"errors"
 	"fmt"
 	"log"
-	"math"
 	"os"
+	"strconv"
 	"sync"
 	"time"
 
 	"github.com/hazelcast/hazelcast-go-client"
 	"github.com/hazelcast/hazelcast-go-client/cluster"
 	"github.com/hazelcast/hazelcast-go-client/predicate"
+	"github.com/hazelcast/hazelcast-go-client/types"
 	"github.com/marincor/rinha-de-backend-2025-marincor-golang/constants"
 	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/entities"
 )
 
+var errNotRunning = errors.New("hazelcast client is not running")
+
+const (
+	claimPrefix = "claim:"
+	savedPrefix = "saved:"
+
+	millisecondsPerSecond = 1000
+)
+
+// Client keeps entries under "<processor>:<second>:<correlationId>" and per
+// second count/amount buckets under "<processor>:<second>" in a sibling map, so
+// summaries add up buckets and only read entries for the partial edge seconds.
+// Everything expires after the retention window.
 type Client struct {
-	client    *hazelcast.Client
-	clientMap *hazelcast.Map
+	client        *hazelcast.Client
+	clientMap     entryMap
+	bucketMap     entryMap
+	processorsMap entryMap
+	// processors already registered in processorsMap by this instance
+	knownProcessors sync.Map
+}
+
+// entryMap is the part of *hazelcast.Map the client relies on.
+type entryMap interface {
+	NewLockContext(ctx context.Context) context.Context
+	Lock(ctx context.Context, key interface{}) error
+	Unlock(ctx context.Context, key interface{}) error
+	Get(ctx context.Context, key interface{}) (interface{}, error)
+	GetAll(ctx context.Context, keys ...interface{}) ([]types.Entry, error)
+	GetEntrySetWithPredicate(ctx context.Context, predicate predicate.Predicate) ([]types.Entry, error)
+	GetKeySet(ctx context.Context) ([]interface{}, error)
+	SetWithTTL(ctx context.Context, key interface{}, value interface{}, ttl time.Duration) error
+	PutIfAbsent(ctx context.Context, key interface{}, value interface{}) (interface{}, error)
+	PutIfAbsentWithTTL(ctx context.Context, key interface{}, value interface{}, ttl time.Duration) (interface{}, error)
+	Delete(ctx context.Context, key interface{}) error
 }
 
 func New(mapName string) *Client {
 	gob.Register(PaymentEntry{})
 	gob.Register([]PaymentEntry{})
+	gob.Register(SummaryBucket{})
 
 	context := context.Background()
 
@@ -56,9 +91,31 @@ func New(mapName string) *Client {
 		)
 	}
 
+	bucketMap, err := client.GetMap(context, mapName+"-buckets")
+	if err != nil {
+		log.Fatal(
+			map[string]interface{}{
+				"message": "error getting buckets map",
+				"error":   err,
+			},
+		)
+	}
+
+	processorsMap, err := client.GetMap(context, mapName+"-processors")
+	if err != nil {
+		log.Fatal(
+			map[string]interface{}{
+				"message": "error getting processors map",
+				"error":   err,
+			},
+		)
+	}
+
 	return &Client{
-		client:    client,
-		clientMap: myMap,
+		client:        client,
+		clientMap:     myMap,
+		bucketMap:     bucketMap,
+		processorsMap: processorsMap,
 	}
 }
 
@@ -71,127 +128,298 @@ func (c *Client) Save(payload *entities.PaymentPayloadStorage) error {
 		RequestedAt: payload.RequestedAt,
 	}
 
-	key := fmt.Sprintf("%s:%s", string(payload.ProcessorProvider), payload.ID)
+	requestedAt, err := time.Parse(constants.DefaultTimeFormat, payload.RequestedAt)
+	if err != nil {
+		return fmt.Errorf("error parsing requested at: %w", err)
+	}
 
-	if err := c.clientMap.Set(context, key, entry); err != nil {
+	// a payment replayed after a crash must not be counted twice
+	previous, err := c.clientMap.PutIfAbsentWithTTL(
+		context, savedPrefix+payload.ID, string(payload.ProcessorProvider), constants.PaymentEntryTTL,
+	)
+	if err != nil {
+		return fmt.Errorf("error marking entry as saved: %w", err)
+	}
+
+	if previous != nil {
+		return nil
+	}
+
+	if err := c.record(context, payload.ProcessorProvider, requestedAt, entry); err != nil {
+		// the marker would make the retry skip a payment that was never counted
+		if deleteErr := c.clientMap.Delete(context, savedPrefix+payload.ID); deleteErr != nil {
+			log.Print(
+				map[string]interface{}{
+					"message": "error rolling back saved marker",
+					"error":   deleteErr,
+				},
+			)
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// record writes the entry and adds it to its bucket; writing the entry again is harmless,
+// so a retry after a failed bucket update does not count it twice.
+func (c *Client) record(
+	ctx context.Context,
+	processorProvider entities.ProcessorProvider,
+	requestedAt time.Time,
+	entry PaymentEntry,
+) error {
+	key := fmt.Sprintf("%s:%d:%s", string(processorProvider), requestedAt.Unix(), entry.ID)
+
+	if err := c.clientMap.SetWithTTL(ctx, key, entry, constants.PaymentEntryTTL); err != nil {
 		return fmt.Errorf("error saving entry: %w", err)
 	}
 
+	if err := c.registerProcessor(ctx, processorProvider); err != nil {
+		return err
+	}
+
+	return c.incrementBucket(ctx, bucketKey(processorProvider, requestedAt.Unix()), entry.Amount)
+}
+
+// registerProcessor remembers which processors have entries, so summaries know what to add up.
+func (c *Client) registerProcessor(ctx context.Context, processorProvider entities.ProcessorProvider) error {
+	if _, ok := c.knownProcessors.Load(processorProvider); ok {
+		return nil
+	}
+
+	if _, err := c.processorsMap.PutIfAbsent(ctx, string(processorProvider), true); err != nil {
+		return fmt.Errorf("error registering processor: %w", err)
+	}
+
+	c.knownProcessors.Store(processorProvider, struct{}{})
+
+	return nil
+}
+
+func (c *Client) incrementBucket(ctx context.Context, key string, amount entities.Money) error {
+	lockContext := c.bucketMap.NewLockContext(ctx)
+
+	if err := c.bucketMap.Lock(lockContext, key); err != nil {
+		return fmt.Errorf("error locking bucket: %w", err)
+	}
+
+	defer func() {
+		if err := c.bucketMap.Unlock(lockContext, key); err != nil {
+			log.Print(
+				map[string]interface{}{
+					"message": "error unlocking bucket",
+					"error":   err,
+				},
+			)
+		}
+	}()
+
+	value, err := c.bucketMap.Get(lockContext, key)
+	if err != nil {
+		return fmt.Errorf("error getting bucket: %w", err)
+	}
+
+	bucket, _ := value.(SummaryBucket)
+
+	bucket.Count++
+	bucket.Amount += amount
+
+	if err := c.bucketMap.SetWithTTL(lockContext, key, bucket, constants.PaymentEntryTTL); err != nil {
+		return fmt.Errorf("error saving bucket: %w", err)
+	}
+
+	return nil
+}
+
+func (c *Client) Claim(correlationID string) (bool, error) {
+	context := context.Background()
+
+	previous, err := c.clientMap.PutIfAbsentWithTTL(context, claimPrefix+correlationID, true, constants.PaymentEntryTTL)
+	if err != nil {
+		return false, fmt.Errorf("error claiming correlation id: %w", err)
+	}
+
+	return previous == nil, nil
+}
+
+func (c *Client) Release(correlationID string) error {
+	context := context.Background()
+
+	if err := c.clientMap.Delete(context, claimPrefix+correlationID); err != nil {
+		return fmt.Errorf("error releasing correlation id: %w", err)
+	}
+
+	return nil
+}
+
+func (c *Client) Ping() error {
+	if !c.client.Running() {
+		return errNotRunning
+	}
+
 	return nil
 }
 
 func (c *Client) Retrieve(filters *entities.PaymentSummaryFilters) (*entities.PaymentResultStorage, error) {
 	context := context.Background()
 
-	//nolint:govet
 	result := &entities.PaymentResultStorage{
-		entities.PaymentSummaryResponse{
-			Default: entities.Summary{
-				TotalRequests: 0,
-				TotalAmount:   0,
-			},
-			Fallback: entities.Summary{
-				TotalRequests: 0,
-				TotalAmount:   0,
-			},
-		},
+		PaymentSummaryResponse: entities.NewPaymentSummaryResponse(),
+	}
+
+	processors, err := c.processors(context)
+	if err != nil {
+		return nil, err
 	}
 
 	var (
 		waitGroup sync.WaitGroup
-		err       error
+		mutex     sync.Mutex
 	)
 
-	jobs := 2
+	waitGroup.Add(len(processors))
 
-	waitGroup.Add(jobs)
+	for _, processorProvider := range processors {
+		go func() {
+			defer waitGroup.Done()
 
-	go func() {
-		defer waitGroup.Done()
-		err = c.setValues(context, entities.Default, result, filters)
-	}()
+			summary, processErr := c.setValues(context, processorProvider, filters)
 
-	go func() {
-		defer waitGroup.Done()
-		err = c.setValues(context, entities.Fallback, result, filters)
-	}()
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			if processErr != nil {
+				err = processErr
+
+				return
+			}
+
+			result.Add(processorProvider, summary)
+		}()
+	}
 
 	waitGroup.Wait()
 
 	return result, err
 }
 
-//nolint:cyclop,funlen // long but necessary
+func (c *Client) processors(ctx context.Context) ([]entities.ProcessorProvider, error) {
+	keys, err := c.processorsMap.GetKeySet(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error getting processors: %w", err)
+	}
+
+	processors := []entities.ProcessorProvider{entities.Default, entities.Fallback}
+
+	for _, key := range keys {
+		name, ok := key.(string)
+		if ok && name != string(entities.Default) && name != string(entities.Fallback) {
+			processors = append(processors, entities.ProcessorProvider(name))
+		}
+	}
+
+	return processors, nil
+}
+
 func (c *Client) setValues(
 	context context.Context,
 	processorProvider entities.ProcessorProvider,
-	result *entities.PaymentResultStorage,
 	filters *entities.PaymentSummaryFilters,
-) error {
-	predicate := predicate.Like("__key", string(processorProvider)+":%%")
+) (entities.Summary, error) {
+	window := entities.NewSecondWindow(filters)
 
-	entries, err := c.clientMap.GetEntrySetWithPredicate(context, predicate)
-	if err != nil {
-		return fmt.Errorf("error getting entries with predicate: %w", err)
-	}
+	var (
+		totalRequests int
+		totalAmount   entities.Money
+	)
 
-	filterEnabled := filters != nil && filters.From != nil && filters.To != nil
+	now := time.Now()
 
-	if filterEnabled {
-		utcFrom := filters.From.UTC()
-		utcTo := filters.To.UTC()
+	if first, last, ok := window.Seconds(
+		now.Add(-constants.PaymentEntryTTL).Unix(),
+		now.Add(constants.PaymentClockSkew).Unix(),
+	); ok {
+		keys := make([]interface{}, 0, last-first+1)
+		for second := first; second <= last; second++ {
+			keys = append(keys, bucketKey(processorProvider, second))
+		}
 
-		filters.From = &utcFrom
-		filters.To = &utcTo
-	}
+		buckets, err := c.bucketMap.GetAll(context, keys...)
+		if err != nil {
+			return entities.Summary{}, fmt.Errorf("error getting buckets: %w", err)
+		}
 
-	for _, entry := range entries {
-		//nolint:nestif // long but necessary
-		if response, ok := entry.Value.(PaymentEntry); ok {
-			requestedAt, err := time.Parse(constants.DefaultTimeFormat, response.RequestedAt)
-			if err != nil {
+		for _, entry := range buckets {
+			bucket, ok := entry.Value.(SummaryBucket)
+			if !ok {
 				continue
 			}
 
-			inDateRange := filterEnabled && !requestedAt.Before(*filters.From) && !requestedAt.After(*filters.To)
+			totalRequests += bucket.Count
+			totalAmount += bucket.Amount
+		}
+	}
+
+	for _, edge := range window.Edges {
+		requests, amount, err := c.sumEdge(context, processorProvider, edge)
+		if err != nil {
+			return entities.Summary{}, err
+		}
+
+		totalRequests += requests
+		totalAmount += amount
+	}
 
-			if processorProvider == entities.Default {
-				if inDateRange {
-					result.Default.TotalRequests++
-					result.Default.TotalAmount += response.Amount
+	return entities.Summary{
+		TotalRequests: totalRequests,
+		TotalAmount:   totalAmount,
+	}, nil
+}
 
-					continue
-				}
+// sumEdge reads the individual entries of the seconds only partially covered by the range.
+func (c *Client) sumEdge(
+	context context.Context,
+	processorProvider entities.ProcessorProvider,
+	edge entities.MillisecondRange,
+) (int, entities.Money, error) {
+	var (
+		totalRequests int
+		totalAmount   entities.Money
+	)
 
-				if filterEnabled {
-					continue
-				}
+	for second := edge.From / millisecondsPerSecond; second <= edge.To/millisecondsPerSecond; second++ {
+		pattern := fmt.Sprintf("%s:%d:%%", string(processorProvider), second)
 
-				result.Default.TotalRequests++
-				result.Default.TotalAmount += response.Amount
-			} else {
-				if inDateRange {
-					result.Fallback.TotalRequests++
-					result.Fallback.TotalAmount += response.Amount
+		entries, err := c.clientMap.GetEntrySetWithPredicate(context, predicate.Like("__key", pattern))
+		if err != nil {
+			return 0, 0, fmt.Errorf("error getting entries with predicate: %w", err)
+		}
 
-					continue
-				}
+		for _, entry := range entries {
+			response, ok := entry.Value.(PaymentEntry)
+			if !ok {
+				continue
+			}
 
-				if filterEnabled {
-					continue
-				}
+			requestedAt, err := time.Parse(constants.DefaultTimeFormat, response.RequestedAt)
+			if err != nil {
+				continue
+			}
 
-				result.Fallback.TotalRequests++
-				result.Fallback.TotalAmount += response.Amount
+			if requestedAt.UnixMilli() < edge.From || requestedAt.UnixMilli() > edge.To {
+				continue
 			}
+
+			totalRequests++
+			totalAmount += response.Amount
 		}
 	}
 
-	roundNumber := 100
-	roundFloat := float64(roundNumber)
-
-	result.Default.TotalAmount = math.Round(result.Default.TotalAmount*roundFloat) / roundFloat
-	result.Fallback.TotalAmount = math.Round(result.Fallback.TotalAmount*roundFloat) / roundFloat
+	return totalRequests, totalAmount, nil
+}
 
-	return nil
+func bucketKey(processorProvider entities.ProcessorProvider, second int64) string {
+	return string(processorProvider) + ":" + strconv.FormatInt(second, 10)
 }
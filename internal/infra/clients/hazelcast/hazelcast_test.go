@@ -0,0 +1,206 @@
+package hazelcast
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hazelcast/hazelcast-go-client/predicate"
+	"github.com/hazelcast/hazelcast-go-client/types"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/constants"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errMapUnavailable = errors.New("map unavailable")
+
+// fakeMap keeps entries in memory, ignoring their TTL, and fails the next
+// failSets writes.
+type fakeMap struct {
+	entries  map[interface{}]interface{}
+	mutex    sync.Mutex
+	failSets int
+}
+
+func newFakeMap() *fakeMap {
+	return &fakeMap{entries: map[interface{}]interface{}{}}
+}
+
+func (m *fakeMap) NewLockContext(ctx context.Context) context.Context {
+	return ctx
+}
+
+func (m *fakeMap) Lock(_ context.Context, _ interface{}) error {
+	return nil
+}
+
+func (m *fakeMap) Unlock(_ context.Context, _ interface{}) error {
+	return nil
+}
+
+func (m *fakeMap) Get(_ context.Context, key interface{}) (interface{}, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.entries[key], nil
+}
+
+func (m *fakeMap) GetEntrySetWithPredicate(_ context.Context, _ predicate.Predicate) ([]types.Entry, error) {
+	return nil, errMapUnavailable
+}
+
+func (m *fakeMap) GetKeySet(_ context.Context) ([]interface{}, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	keys := make([]interface{}, 0, len(m.entries))
+	for key := range m.entries {
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+func (m *fakeMap) GetAll(_ context.Context, keys ...interface{}) ([]types.Entry, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	entries := make([]types.Entry, 0, len(keys))
+
+	for _, key := range keys {
+		if value, ok := m.entries[key]; ok {
+			entries = append(entries, types.NewEntry(key, value))
+		}
+	}
+
+	return entries, nil
+}
+
+func (m *fakeMap) SetWithTTL(_ context.Context, key interface{}, value interface{}, _ time.Duration) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.failSets > 0 {
+		m.failSets--
+
+		return errMapUnavailable
+	}
+
+	m.entries[key] = value
+
+	return nil
+}
+
+func (m *fakeMap) PutIfAbsent(_ context.Context, key interface{}, value interface{}) (interface{}, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if previous, ok := m.entries[key]; ok {
+		return previous, nil
+	}
+
+	m.entries[key] = value
+
+	return nil, nil
+}
+
+func (m *fakeMap) PutIfAbsentWithTTL(
+	ctx context.Context,
+	key interface{},
+	value interface{},
+	_ time.Duration,
+) (interface{}, error) {
+	return m.PutIfAbsent(ctx, key, value)
+}
+
+func (m *fakeMap) Delete(_ context.Context, key interface{}) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	delete(m.entries, key)
+
+	return nil
+}
+
+func newTestClient() (*Client, *fakeMap, *fakeMap) {
+	entries, buckets := newFakeMap(), newFakeMap()
+
+	return &Client{clientMap: entries, bucketMap: buckets, processorsMap: newFakeMap()}, entries, buckets
+}
+
+func newPayload(id string, requestedAt time.Time) *entities.PaymentPayloadStorage {
+	return &entities.PaymentPayloadStorage{
+		ID:                id,
+		Amount:            entities.NewMoneyFromCents(1990),
+		RequestedAt:       requestedAt.Format(constants.DefaultTimeFormat),
+		ProcessorProvider: entities.Default,
+	}
+}
+
+func TestSaveCountsAReplayedPaymentOnce(t *testing.T) {
+	t.Parallel()
+
+	client, _, buckets := newTestClient()
+	requestedAt := time.Now().UTC()
+
+	require.NoError(t, client.Save(newPayload("replayed", requestedAt)))
+	require.NoError(t, client.Save(newPayload("replayed", requestedAt)))
+
+	bucket, _ := buckets.entries[bucketKey(entities.Default, requestedAt.Unix())].(SummaryBucket)
+	assert.Equal(t, 1, bucket.Count)
+	assert.Equal(t, "19.90", bucket.Amount.String())
+}
+
+func TestSaveRollsBackTheMarkerWhenAWriteFails(t *testing.T) {
+	t.Parallel()
+
+	for name, failing := range map[string]func(entries, buckets *fakeMap){
+		"entry":  func(entries, _ *fakeMap) { entries.failSets = 1 },
+		"bucket": func(_, buckets *fakeMap) { buckets.failSets = 1 },
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			client, entries, buckets := newTestClient()
+			requestedAt := time.Now().UTC()
+
+			failing(entries, buckets)
+
+			require.Error(t, client.Save(newPayload("retried", requestedAt)))
+			assert.NotContains(t, entries.entries, savedPrefix+"retried", "a failed save must not stay marked as saved")
+
+			require.NoError(t, client.Save(newPayload("retried", requestedAt)))
+
+			bucket, _ := buckets.entries[bucketKey(entities.Default, requestedAt.Unix())].(SummaryBucket)
+			assert.Equal(t, 1, bucket.Count)
+			assert.Contains(t, entries.entries, savedPrefix+"retried")
+		})
+	}
+}
+
+func TestRetrieveReadsTheBucketsInRange(t *testing.T) {
+	t.Parallel()
+
+	client, _, _ := newTestClient()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	for id, requestedAt := range map[string]time.Time{
+		"outside": now.Add(-time.Minute),
+		"first":   now.Add(-10 * time.Second),
+		"last":    now.Add(-time.Second),
+	} {
+		require.NoError(t, client.Save(newPayload(id, requestedAt)))
+	}
+
+	// whole seconds only, so no entry is read for the edges
+	from := now.Add(-10 * time.Second)
+	to := now.Add(-time.Millisecond)
+
+	summary, err := client.Retrieve(&entities.PaymentSummaryFilters{From: &from, To: &to})
+	require.NoError(t, err)
+	assert.Equal(t, 2, summary.Default.TotalRequests)
+	assert.Equal(t, "39.80", summary.Default.TotalAmount.String())
+}
@@ -1,7 +1,14 @@
 package hazelcast
 
+import "github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/entities"
+
 type PaymentEntry struct {
-	ID          string  `json:"id"`
-	RequestedAt string  `json:"requested_at"`
-	Amount      float64 `json:"amount"`
+	ID          string         `json:"id"`
+	RequestedAt string         `json:"requested_at"`
+	Amount      entities.Money `json:"amount"`
+}
+
+type SummaryBucket struct {
+	Amount entities.Money
+	Count  int
 }
@@ -0,0 +1,71 @@
+package memory
+
+import (
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/constants"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/entities"
+)
+
+// DeadLetterStore keeps the payments that exhausted their attempts by correlationId.
+type DeadLetterStore struct {
+	payments map[string]entities.DeadLetterPayment
+	mutex    sync.RWMutex
+}
+
+func NewDeadLetterStore() *DeadLetterStore {
+	return &DeadLetterStore{
+		payments: map[string]entities.DeadLetterPayment{},
+	}
+}
+
+func (s *DeadLetterStore) Add(payment *entities.DeadLetterPayment) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.payments[payment.CorrelationID] = *payment
+
+	return nil
+}
+
+func (s *DeadLetterStore) List() ([]*entities.DeadLetterPayment, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	payments := make([]*entities.DeadLetterPayment, 0, len(s.payments))
+
+	for _, correlationID := range slices.Sorted(maps.Keys(s.payments)) {
+		payment := s.payments[correlationID]
+		payments = append(payments, &payment)
+	}
+
+	slices.SortStableFunc(payments, func(a, b *entities.DeadLetterPayment) int {
+		return a.FailedAt.Compare(b.FailedAt)
+	})
+
+	return payments, nil
+}
+
+func (s *DeadLetterStore) Get(correlationID string) (*entities.DeadLetterPayment, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	payment, ok := s.payments[correlationID]
+	if !ok {
+		return nil, constants.ErrDeadLetterNotFound
+	}
+
+	return &payment, nil
+}
+
+func (s *DeadLetterStore) Remove(correlationID string) (bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	_, ok := s.payments[correlationID]
+	delete(s.payments, correlationID)
+
+	return ok, nil
+}
@@ -0,0 +1,67 @@
+package memory
+
+import "time"
+
+type expiringKey struct {
+	expiresAt time.Time
+	key       string
+}
+
+// expiringSet forgets each key once its TTL has passed, like a redis key set
+// with PX. Keys are queued in the order they were added, so the expired ones
+// are dropped from the front on every write instead of by a background sweep.
+type expiringSet struct {
+	// evicted, when set, is told every key that expired
+	evicted   func(key string)
+	expiresAt map[string]time.Time
+	queue     []expiringKey
+	ttl       time.Duration
+}
+
+func newExpiringSet(ttl time.Duration) *expiringSet {
+	return &expiringSet{
+		expiresAt: map[string]time.Time{},
+		ttl:       ttl,
+	}
+}
+
+// add reports false when the key is already there and has not expired yet.
+func (s *expiringSet) add(key string, now time.Time) bool {
+	s.purge(now)
+
+	if _, ok := s.expiresAt[key]; ok {
+		return false
+	}
+
+	expiresAt := now.Add(s.ttl)
+
+	s.expiresAt[key] = expiresAt
+	s.queue = append(s.queue, expiringKey{expiresAt: expiresAt, key: key})
+
+	return true
+}
+
+func (s *expiringSet) remove(key string) {
+	delete(s.expiresAt, key)
+}
+
+func (s *expiringSet) len() int {
+	return len(s.expiresAt)
+}
+
+func (s *expiringSet) purge(now time.Time) {
+	expired := 0
+
+	for ; expired < len(s.queue) && !s.queue[expired].expiresAt.After(now); expired++ {
+		// the key may have been removed and added again since
+		if expiresAt, ok := s.expiresAt[s.queue[expired].key]; ok && expiresAt.Equal(s.queue[expired].expiresAt) {
+			delete(s.expiresAt, s.queue[expired].key)
+
+			if s.evicted != nil {
+				s.evicted(s.queue[expired].key)
+			}
+		}
+	}
+
+	s.queue = s.queue[expired:]
+}
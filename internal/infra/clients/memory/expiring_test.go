@@ -0,0 +1,50 @@
+package memory
+
+import (
+	"testing"
+	"time"
+
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/constants"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClaimsAndSavedMarkersExpire(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC)
+
+	storage := New()
+	storage.now = func() time.Time { return now }
+
+	payload := &entities.PaymentPayloadStorage{
+		ID:                "payment",
+		ProcessorProvider: entities.Default,
+		RequestedAt:       now.Format(constants.DefaultTimeFormat),
+		Amount:            entities.NewMoneyFromCents(100),
+	}
+
+	claimed, err := storage.Claim("payment")
+	require.NoError(t, err)
+	require.True(t, claimed)
+	require.NoError(t, storage.Save(payload))
+
+	now = now.Add(constants.PaymentEntryTTL - time.Second)
+
+	claimed, err = storage.Claim("payment")
+	require.NoError(t, err)
+	assert.False(t, claimed)
+
+	now = now.Add(time.Second)
+
+	claimed, err = storage.Claim("payment")
+	require.NoError(t, err)
+	assert.True(t, claimed, "an unreleased claim expires")
+
+	payload.ID = "other"
+	require.NoError(t, storage.Save(payload))
+
+	assert.Equal(t, 1, storage.saved.len(), "expired saved markers are dropped on the next save")
+	assert.Equal(t, 1, storage.claims.len())
+}
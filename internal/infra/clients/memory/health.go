@@ -0,0 +1,58 @@
+package memory
+
+import (
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/entities"
+)
+
+// LeaderElector always leads: a single instance polls the processors itself.
+type LeaderElector struct{}
+
+func NewLeaderElector() *LeaderElector {
+	return &LeaderElector{}
+}
+
+func (*LeaderElector) TryLead() (bool, error) { return true, nil }
+
+func (*LeaderElector) Resign() error { return nil }
+
+// HealthSnapshotStore keeps the last published snapshot until its TTL passes.
+type HealthSnapshotStore struct {
+	expiresAt time.Time
+	snapshot  map[entities.ProcessorProvider]entities.ProcessorHealth
+	now       func() time.Time
+	mutex     sync.RWMutex
+	ttl       time.Duration
+}
+
+func NewHealthSnapshotStore(ttl time.Duration) *HealthSnapshotStore {
+	return &HealthSnapshotStore{
+		now: time.Now,
+		ttl: ttl,
+	}
+}
+
+// Publish keeps a copy, the leader goes on updating the snapshot it published.
+func (s *HealthSnapshotStore) Publish(snapshot map[entities.ProcessorProvider]entities.ProcessorHealth) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.snapshot = maps.Clone(snapshot)
+	s.expiresAt = s.now().Add(s.ttl)
+
+	return nil
+}
+
+func (s *HealthSnapshotStore) Load() (map[entities.ProcessorProvider]entities.ProcessorHealth, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if s.snapshot == nil || !s.now().Before(s.expiresAt) {
+		return map[entities.ProcessorProvider]entities.ProcessorHealth{}, nil
+	}
+
+	return s.snapshot, nil
+}
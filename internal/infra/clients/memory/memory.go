@@ -0,0 +1,139 @@
+package memory
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/constants"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/entities"
+)
+
+// Client keeps each processor payments in a slice ordered by the requestedAt
+// unix millisecond, so a summary is two binary searches and a sum over the
+// range in between. It only sees what this process saved: use it in tests and
+// single instance deployments. Like the redis client, it keeps payments for
+// constants.PaymentEntryTTL behind the newest one and saved markers and claims
+// for constants.PaymentEntryTTL after they were set.
+type Client struct {
+	indexes map[entities.ProcessorProvider][]PaymentEntry
+	saved   *expiringSet
+	claims  *expiringSet
+	now     func() time.Time
+	mutex   sync.RWMutex
+}
+
+func New() *Client {
+	return &Client{
+		indexes: map[entities.ProcessorProvider][]PaymentEntry{},
+		saved:   newExpiringSet(constants.PaymentEntryTTL),
+		claims:  newExpiringSet(constants.PaymentEntryTTL),
+		now:     time.Now,
+	}
+}
+
+// Save is idempotent per correlationId, so a payment replayed after a crash is never counted twice.
+func (c *Client) Save(payload *entities.PaymentPayloadStorage) error {
+	requestedAt, err := time.Parse(constants.DefaultTimeFormat, payload.RequestedAt)
+	if err != nil {
+		return fmt.Errorf("error parsing requested at: %w", err)
+	}
+
+	entry := PaymentEntry{
+		ID:          payload.ID,
+		RequestedAt: requestedAt.UnixMilli(),
+		Amount:      payload.Amount,
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if !c.saved.add(payload.ID, c.now()) {
+		return nil
+	}
+
+	index := trim(c.indexes[payload.ProcessorProvider], entry.RequestedAt)
+
+	// payments mostly arrive in order, so this is nearly always an append
+	position := len(index)
+	for position > 0 && index[position-1].RequestedAt > entry.RequestedAt {
+		position--
+	}
+
+	c.indexes[payload.ProcessorProvider] = slices.Insert(index, position, entry)
+
+	return nil
+}
+
+func (c *Client) Claim(correlationID string) (bool, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.claims.add(correlationID, c.now()), nil
+}
+
+func (c *Client) Release(correlationID string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.claims.remove(correlationID)
+
+	return nil
+}
+
+func (c *Client) Ping() error {
+	return nil
+}
+
+func (c *Client) Retrieve(filters *entities.PaymentSummaryFilters) (*entities.PaymentResultStorage, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	result := &entities.PaymentResultStorage{
+		PaymentSummaryResponse: entities.NewPaymentSummaryResponse(),
+	}
+
+	for processorProvider, index := range c.indexes {
+		result.Add(processorProvider, c.summarize(index, filters))
+	}
+
+	return result, nil
+}
+
+func (c *Client) summarize(index []PaymentEntry, filters *entities.PaymentSummaryFilters) entities.Summary {
+	entries := index
+
+	// like the redis client, a range missing either bound covers everything
+	if !entities.NewSecondWindow(filters).Unbounded {
+		// both searches land on the first entry at or after the millisecond
+		from, _ := slices.BinarySearchFunc(index, filters.From.UnixMilli(), compareRequestedAt)
+		to, _ := slices.BinarySearchFunc(index, filters.To.UnixMilli()+1, compareRequestedAt)
+
+		entries = index[from:max(to, from)]
+	}
+
+	summary := entities.Summary{
+		TotalRequests: len(entries),
+		TotalAmount:   0,
+	}
+
+	for _, entry := range entries {
+		summary.TotalAmount += entry.Amount
+	}
+
+	return summary
+}
+
+// trim drops the entries more than the retention window older than the one
+// being saved, which is where payments mostly arrive, at the end.
+func trim(index []PaymentEntry, requestedAt int64) []PaymentEntry {
+	retained, _ := slices.BinarySearchFunc(index, requestedAt-constants.PaymentEntryTTL.Milliseconds(), compareRequestedAt)
+
+	return index[retained:]
+}
+
+func compareRequestedAt(entry PaymentEntry, requestedAt int64) int {
+	return cmp.Compare(entry.RequestedAt, requestedAt)
+}
@@ -0,0 +1,99 @@
+package memory_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/constants"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/entities"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/infra/clients/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetrieveFiltersByRequestedAt(t *testing.T) {
+	t.Parallel()
+
+	storage := memory.New()
+	start := time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC)
+
+	// saved out of order and twice, like a replay after a crash would
+	for _, offset := range []int{5, 0, 3, 1, 4, 2, 3} {
+		require.NoError(t, storage.Save(&entities.PaymentPayloadStorage{
+			ID:                string(rune('a' + offset)),
+			ProcessorProvider: entities.Default,
+			RequestedAt:       start.Add(time.Duration(offset) * time.Second).Format(constants.DefaultTimeFormat),
+			Amount:            entities.NewMoneyFromCents(1990),
+		}))
+	}
+
+	require.NoError(t, storage.Save(&entities.PaymentPayloadStorage{
+		ID:                "fallback",
+		ProcessorProvider: entities.Fallback,
+		RequestedAt:       start.Format(constants.DefaultTimeFormat),
+		Amount:            entities.NewMoneyFromCents(10),
+	}))
+
+	from := start.Add(time.Second)
+	to := start.Add(4 * time.Second)
+
+	result, err := storage.Retrieve(&entities.PaymentSummaryFilters{From: &from, To: &to})
+	require.NoError(t, err)
+
+	assert.Equal(t, 4, result.Default.TotalRequests)
+	assert.Equal(t, "79.60", result.Default.TotalAmount.String())
+	assert.Equal(t, 0, result.Fallback.TotalRequests)
+
+	result, err = storage.Retrieve(&entities.PaymentSummaryFilters{From: &from})
+	require.NoError(t, err)
+
+	assert.Equal(t, 6, result.Default.TotalRequests)
+	assert.Equal(t, 1, result.Fallback.TotalRequests)
+
+	result, err = storage.Retrieve(&entities.PaymentSummaryFilters{From: &to, To: &from})
+	require.NoError(t, err)
+
+	assert.Equal(t, 0, result.Default.TotalRequests)
+}
+
+func TestClaimAndRelease(t *testing.T) {
+	t.Parallel()
+
+	storage := memory.New()
+
+	claimed, err := storage.Claim("payment")
+	require.NoError(t, err)
+	assert.True(t, claimed)
+
+	claimed, err = storage.Claim("payment")
+	require.NoError(t, err)
+	assert.False(t, claimed)
+
+	require.NoError(t, storage.Release("payment"))
+
+	claimed, err = storage.Claim("payment")
+	require.NoError(t, err)
+	assert.True(t, claimed)
+}
+
+func TestSaveForgetsPaymentsPastTheRetentionWindow(t *testing.T) {
+	t.Parallel()
+
+	storage := memory.New()
+	start := time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC)
+
+	// old is only dropped by a save past its retention window
+	for index, offset := range []time.Duration{0, constants.PaymentEntryTTL, constants.PaymentEntryTTL + time.Second} {
+		require.NoError(t, storage.Save(&entities.PaymentPayloadStorage{
+			ID:                string(rune('a' + index)),
+			ProcessorProvider: entities.Default,
+			RequestedAt:       start.Add(offset).Format(constants.DefaultTimeFormat),
+			Amount:            entities.NewMoneyFromCents(100),
+		}))
+	}
+
+	result, err := storage.Retrieve(nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, result.Default.TotalRequests)
+}
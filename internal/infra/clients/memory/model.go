@@ -0,0 +1,9 @@
+package memory
+
+import "github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/entities"
+
+type PaymentEntry struct {
+	ID          string
+	RequestedAt int64
+	Amount      entities.Money
+}
@@ -0,0 +1,86 @@
+package memory
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/constants"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/entities"
+)
+
+// PaymentStatusStore keeps the status of the payments this process saw, with
+// their latest constants.PaymentStatusHistoryLimit transitions, each forgotten
+// constants.PaymentEntryTTL after its first transition.
+type PaymentStatusStore struct {
+	statuses map[string]*entities.PaymentStatus
+	recorded *expiringSet
+	now      func() time.Time
+	mutex    sync.Mutex
+}
+
+func NewPaymentStatusStore() *PaymentStatusStore {
+	store := &PaymentStatusStore{
+		statuses: map[string]*entities.PaymentStatus{},
+		recorded: newExpiringSet(constants.PaymentEntryTTL),
+		now:      time.Now,
+	}
+
+	store.recorded.evicted = func(correlationID string) { delete(store.statuses, correlationID) }
+
+	return store
+}
+
+func (s *PaymentStatusStore) Record(correlationID string, transitions ...*entities.PaymentTransition) error {
+	if len(transitions) == 0 {
+		return nil
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.recorded.add(correlationID, s.now()) {
+		s.statuses[correlationID] = &entities.PaymentStatus{CorrelationID: correlationID}
+	}
+
+	status := s.statuses[correlationID]
+
+	for _, transition := range transitions {
+		status.UpdatedAt = transition.At.UTC()
+		status.State = transition.State
+		status.Attempts = transition.Attempts
+
+		if transition.Processor != "" {
+			status.Processor = transition.Processor
+		}
+
+		if transition.Error != "" {
+			status.LastError = transition.Error
+		}
+
+		status.History = append(status.History, *transition)
+	}
+
+	if excess := len(status.History) - constants.PaymentStatusHistoryLimit; excess > 0 {
+		status.History = slices.Delete(status.History, 0, excess)
+	}
+
+	return nil
+}
+
+func (s *PaymentStatusStore) Get(correlationID string) (*entities.PaymentStatus, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.recorded.purge(s.now())
+
+	status, ok := s.statuses[correlationID]
+	if !ok {
+		return nil, constants.ErrPaymentNotFound
+	}
+
+	copied := *status
+	copied.History = slices.Clone(status.History)
+
+	return &copied, nil
+}
@@ -0,0 +1,41 @@
+package memory
+
+import (
+	"testing"
+	"time"
+
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/constants"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPaymentStatusKeepsHistoryUntilItExpires(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC)
+
+	store := NewPaymentStatusStore()
+	store.now = func() time.Time { return now }
+
+	require.NoError(t, store.Record("payment",
+		&entities.PaymentTransition{At: now, State: entities.PaymentQueued},
+		&entities.PaymentTransition{At: now, State: entities.PaymentFailed, Error: "timeout", Attempts: 1},
+	))
+	require.NoError(t, store.Record("payment",
+		&entities.PaymentTransition{At: now, State: entities.PaymentProcessedFallback, Processor: entities.Fallback, Attempts: 2},
+	))
+
+	status, err := store.Get("payment")
+	require.NoError(t, err)
+	assert.Equal(t, entities.PaymentProcessedFallback, status.State)
+	assert.Equal(t, entities.Fallback, status.Processor)
+	assert.Equal(t, "timeout", status.LastError)
+	assert.Equal(t, 2, status.Attempts)
+	assert.Len(t, status.History, 3)
+
+	now = now.Add(constants.PaymentEntryTTL)
+
+	_, err = store.Get("payment")
+	require.ErrorIs(t, err, constants.ErrPaymentNotFound)
+}
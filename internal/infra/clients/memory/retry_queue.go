@@ -0,0 +1,126 @@
+package memory
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/constants"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/entities"
+)
+
+type retryEntry struct {
+	// at is when the payment is due, or when its claim lease runs out
+	at      time.Time
+	payment entities.QueuedPayment
+}
+
+// RetryQueue keeps the retries of this process ordered by the time they are
+// due. Like the redis one, a claim not acknowledged within
+// constants.PaymentRetryLease is handed out again.
+type RetryQueue struct {
+	claimed   map[string]retryEntry
+	scheduled []retryEntry
+	mutex     sync.Mutex
+}
+
+func NewRetryQueue() *RetryQueue {
+	return &RetryQueue{
+		claimed: map[string]retryEntry{},
+	}
+}
+
+func (q *RetryQueue) Schedule(payment *entities.QueuedPayment, at time.Time) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	entry := retryEntry{at: at, payment: *payment}
+	entry.payment.ID = ""
+	entry.payment.RetryID = uuid.NewString()
+
+	q.insert(entry)
+
+	return nil
+}
+
+func (q *RetryQueue) Claim(now time.Time, limit int) ([]*entities.QueuedPayment, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	for retryID, entry := range q.claimed {
+		if !entry.at.After(now) {
+			delete(q.claimed, retryID)
+			q.insert(entry)
+		}
+	}
+
+	due := 0
+	for due < len(q.scheduled) && due < limit && !q.scheduled[due].at.After(now) {
+		due++
+	}
+
+	payments := make([]*entities.QueuedPayment, 0, due)
+
+	for _, entry := range q.scheduled[:due] {
+		entry.at = now.Add(constants.PaymentRetryLease)
+		q.claimed[entry.payment.RetryID] = entry
+
+		payment := entry.payment
+		payment.LeaseUntil = entry.at
+		payments = append(payments, &payment)
+	}
+
+	q.scheduled = slices.Delete(q.scheduled, 0, due)
+
+	return payments, nil
+}
+
+func (q *RetryQueue) Extend(payment *entities.QueuedPayment, now time.Time) (bool, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	// handed out again once the lease ran out, the deadline is not the same
+	entry, ok := q.claimed[payment.RetryID]
+	if !ok || !entry.at.Equal(payment.LeaseUntil) {
+		return false, nil
+	}
+
+	entry.at = now.Add(constants.PaymentRetryLease)
+	q.claimed[payment.RetryID] = entry
+	payment.LeaseUntil = entry.at
+
+	return true, nil
+}
+
+func (q *RetryQueue) Ack(payment *entities.QueuedPayment) error {
+	if payment.RetryID == "" {
+		return nil
+	}
+
+	q.mutex.Lock()
+	delete(q.claimed, payment.RetryID)
+	q.mutex.Unlock()
+
+	payment.RetryID = ""
+
+	return nil
+}
+
+func (q *RetryQueue) Len() (int, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	return len(q.scheduled) + len(q.claimed), nil
+}
+
+// insert keeps the entries ordered by due time, the ones due at the same time
+// in the order they were scheduled.
+func (q *RetryQueue) insert(entry retryEntry) {
+	position, _ := slices.BinarySearchFunc(q.scheduled, entry.at, func(scheduled retryEntry, at time.Time) int {
+		return cmp.Or(scheduled.at.Compare(at), -1)
+	})
+
+	q.scheduled = slices.Insert(q.scheduled, position, entry)
+}
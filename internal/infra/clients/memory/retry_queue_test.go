@@ -0,0 +1,75 @@
+package memory
+
+import (
+	"testing"
+	"time"
+
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/constants"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryQueueClaimsDuePaymentsInOrderUntilAcknowledged(t *testing.T) {
+	t.Parallel()
+
+	queue := NewRetryQueue()
+	now := time.Now()
+
+	require.NoError(t, queue.Schedule(&entities.QueuedPayment{CorrelationID: "later"}, now.Add(time.Minute)))
+	require.NoError(t, queue.Schedule(&entities.QueuedPayment{CorrelationID: "second"}, now))
+	require.NoError(t, queue.Schedule(&entities.QueuedPayment{CorrelationID: "first", Attempts: 1}, now.Add(-time.Second)))
+
+	claimed, err := queue.Claim(now, constants.PaymentRetryBatchSize)
+	require.NoError(t, err)
+	require.Len(t, claimed, 2)
+	assert.Equal(t, "first", claimed[0].CorrelationID)
+	assert.Equal(t, 1, claimed[0].Attempts)
+	assert.NotEmpty(t, claimed[0].RetryID)
+	assert.Equal(t, "second", claimed[1].CorrelationID)
+
+	again, err := queue.Claim(now, constants.PaymentRetryBatchSize)
+	require.NoError(t, err)
+	assert.Empty(t, again)
+
+	length, err := queue.Len()
+	require.NoError(t, err)
+	assert.Equal(t, 3, length, "the claims still count until acknowledged")
+
+	require.NoError(t, queue.Ack(claimed[0]))
+	assert.Empty(t, claimed[0].RetryID)
+
+	length, err = queue.Len()
+	require.NoError(t, err)
+	assert.Equal(t, 2, length)
+}
+
+func TestRetryQueueHandsExpiredClaimsOutAgain(t *testing.T) {
+	t.Parallel()
+
+	queue := NewRetryQueue()
+	now := time.Now()
+
+	require.NoError(t, queue.Schedule(&entities.QueuedPayment{CorrelationID: "orphan"}, now))
+
+	claimed, err := queue.Claim(now, constants.PaymentRetryBatchSize)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+
+	// scheduled again as it is before the lease ran out, then never acknowledged
+	require.NoError(t, queue.Schedule(claimed[0], now.Add(time.Hour)))
+
+	reclaimed, err := queue.Claim(now.Add(constants.PaymentRetryLease+time.Millisecond), constants.PaymentRetryBatchSize)
+	require.NoError(t, err)
+	require.Len(t, reclaimed, 1)
+	assert.Equal(t, "orphan", reclaimed[0].CorrelationID)
+	assert.Equal(t, claimed[0].RetryID, reclaimed[0].RetryID, "the claim, not the later scheduling")
+
+	extended, err := queue.Extend(claimed[0], now.Add(constants.PaymentRetryLease+time.Second))
+	require.NoError(t, err)
+	assert.False(t, extended, "claimed again since")
+
+	extended, err = queue.Extend(reclaimed[0], now.Add(constants.PaymentRetryLease+time.Second))
+	require.NoError(t, err)
+	assert.True(t, extended)
+}
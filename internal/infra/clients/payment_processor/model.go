@@ -1,17 +1,16 @@
 package paymentprocessor
 
+import "github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/entities"
+
 type Response struct {
 	Message string `json:"message"`
 }
 
-type Summary struct {
-	TotalRequests int     `json:"totalRequests"`
-	TotalAmount   float64 `json:"totalAmount"`
-}
-
-type PaymentSummaryResponse struct {
-	Default  Summary `json:"default"`
-	Fallback Summary `json:"fallback"`
+// AdminSummary is what a processor reports about the payments it processed
+// itself; fee fields are left out since they are not kept at cent precision.
+type AdminSummary struct {
+	TotalRequests int            `json:"totalRequests"`
+	TotalAmount   entities.Money `json:"totalAmount"`
 }
 
 type Health struct {
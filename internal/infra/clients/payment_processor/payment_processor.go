@@ -1,89 +1,93 @@
 package paymentprocessor
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"log"
 	"net/url"
-	"os"
 	"time"
 
 	"github.com/marincor/rinha-de-backend-2025-marincor-golang/constants"
 	"github.com/marincor/rinha-de-backend-2025-marincor-golang/helpers"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/contracts"
 	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/entities"
 	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/infra/clients/request"
 )
 
 var (
 	errInvalidStatusCode = errors.New("invalid status code")
-	errTooManyRequests   = errors.New("too many requests")
 	errHealthFailing     = errors.New("health check failing")
+	errMissingRinhaToken = errors.New("RINHA_TOKEN not configured, processor admin endpoints are unavailable")
 )
 
 const (
-	throttlingFactor   = 5.1 * float64(time.Second)
-	maxRequestTimeInMs = 50
-
-	maxRetries   = 5
-	initialDelay = time.Millisecond
-	multiplier   = 3
-	randomInt    = 4
+	rinhaTokenHeader = "X-Rinha-Token"
 )
 
+// statusError is a processor answer to a payment other than 200.
+type statusError struct {
+	status     string
+	statusCode int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("%s: Error to process payment: %s", errInvalidStatusCode, e.status)
+}
+
+func (e *statusError) Unwrap() error {
+	return errInvalidStatusCode
+}
+
 type Client struct {
-	baseURL           string
+	healthReader      contracts.ProcessorHealthReader
+	limiter           contracts.ConcurrencyLimiter
 	request           *request.HTTPRequest
+	healthRequest     *request.HTTPRequest
+	retryPolicy       *helpers.RetryPolicy
+	baseURL           string
+	rinhaToken        string
 	processorProvider entities.ProcessorProvider
-	failing           bool
 }
 
-func New(baseURL string, processorProvider entities.ProcessorProvider) *Client {
-	client := &Client{
+// New returns a client that fails payments fast while the shared health
+// snapshot says the processor is failing. Choosing between healthy processors
+// by latency or fee is left to the payment router. Each payment request waits
+// for room under the limiter, which learns from how it went, and is retried
+// while another attempt may go through; a 422 duplicate means one already did.
+// The rinhaToken authenticates the admin endpoints, PaymentsSummary fails
+// without it.
+func New(
+	baseURL string,
+	rinhaToken string,
+	processorProvider entities.ProcessorProvider,
+	healthReader contracts.ProcessorHealthReader,
+	limiter contracts.ConcurrencyLimiter,
+) *Client {
+	healthRequest := request.New()
+	healthRequest.SetNewTimeout(constants.HealthCheckTimeout)
+
+	return &Client{
+		healthReader:  healthReader,
+		limiter:       limiter,
+		request:       request.New(),
+		healthRequest: healthRequest,
+		retryPolicy: &helpers.RetryPolicy{
+			Classify:    classify,
+			Jitter:      constants.RetryJitterDecorrelated,
+			BaseDelay:   constants.PaymentRequestBaseDelay,
+			MaxDelay:    constants.PaymentRequestMaxDelay,
+			MaxElapsed:  constants.PaymentRequestMaxElapsed,
+			MaxAttempts: constants.PaymentRequestMaxAttempts,
+		},
 		baseURL:           baseURL,
-		request:           request.New(),
+		rinhaToken:        rinhaToken,
 		processorProvider: processorProvider,
-		failing:           false,
-	}
-
-	if processorProvider == entities.Default {
-		go func(currentClient *Client) {
-			init := true
-
-			healthRequestClient := request.New()
-
-			healthTimeout := 30
-
-			healthRequestClient.SetNewTimeout(time.Duration(healthTimeout) * time.Second)
-
-			fallbackURL := os.Getenv("PAYMENT_PROCESSOR_FALLBACK")
-
-			for {
-				if !init {
-					time.Sleep(time.Duration(throttlingFactor))
-				}
-
-				fallbackHealth, _ := currentClient.health(fallbackURL, healthRequestClient)
-
-				health, err := currentClient.health(currentClient.baseURL, healthRequestClient)
-				if err != nil {
-					currentClient.failing = !errors.Is(err, errTooManyRequests)
-
-					continue
-				}
-
-				currentClient.failing = health.Failing
-
-				if health.MinResponseTime >= maxRequestTimeInMs || (fallbackHealth != nil && !fallbackHealth.Failing && health.MinResponseTime > fallbackHealth.MinResponseTime) {
-					currentClient.failing = true
-				}
-			}
-		}(client)
 	}
-
-	return client
 }
 
 func (c *Client) ProcessPayment(paymentRequest *entities.PaymentRequest) (*entities.PaymentResponse, error) {
-	if c.failing {
+	if c.healthFailing() {
 		return nil, errHealthFailing
 	}
 
@@ -95,35 +99,146 @@ func (c *Client) ProcessPayment(paymentRequest *entities.PaymentRequest) (*entit
 
 	headers := map[string]string{}
 
-	response, err := helpers.ExponentialBackoffRetry(func() (*request.Response, error) {
-		response, err := c.request.POST(c.baseURL+"/payments", headers, body)
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+
+	// no point in backing off any longer once another processor owns it
+	if paymentRequest.Hedge != nil {
+		go func() {
+			select {
+			case <-paymentRequest.Hedge.Settled():
+				if paymentRequest.Hedge.Lost(c.processorProvider) {
+					cancel(constants.ErrPaymentHedged)
+				}
+			case <-ctx.Done():
+			}
+		}()
+	}
+
+	response, err := helpers.Retry(ctx, c.retryPolicy, func() (*request.Response, error) {
+		response, err := c.post(body, headers, paymentRequest)
 		if err != nil {
 			return response, fmt.Errorf("error processing payment: %w", err)
 		}
 
 		if response.StatusCode != constants.HTTPStatusOK {
-			return response, constants.NewErrorWrapper(errInvalidStatusCode, fmt.Sprintf("Error to process payment: %s", response.Status))
+			return response, &statusError{status: response.Status, statusCode: response.StatusCode}
 		}
 
 		return response, nil
-	}, maxRetries, initialDelay, multiplier, randomInt)
+	})
 	if err != nil {
 		return nil, fmt.Errorf("error processing payment: %w", err)
 	}
 
-	if response.StatusCode != constants.HTTPStatusOK {
-		return &entities.PaymentResponse{
-			Message:           "error invalid status",
-			ProcessorProvider: c.processorProvider,
-		}, fmt.Errorf("error to process payment: %w: %s", constants.ErrInvalidStatusCode, response.Status)
+	message := "success"
+	if response.StatusCode == constants.HTTPStatusUnprocessableEntity {
+		message = "already processed"
 	}
 
 	return &entities.PaymentResponse{
-		Message:           "success",
+		Message:           message,
 		ProcessorProvider: c.processorProvider,
 	}, nil
 }
 
+// post holds a limiter slot for the request only, not while backing off. Server
+// errors and timeouts tell the processor is overloaded, other answers do not.
+// A hedged payment is only sent while no other processor owns it, and is
+// settled as soon as this one answers it took it, a 422 included.
+func (c *Client) post(
+	body map[string]any,
+	headers map[string]string,
+	paymentRequest *entities.PaymentRequest,
+) (*request.Response, error) {
+	hedge := paymentRequest.Hedge
+
+	ctx, cancel := context.WithTimeout(context.Background(), constants.ConcurrencyAcquireTimeout)
+	defer cancel()
+
+	if err := c.limiter.Acquire(ctx); err != nil {
+		return nil, fmt.Errorf("error waiting for the concurrency limiter: %w", err)
+	}
+
+	if hedge.Lost(c.processorProvider) {
+		c.limiter.Cancel()
+
+		return nil, constants.ErrPaymentHedged
+	}
+
+	startedAt := time.Now()
+
+	response, err := c.request.POST(c.baseURL+"/payments", headers, body)
+
+	c.limiter.Release(time.Since(startedAt), err != nil || response.StatusCode >= constants.HTTPStatusInternalServerError)
+
+	if err == nil && taken(response) && !hedge.Settle(c.processorProvider) {
+		go log.Print(
+			map[string]interface{}{
+				"correlation_id": paymentRequest.CorrelationID,
+				"processor":      c.processorProvider,
+				"action":         "payment taken by two processors, counted under the first one",
+			},
+		)
+	}
+
+	return response, err
+}
+
+// classify retries what may go through on another attempt, a request without
+// an answer or one the processor could not handle then. A 422 tells an earlier
+// attempt went through; other answers will not change, and neither will a
+// payment another processor owns.
+func classify(err error) helpers.ErrorClass {
+	if errors.Is(err, constants.ErrPaymentHedged) {
+		return helpers.ErrorTerminal
+	}
+
+	var statusErr *statusError
+	if !errors.As(err, &statusErr) {
+		return helpers.ErrorRetryable
+	}
+
+	switch {
+	case statusErr.statusCode == constants.HTTPStatusUnprocessableEntity:
+		return helpers.ErrorAlreadyDone
+	case statusErr.statusCode == constants.HTTPStatusTooManyRequests,
+		statusErr.statusCode >= constants.HTTPStatusInternalServerError:
+		return helpers.ErrorRetryable
+	default:
+		return helpers.ErrorTerminal
+	}
+}
+
+// taken tells the processor has the payment, a 422 meaning an earlier attempt
+// that got no answer went through, since correlationIds are unique.
+func taken(response *request.Response) bool {
+	return response.StatusCode == constants.HTTPStatusOK ||
+		response.StatusCode == constants.HTTPStatusUnprocessableEntity
+}
+
+// healthFailing reads the shared health snapshot. The default processor also
+// counts as failing while it answers slowly, or slower than a healthy fallback,
+// so payments go to the fallback instead of waiting on it.
+func (c *Client) healthFailing() bool {
+	health, ok := c.healthReader.ProcessorHealth(c.processorProvider)
+	if !ok {
+		return false
+	}
+
+	if health.Failing || c.processorProvider != entities.Default {
+		return health.Failing
+	}
+
+	if time.Duration(health.MinResponseTime)*time.Millisecond >= constants.HealthSlowDefaultResponseTime {
+		return true
+	}
+
+	fallback, ok := c.healthReader.ProcessorHealth(entities.Fallback)
+
+	return ok && !fallback.Failing && health.MinResponseTime > fallback.MinResponseTime
+}
+
 func (c *Client) PaymentsSummary(filters *entities.PaymentSummaryFilters) (*entities.PaymentSummaryResponse, error) {
 	endpointURL, err := url.Parse(c.baseURL + "/admin/payments-summary")
 	if err != nil {
@@ -144,7 +259,13 @@ func (c *Client) PaymentsSummary(filters *entities.PaymentSummaryFilters) (*enti
 		endpointURL.RawQuery = query.Encode()
 	}
 
-	headers := map[string]string{}
+	if c.rinhaToken == "" {
+		return nil, errMissingRinhaToken
+	}
+
+	headers := map[string]string{
+		rinhaTokenHeader: c.rinhaToken,
+	}
 
 	response, err := c.request.GET(endpointURL.String(), headers)
 	if err != nil {
@@ -155,34 +276,38 @@ func (c *Client) PaymentsSummary(filters *entities.PaymentSummaryFilters) (*enti
 		return nil, constants.NewErrorWrapper(errInvalidStatusCode, fmt.Sprintf("Error to get summary: %s", response.Status))
 	}
 
-	var paymentSummaryResponse PaymentSummaryResponse
-	err = helpers.Unmarshal(response.Body, &paymentSummaryResponse)
+	var adminSummary AdminSummary
+	err = helpers.Unmarshal(response.Body, &adminSummary)
 	if err != nil {
 		return nil, fmt.Errorf("error unmarshalling payment summary response: %w", err)
 	}
 
-	return &entities.PaymentSummaryResponse{
-		Default: entities.Summary{
-			TotalRequests: paymentSummaryResponse.Default.TotalRequests,
-			TotalAmount:   paymentSummaryResponse.Default.TotalAmount,
-		},
-		Fallback: entities.Summary{
-			TotalRequests: paymentSummaryResponse.Fallback.TotalRequests,
-			TotalAmount:   paymentSummaryResponse.Fallback.TotalAmount,
-		},
-	}, nil
+	summary := entities.Summary{
+		TotalRequests: adminSummary.TotalRequests,
+		TotalAmount:   adminSummary.TotalAmount,
+	}
+
+	// each processor only knows about its own payments
+	result := &entities.PaymentSummaryResponse{}
+	result.Add(c.processorProvider, summary)
+
+	return result, nil
 }
 
-func (c *Client) health(url string, healthRequestClient *request.HTTPRequest) (*Health, error) {
+// CheckHealth asks the processor for its health, which it only answers once
+// every 5 seconds; constants.ErrHealthRateLimited is returned otherwise.
+func (c *Client) CheckHealth() (*entities.ProcessorHealth, error) {
 	headers := map[string]string{}
 
-	response, err := healthRequestClient.GET(url+"/payments/service-health", headers)
+	checkedAt := time.Now().UTC()
+
+	response, err := c.healthRequest.GET(c.baseURL+"/payments/service-health", headers)
 	if err != nil {
 		return nil, fmt.Errorf("error getting payments health: %w", err)
 	}
 
 	if response.StatusCode == constants.HTTPStatusTooManyRequests {
-		return nil, errTooManyRequests
+		return nil, constants.ErrHealthRateLimited
 	}
 
 	if response.StatusCode != constants.HTTPStatusOK {
@@ -195,5 +320,10 @@ func (c *Client) health(url string, healthRequestClient *request.HTTPRequest) (*
 		return nil, fmt.Errorf("error unmarshalling payment health response: %w", err)
 	}
 
-	return &paymentHealthResponse, nil
+	return &entities.ProcessorHealth{
+		LastChecked:     checkedAt,
+		Source:          entities.HealthSourceEndpoint,
+		MinResponseTime: paymentHealthResponse.MinResponseTime,
+		Failing:         paymentHealthResponse.Failing,
+	}, nil
 }
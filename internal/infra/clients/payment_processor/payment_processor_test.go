@@ -0,0 +1,58 @@
+package paymentprocessor
+
+import (
+	"testing"
+
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/entities"
+	"github.com/stretchr/testify/assert"
+)
+
+type healthSnapshot map[entities.ProcessorProvider]entities.ProcessorHealth
+
+func (s healthSnapshot) ProcessorHealth(processorProvider entities.ProcessorProvider) (entities.ProcessorHealth, bool) {
+	health, ok := s[processorProvider]
+
+	return health, ok
+}
+
+func (s healthSnapshot) ProcessorsHealth() map[entities.ProcessorProvider]entities.ProcessorHealth {
+	return s
+}
+
+func TestSlowDefaultCountsAsFailing(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		snapshot healthSnapshot
+		failing  bool
+	}{
+		"no snapshot yet": {snapshot: healthSnapshot{}},
+		"fast default": {snapshot: healthSnapshot{
+			entities.Default:  {MinResponseTime: 10},
+			entities.Fallback: {MinResponseTime: 20},
+		}},
+		"default reported failing": {snapshot: healthSnapshot{
+			entities.Default: {Failing: true},
+		}, failing: true},
+		"default too slow": {snapshot: healthSnapshot{
+			entities.Default: {MinResponseTime: 50},
+		}, failing: true},
+		"default slower than a healthy fallback": {snapshot: healthSnapshot{
+			entities.Default:  {MinResponseTime: 30},
+			entities.Fallback: {MinResponseTime: 20},
+		}, failing: true},
+		"default slower than a failing fallback": {snapshot: healthSnapshot{
+			entities.Default:  {MinResponseTime: 30},
+			entities.Fallback: {MinResponseTime: 20, Failing: true},
+		}},
+	}
+
+	for name, testCase := range cases {
+		client := &Client{healthReader: testCase.snapshot, processorProvider: entities.Default}
+		assert.Equal(t, testCase.failing, client.healthFailing(), name)
+	}
+
+	// the fallback is never left for being slow
+	fallback := &Client{healthReader: healthSnapshot{entities.Fallback: {MinResponseTime: 500}}, processorProvider: entities.Fallback}
+	assert.False(t, fallback.healthFailing())
+}
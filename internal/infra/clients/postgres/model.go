@@ -0,0 +1,13 @@
+package postgres
+
+import (
+	"time"
+
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/entities"
+)
+
+type saveRequest struct {
+	requestedAt time.Time
+	done        chan error
+	payload     *entities.PaymentPayloadStorage
+}
@@ -0,0 +1,249 @@
+package postgres_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	embeddedpostgres "github.com/fergusstrange/embedded-postgres"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/constants"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/entities"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/infra/clients/postgres"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// why the tests cannot run, when no postgres could be started
+var unavailable string
+
+// TestMain starts a local postgres unless POSTGRES_URL points to one already.
+// The first run downloads its binaries, which needs network access.
+func TestMain(m *testing.M) {
+	if os.Getenv("POSTGRES_URL") != "" {
+		os.Exit(m.Run())
+	}
+
+	database, err := startPostgres()
+	if err != nil {
+		unavailable = fmt.Sprintf("POSTGRES_URL not set and no local postgres could be started: %v", err)
+
+		os.Exit(m.Run())
+	}
+
+	code := m.Run()
+
+	if err := database.Stop(); err != nil {
+		fmt.Fprintln(os.Stderr, "error stopping postgres:", err)
+	}
+
+	os.Exit(code)
+}
+
+func startPostgres() (*embeddedpostgres.EmbeddedPostgres, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	port := listener.Addr().(*net.TCPAddr).Port
+	_ = listener.Close()
+
+	runtimePath, err := os.MkdirTemp("", "postgres")
+	if err != nil {
+		return nil, err
+	}
+
+	config := embeddedpostgres.DefaultConfig().
+		Port(uint32(port)).
+		Database("rinha").
+		RuntimePath(runtimePath).
+		Logger(io.Discard)
+
+	database := embeddedpostgres.NewDatabase(config)
+	if err := database.Start(); err != nil {
+		return nil, err
+	}
+
+	os.Setenv("POSTGRES_URL", config.GetConnectionURL()+"?sslmode=disable")
+
+	return database, nil
+}
+
+func newClient(t *testing.T) *postgres.Client {
+	t.Helper()
+
+	if unavailable != "" {
+		t.Skip(unavailable)
+	}
+
+	client := postgres.New()
+	t.Cleanup(client.Close)
+
+	return client
+}
+
+func TestSaveAndRetrieve(t *testing.T) {
+	t.Parallel()
+
+	client := newClient(t)
+
+	// a random minute in the past keeps concurrent runs from seeing each other
+	start := time.Unix(946684800, 0).UTC().Add(time.Duration(time.Now().UnixNano()%1e6) * time.Minute)
+
+	var waitGroup sync.WaitGroup
+
+	for i := range 200 {
+		processorProvider := entities.Default
+		if i%4 == 0 {
+			processorProvider = entities.Fallback
+		}
+
+		payload := &entities.PaymentPayloadStorage{
+			ID:                uuid.NewString(),
+			ProcessorProvider: processorProvider,
+			RequestedAt:       start.Add(time.Duration(i) * 100 * time.Millisecond).Format(constants.DefaultTimeFormat),
+			Amount:            entities.NewMoneyFromCents(1990),
+		}
+
+		waitGroup.Add(1)
+
+		go func() {
+			defer waitGroup.Done()
+
+			assert.NoError(t, client.Save(payload))
+			// saving twice never counts twice
+			assert.NoError(t, client.Save(payload))
+		}()
+	}
+
+	waitGroup.Wait()
+
+	from := start
+	to := start.Add(9900 * time.Millisecond)
+
+	result, err := client.Retrieve(&entities.PaymentSummaryFilters{From: &from, To: &to})
+	require.NoError(t, err)
+
+	assert.Equal(t, 75, result.Default.TotalRequests)
+	assert.Equal(t, int64(75*1990), result.Default.TotalAmount.Cents())
+	assert.Equal(t, 25, result.Fallback.TotalRequests)
+	assert.Equal(t, int64(25*1990), result.Fallback.TotalAmount.Cents())
+}
+
+func TestClaimAndRelease(t *testing.T) {
+	t.Parallel()
+
+	client := newClient(t)
+
+	correlationID := uuid.NewString()
+
+	claimed, err := client.Claim(correlationID)
+	require.NoError(t, err)
+	assert.True(t, claimed)
+
+	claimed, err = client.Claim(correlationID)
+	require.NoError(t, err)
+	assert.False(t, claimed)
+
+	require.NoError(t, client.Release(correlationID))
+
+	claimed, err = client.Claim(correlationID)
+	require.NoError(t, err)
+	assert.True(t, claimed)
+}
+
+func TestClaimTakesOverAnExpiredClaim(t *testing.T) {
+	t.Parallel()
+
+	client := newClient(t)
+	ctx := context.Background()
+
+	correlationID := uuid.NewString()
+
+	claimed, err := client.Claim(correlationID)
+	require.NoError(t, err)
+	require.True(t, claimed)
+
+	connection, err := pgx.Connect(ctx, os.Getenv("POSTGRES_URL"))
+	require.NoError(t, err)
+
+	defer connection.Close(ctx)
+
+	_, err = connection.Exec(ctx,
+		"UPDATE payment_claims SET claimed_at = now() - interval '1 hour' WHERE correlationId = $1", correlationID)
+	require.NoError(t, err)
+
+	claimed, err = client.Claim(correlationID)
+	require.NoError(t, err)
+	assert.True(t, claimed, "a claim older than the TTL has expired")
+
+	claimed, err = client.Claim(correlationID)
+	require.NoError(t, err)
+	assert.False(t, claimed)
+}
+
+// the schema brings a table created by the original init.sql up to date.
+func TestNewMigratesTheOriginalTable(t *testing.T) {
+	if unavailable != "" {
+		t.Skip(unavailable)
+	}
+
+	ctx := context.Background()
+	schemaName := "migration_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+	requestedAt := time.Now().UTC().Truncate(time.Millisecond)
+
+	connection, err := pgx.Connect(ctx, os.Getenv("POSTGRES_URL"))
+	require.NoError(t, err)
+
+	defer connection.Close(ctx)
+
+	_, err = connection.Exec(ctx, fmt.Sprintf(`
+CREATE SCHEMA %[1]s;
+CREATE UNLOGGED TABLE %[1]s.payments (
+    correlationId UUID PRIMARY KEY,
+    amount DECIMAL NOT NULL,
+    requested_at TIMESTAMP NOT NULL
+);`, schemaName))
+	require.NoError(t, err)
+
+	t.Cleanup(func() { _, _ = connection.Exec(ctx, "DROP SCHEMA "+schemaName+" CASCADE") })
+
+	_, err = connection.Exec(ctx,
+		"INSERT INTO "+schemaName+".payments VALUES ($1, 10, $2)", uuid.NewString(), requestedAt)
+	require.NoError(t, err)
+
+	separator := "?"
+	if strings.Contains(os.Getenv("POSTGRES_URL"), "?") {
+		separator = "&"
+	}
+
+	t.Setenv("POSTGRES_URL", os.Getenv("POSTGRES_URL")+separator+"search_path="+schemaName)
+
+	client := postgres.New()
+	t.Cleanup(client.Close)
+
+	var dataType string
+	require.NoError(t, connection.QueryRow(ctx, `
+SELECT data_type FROM information_schema.columns
+WHERE table_schema = $1 AND table_name = 'payments' AND column_name = 'processor'`, schemaName).Scan(&dataType))
+	assert.Equal(t, "text", dataType)
+
+	require.NoError(t, client.Save(&entities.PaymentPayloadStorage{
+		ID:                uuid.NewString(),
+		ProcessorProvider: entities.Default,
+		RequestedAt:       requestedAt.Format(constants.DefaultTimeFormat),
+		Amount:            entities.NewMoneyFromCents(1990),
+	}))
+
+	result, err := client.Retrieve(nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Default.TotalRequests, "rows without a processor are left out")
+}
@@ -0,0 +1,174 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/constants"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/entities"
+)
+
+// loadBreaker reads the breaker hash and the redis clock, so every instance
+// times the breaker against the same clock whatever their own drift.
+const loadBreaker = `
+local clock = redis.call('TIME')
+local now = tonumber(clock[1]) * 1000 + math.floor(tonumber(clock[2]) / 1000)
+local values = redis.call('HMGET', KEYS[1], 'state', 'failures', 'openedAt', 'generation', 'probes', 'probesAt')
+local state = tonumber(values[1] or '0')
+local failures = tonumber(values[2] or '0')
+local openedAt = tonumber(values[3] or '0')
+local generation = tonumber(values[4] or '0')
+`
+
+// breaker states are stored as 0 closed, 1 open and 2 half-open, the values
+// of constants.CircuitBreaker*. Probe slots are leased for the recovery
+// timeout: the ones taken by an instance that died before reporting back are
+// handed out again once it has passed.
+var acquireBreakerScript = redis.NewScript(loadBreaker + `
+local recoveryTimeout = tonumber(ARGV[1])
+local permitted = 1
+
+if state == 1 then
+	if now - openedAt >= recoveryTimeout then
+		state = 2
+		generation = redis.call('HINCRBY', KEYS[1], 'generation', 1)
+		redis.call('HSET', KEYS[1], 'state', 2, 'probes', 0, 'probesAt', now)
+	else
+		permitted = 0
+	end
+end
+
+if state == 2 then
+	if now - tonumber(values[6] or '0') >= recoveryTimeout then
+		redis.call('HSET', KEYS[1], 'probes', 0, 'probesAt', now)
+	end
+
+	if redis.call('HINCRBY', KEYS[1], 'probes', 1) > tonumber(ARGV[2]) then
+		permitted = 0
+	end
+end
+
+return {state, failures, openedAt, generation, permitted}
+`)
+
+var recordBreakerScript = redis.NewScript(loadBreaker + `
+-- calls let through before the last state change do not change it
+if tonumber(ARGV[3]) ~= generation then
+	return {state, failures, openedAt, generation}
+end
+
+if ARGV[1] == '1' then
+	failures = redis.call('HINCRBY', KEYS[1], 'failures', 1)
+
+	if state == 2 or failures >= tonumber(ARGV[2]) then
+		state = 1
+		openedAt = now
+		generation = redis.call('HINCRBY', KEYS[1], 'generation', 1)
+		redis.call('HSET', KEYS[1], 'state', 1, 'openedAt', openedAt, 'probes', 0)
+	end
+elseif state ~= 0 or failures ~= 0 then
+	if state ~= 0 then
+		generation = redis.call('HINCRBY', KEYS[1], 'generation', 1)
+	end
+
+	state = 0
+	failures = 0
+	redis.call('HSET', KEYS[1], 'state', 0, 'failures', 0, 'probes', 0)
+end
+
+return {state, failures, openedAt, generation}
+`)
+
+// CircuitBreakerStore keeps each breaker in a hash under
+// constants.CircuitBreakerKeyPrefix + name.
+type CircuitBreakerStore struct {
+	client *redis.Client
+}
+
+func NewCircuitBreakerStore() *CircuitBreakerStore {
+	return &CircuitBreakerStore{
+		client: connect(),
+	}
+}
+
+func (s *CircuitBreakerStore) Load(name string) (entities.CircuitBreakerStatus, error) {
+	values, err := s.client.HMGet(context.Background(), constants.CircuitBreakerKeyPrefix+name,
+		"state", "failures", "openedAt", "generation",
+	).Result()
+	if err != nil {
+		return entities.CircuitBreakerStatus{}, fmt.Errorf("error loading circuit breaker: %w", err)
+	}
+
+	fields := make([]int64, len(values))
+
+	for index, value := range values {
+		text, ok := value.(string)
+		if !ok {
+			continue
+		}
+
+		fields[index], err = strconv.ParseInt(text, 10, 64)
+		if err != nil {
+			return entities.CircuitBreakerStatus{}, fmt.Errorf("error decoding circuit breaker: %w", err)
+		}
+	}
+
+	return toStatus(fields), nil
+}
+
+func (s *CircuitBreakerStore) Acquire(
+	name string,
+	recoveryTimeout time.Duration,
+	probes int,
+) (entities.CircuitBreakerStatus, bool, error) {
+	fields, err := acquireBreakerScript.Run(context.Background(), s.client,
+		[]string{constants.CircuitBreakerKeyPrefix + name},
+		recoveryTimeout.Milliseconds(), probes,
+	).Int64Slice()
+	if err != nil {
+		return entities.CircuitBreakerStatus{}, false, fmt.Errorf("error acquiring circuit breaker: %w", err)
+	}
+
+	return toStatus(fields), fields[4] == 1, nil
+}
+
+func (s *CircuitBreakerStore) Record(
+	name string,
+	failed bool,
+	failureThreshold int32,
+	generation int64,
+) (entities.CircuitBreakerStatus, error) {
+	failure := 0
+	if failed {
+		failure = 1
+	}
+
+	fields, err := recordBreakerScript.Run(context.Background(), s.client,
+		[]string{constants.CircuitBreakerKeyPrefix + name},
+		failure, failureThreshold, generation,
+	).Int64Slice()
+	if err != nil {
+		return entities.CircuitBreakerStatus{}, fmt.Errorf("error recording circuit breaker call: %w", err)
+	}
+
+	return toStatus(fields), nil
+}
+
+// toStatus reads the state, failures, openedAt and generation fields, in this
+// order.
+func toStatus(fields []int64) entities.CircuitBreakerStatus {
+	status := entities.CircuitBreakerStatus{
+		Generation: fields[3],
+		State:      int32(fields[0]),
+		Failures:   int32(fields[1]),
+	}
+
+	if fields[2] > 0 {
+		status.OpenedAt = time.UnixMilli(fields[2])
+	}
+
+	return status
+}
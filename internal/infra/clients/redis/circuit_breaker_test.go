@@ -0,0 +1,134 @@
+package redis
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/constants"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testRecoveryTimeout  = time.Second
+	testFailureThreshold = 2
+)
+
+// newTestBreakerStore runs the scripts against a server whose clock the test
+// moves, since the breaker is timed on the redis clock.
+func newTestBreakerStore(t *testing.T) (*CircuitBreakerStore, *miniredis.Miniredis) {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	server.SetTime(time.Now())
+
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+
+	t.Cleanup(func() { _ = client.Close() })
+
+	return &CircuitBreakerStore{client: client}, server
+}
+
+// openBreaker fails calls until the breaker opens, returning its generation.
+func openBreaker(t *testing.T, store *CircuitBreakerStore) int64 {
+	t.Helper()
+
+	for range testFailureThreshold {
+		_, err := store.Record("payments", true, testFailureThreshold, 0)
+		require.NoError(t, err)
+	}
+
+	status, err := store.Load("payments")
+	require.NoError(t, err)
+	require.Equal(t, constants.CircuitBreakerOpen, status.State)
+
+	return status.Generation
+}
+
+func TestBreakerOpensProbesAndCloses(t *testing.T) {
+	t.Parallel()
+
+	store, server := newTestBreakerStore(t)
+	opened := openBreaker(t, store)
+
+	_, permitted, err := store.Acquire("payments", testRecoveryTimeout, 1)
+	require.NoError(t, err)
+	assert.False(t, permitted, "open until the recovery timeout passed on the redis clock")
+
+	server.SetTime(time.Now().Add(testRecoveryTimeout))
+
+	status, permitted, err := store.Acquire("payments", testRecoveryTimeout, 1)
+	require.NoError(t, err)
+	assert.True(t, permitted)
+	assert.Equal(t, constants.CircuitBreakerHalfOpen, status.State)
+	assert.Greater(t, status.Generation, opened)
+
+	_, permitted, err = store.Acquire("payments", testRecoveryTimeout, 1)
+	require.NoError(t, err)
+	assert.False(t, permitted, "a single probe slot")
+
+	status, err = store.Record("payments", false, testFailureThreshold, status.Generation)
+	require.NoError(t, err)
+	assert.Equal(t, constants.CircuitBreakerClosed, status.State)
+	assert.Zero(t, status.Failures)
+}
+
+func TestBreakerHandsLostProbesOutAgain(t *testing.T) {
+	t.Parallel()
+
+	store, server := newTestBreakerStore(t)
+	openBreaker(t, store)
+
+	server.SetTime(time.Now().Add(testRecoveryTimeout))
+
+	// the instance holding the only probe never reports back
+	_, permitted, err := store.Acquire("payments", testRecoveryTimeout, 1)
+	require.NoError(t, err)
+	require.True(t, permitted)
+
+	_, permitted, err = store.Acquire("payments", testRecoveryTimeout, 1)
+	require.NoError(t, err)
+	require.False(t, permitted)
+
+	server.SetTime(time.Now().Add(2 * testRecoveryTimeout))
+
+	status, permitted, err := store.Acquire("payments", testRecoveryTimeout, 1)
+	require.NoError(t, err)
+	assert.True(t, permitted)
+	assert.Equal(t, constants.CircuitBreakerHalfOpen, status.State)
+}
+
+func TestBreakerIgnoresCallsFromAnEarlierGeneration(t *testing.T) {
+	t.Parallel()
+
+	store, server := newTestBreakerStore(t)
+
+	// let through while closed, answers after the breaker opened
+	closed, permitted, err := store.Acquire("payments", testRecoveryTimeout, 1)
+	require.NoError(t, err)
+	require.True(t, permitted)
+
+	openBreaker(t, store)
+
+	status, err := store.Record("payments", false, testFailureThreshold, closed.Generation)
+	require.NoError(t, err)
+	assert.Equal(t, constants.CircuitBreakerOpen, status.State)
+
+	server.SetTime(time.Now().Add(testRecoveryTimeout))
+
+	halfOpen, permitted, err := store.Acquire("payments", testRecoveryTimeout, 1)
+	require.NoError(t, err)
+	require.True(t, permitted)
+
+	for _, failed := range []bool{false, true} {
+		status, err = store.Record("payments", failed, testFailureThreshold, closed.Generation)
+		require.NoError(t, err)
+		assert.Equal(t, constants.CircuitBreakerHalfOpen, status.State, "only the probe decides")
+	}
+
+	status, err = store.Record("payments", true, testFailureThreshold, halfOpen.Generation)
+	require.NoError(t, err)
+	assert.Equal(t, constants.CircuitBreakerOpen, status.State)
+}
@@ -0,0 +1,98 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/constants"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/helpers"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/entities"
+)
+
+// DeadLetterStore keeps payments that exhausted their attempts in a hash keyed by correlationId.
+type DeadLetterStore struct {
+	client *redis.Client
+	key    string
+}
+
+func NewDeadLetterStore(key string) *DeadLetterStore {
+	return &DeadLetterStore{
+		client: connect(),
+		key:    key,
+	}
+}
+
+func (s *DeadLetterStore) Add(payment *entities.DeadLetterPayment) error {
+	ctx := context.Background()
+
+	value, err := helpers.Marshal(payment)
+	if err != nil {
+		return fmt.Errorf("error encoding dead letter: %w", err)
+	}
+
+	if err := s.client.HSet(ctx, s.key, payment.CorrelationID, value).Err(); err != nil {
+		return fmt.Errorf("error saving dead letter: %w", err)
+	}
+
+	return nil
+}
+
+func (s *DeadLetterStore) List() ([]*entities.DeadLetterPayment, error) {
+	ctx := context.Background()
+
+	values, err := s.client.HVals(ctx, s.key).Result()
+	if err != nil {
+		return nil, fmt.Errorf("error listing dead letters: %w", err)
+	}
+
+	payments := make([]*entities.DeadLetterPayment, 0, len(values))
+
+	for _, value := range values {
+		var payment entities.DeadLetterPayment
+		if err := helpers.Unmarshal([]byte(value), &payment); err != nil {
+			continue
+		}
+
+		payments = append(payments, &payment)
+	}
+
+	slices.SortFunc(payments, func(a, b *entities.DeadLetterPayment) int {
+		return a.FailedAt.Compare(b.FailedAt)
+	})
+
+	return payments, nil
+}
+
+func (s *DeadLetterStore) Get(correlationID string) (*entities.DeadLetterPayment, error) {
+	ctx := context.Background()
+
+	value, err := s.client.HGet(ctx, s.key, correlationID).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, constants.ErrDeadLetterNotFound
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("error getting dead letter: %w", err)
+	}
+
+	var payment entities.DeadLetterPayment
+	if err := helpers.Unmarshal([]byte(value), &payment); err != nil {
+		return nil, fmt.Errorf("error decoding dead letter: %w", err)
+	}
+
+	return &payment, nil
+}
+
+func (s *DeadLetterStore) Remove(correlationID string) (bool, error) {
+	ctx := context.Background()
+
+	removed, err := s.client.HDel(ctx, s.key, correlationID).Result()
+	if err != nil {
+		return false, fmt.Errorf("error removing dead letter: %w", err)
+	}
+
+	return removed > 0, nil
+}
@@ -0,0 +1,46 @@
+package redis
+
+import (
+	"testing"
+	"time"
+
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/constants"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeadLetterStoreListsOldestFirstAndRemoves(t *testing.T) {
+	t.Parallel()
+
+	store := &DeadLetterStore{client: newTestClient(t), key: constants.PaymentDeadLetterKey}
+	failedAt := time.Now().UTC().Truncate(time.Millisecond)
+
+	require.NoError(t, store.Add(&entities.DeadLetterPayment{
+		FailedAt: failedAt, CorrelationID: "newer", Amount: entities.NewMoneyFromCents(100), Attempts: 5,
+	}))
+	require.NoError(t, store.Add(&entities.DeadLetterPayment{
+		FailedAt: failedAt.Add(-time.Minute), CorrelationID: "older", Amount: entities.NewMoneyFromCents(250), Attempts: 5,
+	}))
+
+	payments, err := store.List()
+	require.NoError(t, err)
+	require.Len(t, payments, 2)
+	assert.Equal(t, "older", payments[0].CorrelationID)
+	assert.Equal(t, "newer", payments[1].CorrelationID)
+
+	payment, err := store.Get("older")
+	require.NoError(t, err)
+	assert.Equal(t, "2.50", payment.Amount.String())
+
+	removed, err := store.Remove("older")
+	require.NoError(t, err)
+	assert.True(t, removed)
+
+	removed, err = store.Remove("older")
+	require.NoError(t, err)
+	assert.False(t, removed)
+
+	_, err = store.Get("older")
+	require.ErrorIs(t, err, constants.ErrDeadLetterNotFound)
+}
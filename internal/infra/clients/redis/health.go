@@ -0,0 +1,122 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/helpers"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/entities"
+)
+
+// leaseScript takes the lease when nobody holds it and extends it when the
+// caller already does.
+var leaseScript = redis.NewScript(`
+local holder = redis.call('GET', KEYS[1])
+if holder == false then
+	redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+	return 1
+end
+
+if holder == ARGV[1] then
+	redis.call('PEXPIRE', KEYS[1], ARGV[2])
+	return 1
+end
+
+return 0
+`)
+
+var resignScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+
+return 0
+`)
+
+// LeaderElector holds a lease on a key; when the leader stops renewing it, the
+// key expires and the next instance asking takes over.
+type LeaderElector struct {
+	client *redis.Client
+	key    string
+	holder string
+	lease  time.Duration
+}
+
+func NewLeaderElector(key, holder string, lease time.Duration) *LeaderElector {
+	return &LeaderElector{
+		client: connect(),
+		key:    key,
+		holder: holder,
+		lease:  lease,
+	}
+}
+
+func (e *LeaderElector) TryLead() (bool, error) {
+	acquired, err := leaseScript.Run(context.Background(), e.client,
+		[]string{e.key}, e.holder, e.lease.Milliseconds(),
+	).Int()
+	if err != nil {
+		return false, fmt.Errorf("error acquiring lease: %w", err)
+	}
+
+	return acquired == 1, nil
+}
+
+func (e *LeaderElector) Resign() error {
+	if err := resignScript.Run(context.Background(), e.client, []string{e.key}, e.holder).Err(); err != nil {
+		return fmt.Errorf("error resigning lease: %w", err)
+	}
+
+	return nil
+}
+
+// HealthSnapshotStore keeps the processors health as a single JSON value that
+// expires when the leader stops publishing it.
+type HealthSnapshotStore struct {
+	client *redis.Client
+	key    string
+	ttl    time.Duration
+}
+
+func NewHealthSnapshotStore(key string, ttl time.Duration) *HealthSnapshotStore {
+	return &HealthSnapshotStore{
+		client: connect(),
+		key:    key,
+		ttl:    ttl,
+	}
+}
+
+func (s *HealthSnapshotStore) Publish(snapshot map[entities.ProcessorProvider]entities.ProcessorHealth) error {
+	value, err := helpers.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("error encoding health snapshot: %w", err)
+	}
+
+	if err := s.client.Set(context.Background(), s.key, value, s.ttl).Err(); err != nil {
+		return fmt.Errorf("error publishing health snapshot: %w", err)
+	}
+
+	return nil
+}
+
+func (s *HealthSnapshotStore) Load() (map[entities.ProcessorProvider]entities.ProcessorHealth, error) {
+	snapshot := map[entities.ProcessorProvider]entities.ProcessorHealth{}
+
+	value, err := s.client.Get(context.Background(), s.key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return snapshot, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("error loading health snapshot: %w", err)
+	}
+
+	if err := helpers.Unmarshal(value, &snapshot); err != nil {
+		return nil, fmt.Errorf("error decoding health snapshot: %w", err)
+	}
+
+	return snapshot, nil
+}
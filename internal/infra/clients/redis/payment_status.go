@@ -0,0 +1,132 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/constants"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/helpers"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/entities"
+)
+
+const (
+	statusPrefix  = "payment:status:"
+	historyPrefix = "payment:history:"
+
+	stateField     = "state"
+	processorField = "processor"
+	lastErrorField = "lastError"
+	attemptsField  = "attempts"
+	updatedAtField = "updatedAt"
+)
+
+// PaymentStatusStore keeps the current state of a payment in a hash and its
+// latest constants.PaymentStatusHistoryLimit transitions in a list, both
+// expiring with the payment entries. The transitions recorded together are
+// written at once, in a single round trip.
+type PaymentStatusStore struct {
+	client *redis.Client
+}
+
+func NewPaymentStatusStore() *PaymentStatusStore {
+	return &PaymentStatusStore{
+		client: connect(),
+	}
+}
+
+func (s *PaymentStatusStore) Record(correlationID string, transitions ...*entities.PaymentTransition) error {
+	if len(transitions) == 0 {
+		return nil
+	}
+
+	ctx := context.Background()
+
+	statusKey := statusPrefix + correlationID
+	historyKey := historyPrefix + correlationID
+
+	// the latest transition sets the state, the processor and the error stay
+	// from the last one that had them
+	fields := map[string]interface{}{}
+	history := make([]interface{}, 0, len(transitions))
+
+	for _, transition := range transitions {
+		encoded, err := helpers.Marshal(transition)
+		if err != nil {
+			return fmt.Errorf("error encoding transition: %w", err)
+		}
+
+		history = append(history, encoded)
+
+		fields[stateField] = string(transition.State)
+		fields[attemptsField] = transition.Attempts
+		fields[updatedAtField] = transition.At.UTC().Format(time.RFC3339Nano)
+
+		if transition.Processor != "" {
+			fields[processorField] = string(transition.Processor)
+		}
+
+		if transition.Error != "" {
+			fields[lastErrorField] = transition.Error
+		}
+	}
+
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, statusKey, fields)
+		pipe.RPush(ctx, historyKey, history...)
+		pipe.LTrim(ctx, historyKey, -constants.PaymentStatusHistoryLimit, -1)
+		pipe.Expire(ctx, statusKey, constants.PaymentEntryTTL)
+		pipe.Expire(ctx, historyKey, constants.PaymentEntryTTL)
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error recording payment transition: %w", err)
+	}
+
+	return nil
+}
+
+func (s *PaymentStatusStore) Get(correlationID string) (*entities.PaymentStatus, error) {
+	ctx := context.Background()
+
+	pipe := s.client.Pipeline()
+
+	statusCmd := pipe.HGetAll(ctx, statusPrefix+correlationID)
+	historyCmd := pipe.LRange(ctx, historyPrefix+correlationID, 0, -1)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("error getting payment status: %w", err)
+	}
+
+	fields := statusCmd.Val()
+	if len(fields) == 0 {
+		return nil, constants.ErrPaymentNotFound
+	}
+
+	attempts, _ := strconv.Atoi(fields[attemptsField])
+	updatedAt, _ := time.Parse(time.RFC3339Nano, fields[updatedAtField])
+
+	status := &entities.PaymentStatus{
+		UpdatedAt:     updatedAt,
+		CorrelationID: correlationID,
+		State:         entities.PaymentState(fields[stateField]),
+		Processor:     entities.ProcessorProvider(fields[processorField]),
+		LastError:     fields[lastErrorField],
+		Attempts:      attempts,
+		History:       make([]entities.PaymentTransition, 0, len(historyCmd.Val())),
+	}
+
+	for _, rawTransition := range historyCmd.Val() {
+		var transition entities.PaymentTransition
+		if err := helpers.Unmarshal([]byte(rawTransition), &transition); err != nil {
+			continue
+		}
+
+		status.History = append(status.History, transition)
+	}
+
+	return status, nil
+}
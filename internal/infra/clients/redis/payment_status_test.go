@@ -0,0 +1,43 @@
+package redis
+
+import (
+	"testing"
+	"time"
+
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/constants"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPaymentStatusKeepsTheLatestTransitionsOnly(t *testing.T) {
+	t.Parallel()
+
+	store := &PaymentStatusStore{client: newTestClient(t)}
+	at := time.Now().UTC().Truncate(time.Millisecond)
+
+	for deferral := range constants.PaymentStatusHistoryLimit {
+		require.NoError(t, store.Record("payment",
+			&entities.PaymentTransition{At: at, State: entities.PaymentProcessing, Attempts: 1},
+			&entities.PaymentTransition{At: at.Add(time.Duration(deferral)), State: entities.PaymentDeferred, Attempts: 1},
+		))
+	}
+
+	require.NoError(t, store.Record("payment",
+		&entities.PaymentTransition{At: at, State: entities.PaymentProcessing, Attempts: 1},
+		&entities.PaymentTransition{At: at, State: entities.PaymentFailed, Error: "timeout", Attempts: 1},
+		&entities.PaymentTransition{At: at, State: entities.PaymentProcessedFallback, Processor: entities.Fallback, Attempts: 2},
+	))
+
+	status, err := store.Get("payment")
+	require.NoError(t, err)
+	assert.Equal(t, entities.PaymentProcessedFallback, status.State)
+	assert.Equal(t, entities.Fallback, status.Processor)
+	assert.Equal(t, "timeout", status.LastError)
+	assert.Equal(t, 2, status.Attempts)
+	require.Len(t, status.History, constants.PaymentStatusHistoryLimit)
+	assert.Equal(t, entities.PaymentProcessedFallback, status.History[len(status.History)-1].State)
+
+	_, err = store.Get("unknown")
+	require.ErrorIs(t, err, constants.ErrPaymentNotFound)
+}
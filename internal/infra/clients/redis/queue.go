@@ -0,0 +1,95 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/entities"
+)
+
+const (
+	correlationIDField = "correlationId"
+	amountField        = "amount"
+)
+
+// Queue keeps accepted payments in a redis stream until they are acknowledged,
+// so a restarted instance can replay whatever it had not finished.
+type Queue struct {
+	client *redis.Client
+	stream string
+}
+
+func NewQueue(stream string) *Queue {
+	return &Queue{
+		client: connect(),
+		stream: stream,
+	}
+}
+
+func (q *Queue) Enqueue(payment *entities.QueuedPayment) error {
+	ctx := context.Background()
+
+	id, err := q.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: q.stream,
+		Values: map[string]interface{}{
+			correlationIDField: payment.CorrelationID,
+			amountField:        payment.Amount.String(),
+		},
+	}).Result()
+	if err != nil {
+		return fmt.Errorf("error adding payment to stream: %w", err)
+	}
+
+	payment.ID = id
+
+	return nil
+}
+
+func (q *Queue) Ack(payment *entities.QueuedPayment) error {
+	ctx := context.Background()
+
+	if err := q.client.XDel(ctx, q.stream, payment.ID).Err(); err != nil {
+		return fmt.Errorf("error removing payment from stream: %w", err)
+	}
+
+	return nil
+}
+
+func (q *Queue) Len() (int, error) {
+	length, err := q.client.XLen(context.Background(), q.stream).Result()
+	if err != nil {
+		return 0, fmt.Errorf("error counting stream payments: %w", err)
+	}
+
+	return int(length), nil
+}
+
+func (q *Queue) Pending() ([]*entities.QueuedPayment, error) {
+	ctx := context.Background()
+
+	messages, err := q.client.XRange(ctx, q.stream, "-", "+").Result()
+	if err != nil {
+		return nil, fmt.Errorf("error reading stream %s: %w", q.stream, err)
+	}
+
+	payments := make([]*entities.QueuedPayment, 0, len(messages))
+
+	for _, message := range messages {
+		correlationID, _ := message.Values[correlationIDField].(string)
+		rawAmount, _ := message.Values[amountField].(string)
+
+		amount, err := entities.ParseMoney(rawAmount)
+		if err != nil || correlationID == "" {
+			continue
+		}
+
+		payments = append(payments, &entities.QueuedPayment{
+			ID:            message.ID,
+			CorrelationID: correlationID,
+			Amount:        amount,
+		})
+	}
+
+	return payments, nil
+}
@@ -1,13 +1,13 @@
 package redis
 
 import (
-	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"log"
-	"math"
 	"os"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -17,94 +17,234 @@ import (
 	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/entities"
 )
 
-var errGettingBufferFromPool = errors.New("error getting buffer from pool")
+const (
+	claimPrefix    = "claim:"
+	savedPrefix    = "saved:"
+	paymentsPrefix = "payments:"
 
+	legacyScanCount = 1000
+
+	// must match the suffixes used by recordPayment
+	bucketCountSuffix  = "c"
+	bucketAmountSuffix = "m"
+
+	// seconds kept in each bucket hash
+	bucketPeriodSeconds = 60
+)
+
+// recordPayment indexes the payment by its requestedAt epoch, trimming the
+// index to the retention window, adds it to its per second bucket and
+// registers its processor. Each bucket hash only takes the payments of its own
+// minute, so it expires once the retention window has passed over it.
+const recordPayment = `
+redis.call('ZADD', KEYS[2], ARGV[3], ARGV[4])
+redis.call('ZREMRANGEBYSCORE', KEYS[2], '-inf', '(' .. ARGV[7])
+redis.call('HINCRBY', KEYS[3], ARGV[5] .. ':c', 1)
+redis.call('HINCRBY', KEYS[3], ARGV[5] .. ':m', ARGV[6])
+redis.call('PEXPIRE', KEYS[2], ARGV[2])
+redis.call('PEXPIRE', KEYS[3], ARGV[2])
+redis.call('SADD', KEYS[4], ARGV[1])
+`
+
+// saveScript marks the payment as saved and records it in a single round trip.
+var saveScript = redis.NewScript(`
+if not redis.call('SET', KEYS[1], ARGV[1], 'NX', 'PX', ARGV[2]) then
+	return 0
+end
+` + recordPayment + `
+return 1
+`)
+
+// migrateScript records a legacy entry and deletes it in a single round trip.
+// The legacy layout already set the saved marker, so it is refreshed instead
+// of guarding the save; whoever deletes the legacy key is the one recording it.
+var migrateScript = redis.NewScript(`
+if redis.call('DEL', KEYS[5]) == 0 then
+	return 0
+end
+
+redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+` + recordPayment + `
+return 1
+`)
+
+// Client indexes each processor payments in a sorted set scored by the
+// requestedAt unix millisecond, members being "<correlationId>:<amount>", and
+// keeps per second count/amount buckets in one hash per minute ("<second>:c",
+// "<second>:m", the amount in cents).
+// Summaries add up the buckets fully inside the range and only read the index
+// for the partial seconds at its edges.
 type Client struct {
-	client *redis.Client
+	client    *redis.Client
+	namespace string
 }
 
 type PaymentEntry struct {
-	ID          string  `json:"id"`
-	Amount      float64 `json:"amount"`
-	RequestedAt string  `json:"requested_at"`
+	ID          string         `json:"id"`
+	RequestedAt string         `json:"requested_at"`
+	Amount      entities.Money `json:"amount"`
 }
 
-func New() *Client {
-	ctx := context.Background()
+var (
+	connection     *redis.Client
+	connectionOnce sync.Once
+)
 
-	// Configuração específica para Redis
-	redisURL := os.Getenv("REDIS_URL")
-	if redisURL == "" {
-		redisURL = "localhost:6379" // default Redis port
+func New() *Client {
+	return &Client{
+		client:    connect(),
+		namespace: paymentsPrefix,
 	}
+}
+
+// connect shares a single connection pool between every redis backed component.
+func connect() *redis.Client {
+	connectionOnce.Do(func() {
+		ctx := context.Background()
+
+		// Configuração específica para Redis
+		redisURL := os.Getenv("REDIS_URL")
+		if redisURL == "" {
+			redisURL = "localhost:6379" // default Redis port
+		}
+
+		options := &redis.Options{
+			Addr: redisURL,
+			DB:   0, // database padrão
+
+			PoolSize:     10,
+			MinIdleConns: 3,
+			MaxRetries:   3,
+
+			DialTimeout:  5 * time.Second,
+			ReadTimeout:  10 * time.Second,
+			WriteTimeout: 10 * time.Second,
+			PoolTimeout:  10 * time.Second,
+
+			MaxConnAge:  10 * time.Minute,
+			IdleTimeout: 5 * time.Minute,
+		}
+
+		client := redis.NewClient(options)
 
-	options := &redis.Options{
-		Addr: redisURL,
-		DB:   0, // database padrão
+		if err := client.Ping(ctx).Err(); err != nil {
+			log.Fatal(map[string]interface{}{
+				"message": "error connecting to Redis",
+				"error":   err,
+				"url":     redisURL,
+			})
+		}
 
-		PoolSize:     10,
-		MinIdleConns: 3,
-		MaxRetries:   3,
+		log.Printf("Connected to Redis at %s", redisURL)
 
-		DialTimeout:  5 * time.Second,
-		ReadTimeout:  10 * time.Second,
-		WriteTimeout: 10 * time.Second,
-		PoolTimeout:  10 * time.Second,
+		connection = client
+	})
+
+	return connection
+}
 
-		MaxConnAge:  10 * time.Minute,
-		IdleTimeout: 5 * time.Minute,
+func (c *Client) Save(payload *entities.PaymentPayloadStorage) error {
+	ctx := context.Background()
+
+	requestedAt, err := time.Parse(constants.DefaultTimeFormat, payload.RequestedAt)
+	if err != nil {
+		return fmt.Errorf("error parsing requested at: %w", err)
 	}
 
-	client := redis.NewClient(options)
+	return c.index(ctx, payload.ID, payload.ProcessorProvider, requestedAt, payload.Amount)
+}
 
-	if err := client.Ping(ctx).Err(); err != nil {
-		log.Fatal(map[string]interface{}{
-			"message": "error connecting to Redis",
-			"error":   err,
-			"url":     redisURL,
-		})
+// index is idempotent per correlationId, so a payment replayed after a crash is never counted twice.
+func (c *Client) index(
+	ctx context.Context,
+	id string,
+	processorProvider entities.ProcessorProvider,
+	requestedAt time.Time,
+	amount entities.Money,
+) error {
+	if err := saveScript.Run(ctx, c.client,
+		c.paymentKeys(id, processorProvider, requestedAt),
+		paymentArgs(id, processorProvider, requestedAt, amount)...,
+	).Err(); err != nil {
+		return fmt.Errorf("error saving entry: %w", err)
 	}
 
-	log.Printf("Connected to Redis at %s", redisURL)
+	return nil
+}
 
-	return &Client{
-		client: client,
+// migrate records a legacy entry and deletes it, reporting false when another
+// instance migrated it first.
+func (c *Client) migrate(
+	ctx context.Context,
+	legacyKey string,
+	entry *PaymentEntry,
+	processorProvider entities.ProcessorProvider,
+	requestedAt time.Time,
+) (bool, error) {
+	migrated, err := migrateScript.Run(ctx, c.client,
+		append(c.paymentKeys(entry.ID, processorProvider, requestedAt), legacyKey),
+		paymentArgs(entry.ID, processorProvider, requestedAt, entry.Amount)...,
+	).Int()
+	if err != nil {
+		return false, fmt.Errorf("error migrating legacy entry %s: %w", legacyKey, err)
 	}
+
+	return migrated == 1, nil
 }
 
-var jsonBufferPool = sync.Pool{
-	New: func() interface{} {
-		return bytes.NewBuffer(make([]byte, 0, 256))
-	},
+func (c *Client) paymentKeys(id string, processorProvider entities.ProcessorProvider, requestedAt time.Time) []string {
+	return []string{
+		savedPrefix + id,
+		c.indexKey(processorProvider),
+		c.bucketKey(processorProvider, requestedAt.Unix()/bucketPeriodSeconds),
+		c.processorsKey(),
+	}
 }
 
-func (c *Client) Save(payload *entities.PaymentPayloadStorage) error {
+func paymentArgs(
+	id string,
+	processorProvider entities.ProcessorProvider,
+	requestedAt time.Time,
+	amount entities.Money,
+) []interface{} {
+	return []interface{}{
+		string(processorProvider),
+		constants.PaymentEntryTTL.Milliseconds(),
+		requestedAt.UnixMilli(),
+		encodeMember(id, amount),
+		requestedAt.Unix(),
+		amount.Cents(),
+		time.Now().Add(-constants.PaymentEntryTTL).UnixMilli(),
+	}
+}
+
+func (c *Client) Claim(correlationID string) (bool, error) {
 	ctx := context.Background()
 
-	// avoid re-allocating the buffer
-	buf, ok := jsonBufferPool.Get().(*bytes.Buffer)
-	if !ok {
-		return errGettingBufferFromPool
+	claimed, err := c.client.SetNX(ctx, claimPrefix+correlationID, 1, constants.PaymentEntryTTL).Result()
+	if err != nil {
+		return false, fmt.Errorf("error claiming correlation id: %w", err)
 	}
 
-	buf.Reset()
-	defer jsonBufferPool.Put(buf)
+	return claimed, nil
+}
 
-	entry := PaymentEntry{
-		ID:          payload.ID,
-		Amount:      payload.Amount,
-		RequestedAt: payload.RequestedAt,
-	}
+func (c *Client) Release(correlationID string) error {
+	ctx := context.Background()
 
-	encoder := helpers.NewEncoder(buf)
-	if err := encoder.Encode(entry); err != nil {
-		return fmt.Errorf("error encoding entry: %w", err)
+	if err := c.client.Del(ctx, claimPrefix+correlationID).Err(); err != nil {
+		return fmt.Errorf("error releasing correlation id: %w", err)
 	}
 
-	key := fmt.Sprintf("%s:%s", string(payload.ProcessorProvider), payload.ID)
+	return nil
+}
 
-	if err := c.client.Set(ctx, key, buf.Bytes(), 20*time.Minute).Err(); err != nil {
-		return fmt.Errorf("error saving entry: %w", err)
+func (c *Client) Ping() error {
+	ctx, cancel := context.WithTimeout(context.Background(), constants.HealthPingTimeout)
+	defer cancel()
+
+	if err := c.client.Ping(ctx).Err(); err != nil {
+		return fmt.Errorf("error pinging redis: %w", err)
 	}
 
 	return nil
@@ -114,125 +254,247 @@ func (c *Client) Retrieve(filters *entities.PaymentSummaryFilters) (*entities.Pa
 	ctx := context.Background()
 
 	result := &entities.PaymentResultStorage{
-		entities.PaymentSummaryResponse{
-			Default: entities.Summary{
-				TotalRequests: 0,
-				TotalAmount:   0,
-			},
-			Fallback: entities.Summary{
-				TotalRequests: 0,
-				TotalAmount:   0,
-			},
-		},
+		PaymentSummaryResponse: entities.NewPaymentSummaryResponse(),
+	}
+
+	processors, err := c.processors(ctx)
+	if err != nil {
+		return nil, err
 	}
 
 	var (
 		waitGroup sync.WaitGroup
-		err       error
+		mutex     sync.Mutex
 	)
 
-	jobs := 2
-	waitGroup.Add(jobs)
+	waitGroup.Add(len(processors))
 
-	go func() {
-		defer waitGroup.Done()
-		if processErr := c.setValues(ctx, entities.Default, result, filters); processErr != nil {
-			err = processErr
-		}
-	}()
+	for _, processorProvider := range processors {
+		go func() {
+			defer waitGroup.Done()
 
-	go func() {
-		defer waitGroup.Done()
-		if processErr := c.setValues(ctx, entities.Fallback, result, filters); processErr != nil {
-			err = processErr
-		}
-	}()
+			summary, processErr := c.setValues(ctx, processorProvider, filters)
+
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			if processErr != nil {
+				err = processErr
+
+				return
+			}
+
+			result.Add(processorProvider, summary)
+		}()
+	}
 
 	waitGroup.Wait()
 
 	return result, err
 }
 
-//nolint:cyclop,funlen // long but necessary
+// processors lists every processor that has payments, default and fallback always included.
+func (c *Client) processors(ctx context.Context) ([]entities.ProcessorProvider, error) {
+	members, err := c.client.SMembers(ctx, c.processorsKey()).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("error getting processors: %w", err)
+	}
+
+	processors := []entities.ProcessorProvider{entities.Default, entities.Fallback}
+
+	for _, member := range members {
+		if member != string(entities.Default) && member != string(entities.Fallback) {
+			processors = append(processors, entities.ProcessorProvider(member))
+		}
+	}
+
+	return processors, nil
+}
+
 func (c *Client) setValues(
 	ctx context.Context,
 	processorProvider entities.ProcessorProvider,
-	result *entities.PaymentResultStorage,
 	filters *entities.PaymentSummaryFilters,
-) error {
-	pattern := string(processorProvider) + ":*"
-
-	keys, err := c.client.Keys(ctx, pattern).Result()
-	if err != nil {
-		return fmt.Errorf("error getting keys with pattern %s: %w", pattern, err)
-	}
-
-	if len(keys) == 0 {
-		return nil
-	}
+) (entities.Summary, error) {
+	window := entities.NewSecondWindow(filters)
+	now := time.Now()
 
 	pipe := c.client.Pipeline()
 
-	cmds := make([]*redis.StringCmd, len(keys))
-	for i, key := range keys {
-		cmds[i] = pipe.Get(ctx, key)
+	var bucketsCmds []*redis.SliceCmd
+	if first, last, ok := window.Seconds(
+		now.Add(-constants.PaymentEntryTTL).Unix(),
+		now.Add(constants.PaymentClockSkew).Unix(),
+	); ok {
+		bucketsCmds = c.readBuckets(ctx, pipe, processorProvider, first, last)
 	}
 
-	_, err = pipe.Exec(ctx)
-	if err != nil && !errors.Is(err, redis.Nil) {
-		return fmt.Errorf("error executing pipeline: %w", err)
+	edgesCmds := make([]*redis.StringSliceCmd, 0, len(window.Edges))
+	for _, edge := range window.Edges {
+		edgesCmds = append(edgesCmds, pipe.ZRangeByScore(ctx, c.indexKey(processorProvider), &redis.ZRangeBy{
+			Min: strconv.FormatInt(edge.From, 10),
+			Max: strconv.FormatInt(edge.To, 10),
+		}))
 	}
 
-	filterEnabled := filters != nil && filters.From != nil && filters.To != nil
-	var utcFrom, utcTo time.Time
-
-	if filterEnabled {
-		utcFrom = filters.From.UTC()
-		utcTo = filters.To.UTC()
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return entities.Summary{}, fmt.Errorf("error getting %s payments summary: %w", processorProvider, err)
 	}
 
 	var (
 		totalRequests int
-		totalAmount   float64
+		totalAmount   entities.Money
 	)
 
-	for _, cmd := range cmds {
-		entryJSON, err := cmd.Result()
-		if err != nil {
-			continue
-		}
+	for _, bucketsCmd := range bucketsCmds {
+		requests, amount := sumBuckets(bucketsCmd.Val())
+		totalRequests += requests
+		totalAmount += amount
+	}
+
+	for _, edgeCmd := range edgesCmds {
+		for _, member := range edgeCmd.Val() {
+			amount, ok := decodeMemberAmount(member)
+			if !ok {
+				continue
+			}
 
-		var entry PaymentEntry
-		if err := helpers.Unmarshal([]byte(entryJSON), &entry); err != nil {
-			continue
+			totalRequests++
+			totalAmount += amount
 		}
+	}
+
+	return entities.Summary{
+		TotalRequests: totalRequests,
+		TotalAmount:   totalAmount,
+	}, nil
+}
 
-		requestedAt, err := time.Parse(constants.DefaultTimeFormat, entry.RequestedAt)
-		if err != nil {
-			continue
+// readBuckets queues one HMGET per bucket hash for the count and amount fields
+// of every second from first to last.
+func (c *Client) readBuckets(
+	ctx context.Context,
+	pipe redis.Pipeliner,
+	processorProvider entities.ProcessorProvider,
+	first, last int64,
+) []*redis.SliceCmd {
+	cmds := make([]*redis.SliceCmd, 0, last/bucketPeriodSeconds-first/bucketPeriodSeconds+1)
+
+	for period := first / bucketPeriodSeconds; period <= last/bucketPeriodSeconds; period++ {
+		from := max(first, period*bucketPeriodSeconds)
+		to := min(last, (period+1)*bucketPeriodSeconds-1)
+
+		fields := make([]string, 0, 2*(to-from+1))
+		for second := from; second <= to; second++ {
+			prefix := strconv.FormatInt(second, 10) + ":"
+			fields = append(fields, prefix+bucketCountSuffix, prefix+bucketAmountSuffix)
 		}
 
-		if filterEnabled {
-			if requestedAt.Before(utcFrom) || requestedAt.After(utcTo) {
+		cmds = append(cmds, pipe.HMGet(ctx, c.bucketKey(processorProvider, period), fields...))
+	}
+
+	return cmds
+}
+
+// MigrateLegacyKeys moves the "<processor>:<correlationId>" JSON entries of the
+// previous layout into the sorted set index, deleting them once indexed. Only
+// the entries this call moved are counted.
+func (c *Client) MigrateLegacyKeys() (int, error) {
+	ctx := context.Background()
+
+	migrated := 0
+
+	for _, processorProvider := range []entities.ProcessorProvider{entities.Default, entities.Fallback} {
+		iterator := c.client.Scan(ctx, 0, string(processorProvider)+":*", legacyScanCount).Iterator()
+
+		for iterator.Next(ctx) {
+			key := iterator.Val()
+
+			rawEntry, err := c.client.Get(ctx, key).Result()
+			if errors.Is(err, redis.Nil) {
+				continue
+			}
+
+			if err != nil {
+				return migrated, fmt.Errorf("error reading legacy entry %s: %w", key, err)
+			}
+
+			var entry PaymentEntry
+			if err := helpers.Unmarshal([]byte(rawEntry), &entry); err != nil {
+				continue
+			}
+
+			requestedAt, err := time.Parse(constants.DefaultTimeFormat, entry.RequestedAt)
+			if err != nil {
 				continue
 			}
+
+			moved, err := c.migrate(ctx, key, &entry, processorProvider, requestedAt)
+			if err != nil {
+				return migrated, err
+			}
+
+			if moved {
+				migrated++
+			}
+		}
+
+		if err := iterator.Err(); err != nil {
+			return migrated, fmt.Errorf("error scanning legacy entries: %w", err)
+		}
+	}
+
+	return migrated, nil
+}
+
+func (c *Client) indexKey(processorProvider entities.ProcessorProvider) string {
+	return c.namespace + string(processorProvider)
+}
+
+func (c *Client) processorsKey() string {
+	return c.namespace + "processors"
+}
+
+func (c *Client) bucketKey(processorProvider entities.ProcessorProvider, period int64) string {
+	return c.namespace + "buckets:" + string(processorProvider) + ":" + strconv.FormatInt(period, 10)
+}
+
+// sumBuckets adds up HMGET replies, which alternate count and amount fields.
+func sumBuckets(values []interface{}) (int, entities.Money) {
+	var (
+		totalRequests int
+		totalAmount   entities.Money
+	)
+
+	for i := 0; i+1 < len(values); i += 2 {
+		if count, ok := values[i].(string); ok {
+			requests, _ := strconv.Atoi(count)
+			totalRequests += requests
 		}
 
-		totalRequests++
-		totalAmount += entry.Amount
+		if amount, ok := values[i+1].(string); ok {
+			cents, _ := strconv.ParseInt(amount, 10, 64)
+			totalAmount += entities.NewMoneyFromCents(cents)
+		}
 	}
 
-	roundNumber := 100
-	roundFloat := float64(roundNumber)
-	totalAmount = math.Round(totalAmount*roundFloat) / roundFloat
+	return totalRequests, totalAmount
+}
+
+func encodeMember(id string, amount entities.Money) string {
+	return id + ":" + amount.String()
+}
+
+func decodeMemberAmount(member string) (entities.Money, bool) {
+	separator := strings.LastIndexByte(member, ':')
+	if separator < 0 {
+		return 0, false
+	}
 
-	if processorProvider == entities.Default {
-		result.Default.TotalRequests += totalRequests
-		result.Default.TotalAmount += totalAmount
-	} else {
-		result.Fallback.TotalRequests += totalRequests
-		result.Fallback.TotalAmount += totalAmount
+	amount, err := entities.ParseMoney(member[separator+1:])
+	if err != nil {
+		return 0, false
 	}
 
-	return nil
+	return amount, true
 }
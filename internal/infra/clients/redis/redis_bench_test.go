@@ -0,0 +1,91 @@
+//nolint:all // only benchmark
+package redis
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/entities"
+)
+
+// BenchmarkRetrieve needs a running redis (REDIS_URL); it seeds each size with
+// one payment per millisecond and always queries a ten second window, so ns/op
+// should stay flat while the index grows.
+func BenchmarkRetrieve(b *testing.B) {
+	if os.Getenv("REDIS_URL") == "" {
+		b.Skip("REDIS_URL not set")
+	}
+
+	ctx := context.Background()
+	client := connect()
+
+	for _, size := range []int{1_000, 10_000, 100_000, 250_000} {
+		b.Run(fmt.Sprintf("payments=%d", size), func(b *testing.B) {
+			storage := &Client{
+				client:    client,
+				namespace: fmt.Sprintf("bench:%d:%d:", time.Now().UnixNano(), size),
+			}
+
+			defer func() {
+				for _, pattern := range []string{storage.namespace + "*", savedPrefix + storage.namespace + "*"} {
+					keys, _ := client.Keys(ctx, pattern).Result()
+					if len(keys) > 0 {
+						client.Del(ctx, keys...)
+					}
+				}
+			}()
+
+			start := time.Now().Add(-time.Duration(size) * time.Millisecond)
+
+			seed(b, ctx, storage, entities.Default, start, size)
+			seed(b, ctx, storage, entities.Fallback, start, size)
+
+			from := start.Add(time.Duration(size/2) * time.Millisecond)
+			to := from.Add(10 * time.Second)
+
+			filters := &entities.PaymentSummaryFilters{From: &from, To: &to}
+
+			b.ResetTimer()
+
+			for range b.N {
+				if _, err := storage.Retrieve(filters); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func seed(
+	b *testing.B,
+	ctx context.Context,
+	storage *Client,
+	processorProvider entities.ProcessorProvider,
+	start time.Time,
+	size int,
+) {
+	b.Helper()
+
+	batch := 10_000
+
+	for offset := 0; offset < size; offset += batch {
+		pipe := storage.client.Pipeline()
+
+		for i := offset; i < offset+batch && i < size; i++ {
+			id := fmt.Sprintf("%s%s-%d", storage.namespace, processorProvider, i)
+			requestedAt := start.Add(time.Duration(i) * time.Millisecond)
+
+			saveScript.Eval(ctx, pipe,
+				storage.paymentKeys(id, processorProvider, requestedAt),
+				paymentArgs(id, processorProvider, requestedAt, entities.NewMoneyFromCents(1990))...,
+			)
+		}
+
+		if _, err := pipe.Exec(ctx); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/constants"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrateLegacyKeysIndexesEntriesThatWereMarkedAsSaved(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	storage := &Client{client: newTestClient(t), namespace: paymentsPrefix}
+	requestedAt := time.Now().UTC().Format(constants.DefaultTimeFormat)
+
+	// the legacy layout wrote the saved marker next to each JSON entry
+	for key, entry := range map[string]string{
+		"default:a":  `{"id":"a","requested_at":"` + requestedAt + `","amount":19.90}`,
+		"default:b":  `{"id":"b","requested_at":"` + requestedAt + `","amount":10.10}`,
+		"fallback:c": `{"id":"c","requested_at":"` + requestedAt + `","amount":5.00}`,
+	} {
+		require.NoError(t, storage.client.Set(ctx, key, entry, 0).Err())
+		require.NoError(t, storage.client.Set(ctx, savedPrefix+key[len(key)-1:], "legacy", 0).Err())
+	}
+
+	require.NoError(t, storage.client.Set(ctx, "default:broken", "not json", 0).Err())
+
+	migrated, err := storage.MigrateLegacyKeys()
+	require.NoError(t, err)
+	assert.Equal(t, 3, migrated)
+
+	remaining, err := storage.client.Keys(ctx, "default:*").Result()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"default:broken"}, remaining)
+
+	summary, err := storage.Retrieve(nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, summary.Default.TotalRequests)
+	assert.Equal(t, "30.00", summary.Default.TotalAmount.String())
+	assert.Equal(t, 1, summary.Fallback.TotalRequests)
+	assert.Equal(t, "5.00", summary.Fallback.TotalAmount.String())
+
+	migrated, err = storage.MigrateLegacyKeys()
+	require.NoError(t, err)
+	assert.Zero(t, migrated)
+
+	// the refreshed marker still keeps a replay from being counted again
+	require.NoError(t, storage.Save(&entities.PaymentPayloadStorage{
+		ID:                "a",
+		Amount:            entities.NewMoneyFromCents(1990),
+		RequestedAt:       requestedAt,
+		ProcessorProvider: entities.Default,
+	}))
+
+	summary, err = storage.Retrieve(nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, summary.Default.TotalRequests)
+}
+
+func TestSaveTrimsTheIndexAndExpiresEachBucket(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	storage := &Client{client: newTestClient(t), namespace: paymentsPrefix}
+	now := time.Now().UTC().Truncate(time.Second)
+
+	for id, requestedAt := range map[string]time.Time{
+		"expired": now.Add(-constants.PaymentEntryTTL - time.Minute),
+		"earlier": now.Add(-2 * time.Minute).Add(250 * time.Millisecond),
+		"recent":  now.Add(-time.Second),
+	} {
+		require.NoError(t, storage.Save(&entities.PaymentPayloadStorage{
+			ID:                id,
+			Amount:            entities.NewMoneyFromCents(1000),
+			RequestedAt:       requestedAt.Format(constants.DefaultTimeFormat),
+			ProcessorProvider: entities.Default,
+		}))
+	}
+
+	indexed, err := storage.client.ZRange(ctx, storage.indexKey(entities.Default), 0, -1).Result()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"earlier:10.00", "recent:10.00"}, indexed)
+
+	buckets, err := storage.client.Keys(ctx, paymentsPrefix+"buckets:default:*").Result()
+	require.NoError(t, err)
+	require.Len(t, buckets, 3)
+
+	for _, bucket := range buckets {
+		ttl, err := storage.client.PTTL(ctx, bucket).Result()
+		require.NoError(t, err)
+		assert.Positive(t, ttl, bucket)
+	}
+
+	// ms precision edges around the earlier payment, whole seconds in between
+	from := now.Add(-2 * time.Minute).Add(100 * time.Millisecond)
+	to := now
+
+	summary, err := storage.Retrieve(&entities.PaymentSummaryFilters{From: &from, To: &to})
+	require.NoError(t, err)
+	assert.Equal(t, 2, summary.Default.TotalRequests)
+	assert.Equal(t, "20.00", summary.Default.TotalAmount.String())
+
+	to = now.Add(-time.Minute)
+
+	summary, err = storage.Retrieve(&entities.PaymentSummaryFilters{From: &from, To: &to})
+	require.NoError(t, err)
+	assert.Equal(t, 1, summary.Default.TotalRequests)
+
+	summary, err = storage.Retrieve(nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, summary.Default.TotalRequests, "buckets past the retention window are not read")
+}
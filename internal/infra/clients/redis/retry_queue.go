@@ -0,0 +1,191 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/constants"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/helpers"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/entities"
+)
+
+const retryProcessingSuffix = ":processing"
+
+// claimRetriesScript first makes the claims whose lease ran out due again,
+// then moves the due payments to the processing set, scored by the end of
+// their lease, in a single round trip.
+var claimRetriesScript = redis.NewScript(`
+local expired = redis.call('ZRANGEBYSCORE', KEYS[2], '-inf', ARGV[1])
+for _, member in ipairs(expired) do
+	redis.call('ZREM', KEYS[2], member)
+	redis.call('ZADD', KEYS[1], ARGV[1], member)
+end
+
+local due = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, ARGV[2])
+for _, member in ipairs(due) do
+	redis.call('ZREM', KEYS[1], member)
+	redis.call('ZADD', KEYS[2], ARGV[3], member)
+end
+
+return due
+`)
+
+// extendRetryScript restarts a claim lease only while the processing set still
+// scores it by the deadline the claimer got, so a claim handed out again after
+// it ran out is left to its new claimer.
+var extendRetryScript = redis.NewScript(`
+local deadline = redis.call('ZSCORE', KEYS[1], ARGV[1])
+if deadline and tonumber(deadline) == tonumber(ARGV[2]) then
+	redis.call('ZADD', KEYS[1], ARGV[3], ARGV[1])
+	return 1
+end
+
+return 0
+`)
+
+type RetryEntry struct {
+	// ID makes every scheduling a member of its own, so acknowledging a claim
+	// never removes a later one of the same payment.
+	ID            string         `json:"id"`
+	CorrelationID string         `json:"correlationId"`
+	LastError     string         `json:"lastError"`
+	Amount        entities.Money `json:"amount"`
+	Attempts      int            `json:"attempts"`
+	Deferrals     int            `json:"deferrals,omitempty"`
+}
+
+// RetryQueue is a sorted set scored by the unix millisecond a payment is due,
+// shared by every instance. Claimed payments move to a processing set until
+// acknowledged, so the retries of an instance that died are not lost.
+type RetryQueue struct {
+	client *redis.Client
+	key    string
+}
+
+func NewRetryQueue(key string) *RetryQueue {
+	return &RetryQueue{
+		client: connect(),
+		key:    key,
+	}
+}
+
+func (q *RetryQueue) Schedule(payment *entities.QueuedPayment, at time.Time) error {
+	ctx := context.Background()
+
+	member, err := helpers.Marshal(RetryEntry{
+		ID:            uuid.NewString(),
+		CorrelationID: payment.CorrelationID,
+		LastError:     payment.LastError,
+		Amount:        payment.Amount,
+		Attempts:      payment.Attempts,
+		Deferrals:     payment.Deferrals,
+	})
+	if err != nil {
+		return fmt.Errorf("error encoding retry entry: %w", err)
+	}
+
+	if err := q.client.ZAdd(ctx, q.key, &redis.Z{
+		Score:  float64(at.UnixMilli()),
+		Member: member,
+	}).Err(); err != nil {
+		return fmt.Errorf("error scheduling retry: %w", err)
+	}
+
+	return nil
+}
+
+func (q *RetryQueue) Len() (int, error) {
+	ctx := context.Background()
+
+	pipe := q.client.Pipeline()
+	scheduled := pipe.ZCard(ctx, q.key)
+	claimed := pipe.ZCard(ctx, q.processingKey())
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, fmt.Errorf("error counting retries: %w", err)
+	}
+
+	return int(scheduled.Val() + claimed.Val()), nil
+}
+
+func (q *RetryQueue) Claim(now time.Time, limit int) ([]*entities.QueuedPayment, error) {
+	ctx := context.Background()
+	leaseUntil := time.UnixMilli(now.Add(constants.PaymentRetryLease).UnixMilli())
+
+	members, err := claimRetriesScript.Run(ctx, q.client,
+		[]string{q.key, q.processingKey()},
+		now.UnixMilli(),
+		limit,
+		leaseUntil.UnixMilli(),
+	).StringSlice()
+	if err != nil {
+		return nil, fmt.Errorf("error claiming due retries: %w", err)
+	}
+
+	payments := make([]*entities.QueuedPayment, 0, len(members))
+
+	for _, member := range members {
+		var entry RetryEntry
+		if err := helpers.Unmarshal([]byte(member), &entry); err != nil {
+			// nothing to retry, it would only come back once its lease ran out
+			q.client.ZRem(ctx, q.processingKey(), member)
+
+			continue
+		}
+
+		payments = append(payments, &entities.QueuedPayment{
+			LeaseUntil:    leaseUntil,
+			RetryID:       member,
+			CorrelationID: entry.CorrelationID,
+			LastError:     entry.LastError,
+			Amount:        entry.Amount,
+			Attempts:      entry.Attempts,
+			Deferrals:     entry.Deferrals,
+		})
+	}
+
+	return payments, nil
+}
+
+func (q *RetryQueue) Extend(payment *entities.QueuedPayment, now time.Time) (bool, error) {
+	leaseUntil := time.UnixMilli(now.Add(constants.PaymentRetryLease).UnixMilli())
+
+	extended, err := extendRetryScript.Run(context.Background(), q.client,
+		[]string{q.processingKey()},
+		payment.RetryID,
+		payment.LeaseUntil.UnixMilli(),
+		leaseUntil.UnixMilli(),
+	).Int()
+	if err != nil {
+		return false, fmt.Errorf("error extending retry lease: %w", err)
+	}
+
+	if extended == 0 {
+		return false, nil
+	}
+
+	payment.LeaseUntil = leaseUntil
+
+	return true, nil
+}
+
+func (q *RetryQueue) Ack(payment *entities.QueuedPayment) error {
+	if payment.RetryID == "" {
+		return nil
+	}
+
+	if err := q.client.ZRem(context.Background(), q.processingKey(), payment.RetryID).Err(); err != nil {
+		return fmt.Errorf("error acknowledging retry: %w", err)
+	}
+
+	payment.RetryID = ""
+
+	return nil
+}
+
+func (q *RetryQueue) processingKey() string {
+	return q.key + retryProcessingSuffix
+}
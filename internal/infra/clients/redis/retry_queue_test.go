@@ -0,0 +1,131 @@
+package redis
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/constants"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestClient connects to an in-memory redis that runs the Lua scripts too.
+func newTestClient(t *testing.T) *redis.Client {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+
+	t.Cleanup(func() { _ = client.Close() })
+
+	return client
+}
+
+func TestRetryQueueKeepsClaimsUntilAcknowledged(t *testing.T) {
+	t.Parallel()
+
+	queue := &RetryQueue{client: newTestClient(t), key: constants.PaymentRetryQueueKey}
+	now := time.Now()
+
+	require.NoError(t, queue.Schedule(&entities.QueuedPayment{CorrelationID: "due", Attempts: 1}, now))
+	require.NoError(t, queue.Schedule(&entities.QueuedPayment{CorrelationID: "later"}, now.Add(time.Minute)))
+
+	claimed, err := queue.Claim(now, constants.PaymentRetryBatchSize)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	assert.Equal(t, "due", claimed[0].CorrelationID)
+	assert.Equal(t, 1, claimed[0].Attempts)
+	assert.NotEmpty(t, claimed[0].RetryID)
+
+	again, err := queue.Claim(now, constants.PaymentRetryBatchSize)
+	require.NoError(t, err)
+	assert.Empty(t, again, "claimed by someone else already")
+
+	length, err := queue.Len()
+	require.NoError(t, err)
+	assert.Equal(t, 2, length, "the claim still counts until acknowledged")
+
+	require.NoError(t, queue.Ack(claimed[0]))
+	assert.Empty(t, claimed[0].RetryID)
+
+	length, err = queue.Len()
+	require.NoError(t, err)
+	assert.Equal(t, 1, length)
+}
+
+func TestRetryQueueHandsExpiredClaimsOutAgain(t *testing.T) {
+	t.Parallel()
+
+	queue := &RetryQueue{client: newTestClient(t), key: constants.PaymentRetryQueueKey}
+	now := time.Now()
+
+	require.NoError(t, queue.Schedule(&entities.QueuedPayment{CorrelationID: "orphan"}, now))
+
+	claimed, err := queue.Claim(now, constants.PaymentRetryBatchSize)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+
+	// the claimer died before acknowledging it
+	reclaimed, err := queue.Claim(now.Add(constants.PaymentRetryLease+time.Millisecond), constants.PaymentRetryBatchSize)
+	require.NoError(t, err)
+	require.Len(t, reclaimed, 1)
+	assert.Equal(t, "orphan", reclaimed[0].CorrelationID)
+}
+
+func TestRetryQueueExtendsOnlyTheLeaseItHandedOut(t *testing.T) {
+	t.Parallel()
+
+	queue := &RetryQueue{client: newTestClient(t), key: constants.PaymentRetryQueueKey}
+	now := time.Now()
+
+	require.NoError(t, queue.Schedule(&entities.QueuedPayment{CorrelationID: "slow"}, now))
+
+	claimed, err := queue.Claim(now, constants.PaymentRetryBatchSize)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+
+	// taken by a worker before the lease ran out, it is not handed out again
+	later := now.Add(constants.PaymentRetryLease - time.Second)
+
+	extended, err := queue.Extend(claimed[0], later)
+	require.NoError(t, err)
+	require.True(t, extended)
+
+	again, err := queue.Claim(now.Add(constants.PaymentRetryLease+time.Millisecond), constants.PaymentRetryBatchSize)
+	require.NoError(t, err)
+	assert.Empty(t, again)
+
+	// taken after the lease ran out and it was claimed again
+	reclaimed, err := queue.Claim(later.Add(constants.PaymentRetryLease+time.Millisecond), constants.PaymentRetryBatchSize)
+	require.NoError(t, err)
+	require.Len(t, reclaimed, 1)
+
+	extended, err = queue.Extend(claimed[0], later.Add(constants.PaymentRetryLease+time.Second))
+	require.NoError(t, err)
+	assert.False(t, extended)
+}
+
+func TestRetryQueueAckLeavesALaterSchedulingAlone(t *testing.T) {
+	t.Parallel()
+
+	queue := &RetryQueue{client: newTestClient(t), key: constants.PaymentRetryQueueKey}
+	now := time.Now()
+
+	require.NoError(t, queue.Schedule(&entities.QueuedPayment{CorrelationID: "postponed"}, now))
+
+	claimed, err := queue.Claim(now, constants.PaymentRetryBatchSize)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+
+	// scheduled again as it is, then acknowledged
+	require.NoError(t, queue.Schedule(claimed[0], now))
+	require.NoError(t, queue.Ack(claimed[0]))
+
+	rescheduled, err := queue.Claim(now, constants.PaymentRetryBatchSize)
+	require.NoError(t, err)
+	require.Len(t, rescheduled, 1)
+	assert.Equal(t, "postponed", rescheduled[0].CorrelationID)
+}
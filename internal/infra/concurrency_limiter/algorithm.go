@@ -0,0 +1,94 @@
+package concurrencylimiter
+
+import (
+	"math"
+	"time"
+
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/constants"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/entities"
+)
+
+const (
+	// the usual latency follows the samples this slowly.
+	longLatencySmoothing = 0.01
+	// and the limit moves this much of the way to its new value.
+	limitSmoothing   = 0.2
+	minGradient      = 0.5
+	utilizationRatio = 2
+)
+
+// algorithm computes the next limit once a call ended, inFlight still
+// counting it. None of them grows a limit that is not being used.
+type algorithm interface {
+	update(limit float64, latency time.Duration, dropped bool, inFlight int) float64
+}
+
+func newAlgorithm(policy entities.ConcurrencyLimitPolicy) algorithm {
+	switch policy.Mode {
+	case constants.ConcurrencyLimitModeAIMD:
+		return &aimd{slowCall: policy.SlowCallDuration, backoff: policy.Backoff}
+	case constants.ConcurrencyLimitModeGradient:
+		return &gradient{tolerance: policy.Tolerance}
+	default:
+		return fixed{}
+	}
+}
+
+type fixed struct{}
+
+func (fixed) update(limit float64, _ time.Duration, _ bool, _ int) float64 {
+	return limit
+}
+
+// aimd adds one to the limit every limit successful calls, about once per
+// round of calls, and multiplies it by backoff on a failed or slow one.
+type aimd struct {
+	slowCall time.Duration
+	backoff  float64
+}
+
+func (a *aimd) update(limit float64, latency time.Duration, dropped bool, inFlight int) float64 {
+	if dropped || latency >= a.slowCall {
+		return limit * a.backoff
+	}
+
+	if float64(inFlight*utilizationRatio) < limit {
+		return limit
+	}
+
+	return limit + 1/limit
+}
+
+// gradient compares the latency of each call with a slow moving average of
+// them: while it stays under tolerance times the average the limit grows by
+// its square root, past that it shrinks in proportion, down to half at once.
+type gradient struct {
+	longLatency float64
+	tolerance   float64
+}
+
+func (g *gradient) update(limit float64, latency time.Duration, dropped bool, inFlight int) float64 {
+	sample := float64(max(latency, time.Microsecond))
+
+	if g.longLatency == 0 {
+		g.longLatency = sample
+	}
+
+	g.longLatency += (sample - g.longLatency) * longLatencySmoothing
+
+	ratio := minGradient
+	if !dropped {
+		ratio = math.Max(minGradient, math.Min(1, g.tolerance*g.longLatency/sample))
+	}
+
+	if ratio == 1 && float64(inFlight*utilizationRatio) < limit {
+		return limit
+	}
+
+	next := limit*ratio + math.Sqrt(limit)
+	if ratio < 1 {
+		next = limit * ratio
+	}
+
+	return limit*(1-limitSmoothing) + next*limitSmoothing
+}
@@ -0,0 +1,148 @@
+package concurrencylimiter
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/constants"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/entities"
+)
+
+// Limiter lets at most Limit calls run at once, adjusting the limit after
+// every call from its latency and outcome. Calls over the limit wait for one
+// to end instead of failing, so callers feel the processor slowing down.
+type Limiter struct {
+	algorithm   algorithm
+	subscribers []func(int)
+	cond        *sync.Cond
+	policy      entities.ConcurrencyLimitPolicy
+	limit       float64
+	inFlight    int
+	mutex       sync.Mutex
+}
+
+func New(policy entities.ConcurrencyLimitPolicy) *Limiter {
+	policy = withDefaults(policy)
+
+	limiter := &Limiter{
+		algorithm: newAlgorithm(policy),
+		policy:    policy,
+		limit:     float64(policy.InitialLimit),
+	}
+
+	limiter.cond = sync.NewCond(&limiter.mutex)
+
+	return limiter
+}
+
+func (l *Limiter) Acquire(ctx context.Context) error {
+	// wakes the waiters up to see the context is done
+	stop := context.AfterFunc(ctx, func() {
+		l.mutex.Lock()
+		defer l.mutex.Unlock()
+
+		l.cond.Broadcast()
+	})
+	defer stop()
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	for l.inFlight >= int(l.limit) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		l.cond.Wait()
+	}
+
+	l.inFlight++
+
+	return nil
+}
+
+func (l *Limiter) Release(latency time.Duration, dropped bool) {
+	l.mutex.Lock()
+
+	previous := int(l.limit)
+
+	l.limit = l.algorithm.update(l.limit, latency, dropped, l.inFlight)
+	l.limit = math.Max(float64(l.policy.MinLimit), math.Min(float64(l.policy.MaxLimit), l.limit))
+	l.inFlight--
+
+	limit := int(l.limit)
+	subscribers := l.subscribers
+
+	// a grown limit may let several waiting calls through
+	l.cond.Broadcast()
+	l.mutex.Unlock()
+
+	if limit == previous {
+		return
+	}
+
+	for _, subscriber := range subscribers {
+		subscriber(limit)
+	}
+}
+
+func (l *Limiter) Cancel() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.inFlight--
+	l.cond.Signal()
+}
+
+func (l *Limiter) Limit() int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return int(l.limit)
+}
+
+func (l *Limiter) InFlight() int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return l.inFlight
+}
+
+func (l *Limiter) Subscribe(subscriber func(limit int)) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.subscribers = append(l.subscribers, subscriber)
+}
+
+func withDefaults(policy entities.ConcurrencyLimitPolicy) entities.ConcurrencyLimitPolicy {
+	if policy.MinLimit <= 0 {
+		policy.MinLimit = constants.DefaultConcurrencyMinLimit
+	}
+
+	if policy.MaxLimit < policy.MinLimit {
+		policy.MaxLimit = max(policy.MinLimit, constants.DefaultConcurrencyMaxLimit)
+	}
+
+	if policy.InitialLimit <= 0 {
+		policy.InitialLimit = constants.DefaultConcurrencyLimit
+	}
+
+	policy.InitialLimit = min(max(policy.InitialLimit, policy.MinLimit), policy.MaxLimit)
+
+	if policy.SlowCallDuration <= 0 {
+		policy.SlowCallDuration = constants.DefaultConcurrencySlowCall
+	}
+
+	if policy.Backoff <= 0 || policy.Backoff >= 1 {
+		policy.Backoff = constants.DefaultConcurrencyBackoff
+	}
+
+	if policy.Tolerance < 1 {
+		policy.Tolerance = constants.DefaultConcurrencyTolerance
+	}
+
+	return policy
+}
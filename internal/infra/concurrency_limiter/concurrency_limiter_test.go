@@ -0,0 +1,128 @@
+package concurrencylimiter_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/constants"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/entities"
+	concurrencylimiter "github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/infra/concurrency_limiter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// call runs calls at once, each ending with the given latency and outcome.
+func call(limiter *concurrencylimiter.Limiter, calls int, latency time.Duration, dropped bool) {
+	for range calls {
+		_ = limiter.Acquire(context.Background())
+	}
+
+	for range calls {
+		limiter.Release(latency, dropped)
+	}
+}
+
+func TestAcquireWaitsForRoomUnderTheLimit(t *testing.T) {
+	t.Parallel()
+
+	limiter := concurrencylimiter.New(entities.ConcurrencyLimitPolicy{
+		Mode:         constants.ConcurrencyLimitModeFixed,
+		InitialLimit: 1,
+	})
+
+	require.NoError(t, limiter.Acquire(context.Background()))
+
+	var acquired atomic.Bool
+
+	go func() {
+		assert.NoError(t, limiter.Acquire(context.Background()))
+		acquired.Store(true)
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	assert.False(t, acquired.Load())
+
+	limiter.Release(time.Millisecond, false)
+
+	assert.Eventually(t, acquired.Load, time.Second, time.Millisecond)
+	assert.Equal(t, 1, limiter.InFlight())
+}
+
+func TestAcquireGivesUpOnceTheContextIsDone(t *testing.T) {
+	t.Parallel()
+
+	limiter := concurrencylimiter.New(entities.ConcurrencyLimitPolicy{
+		Mode:         constants.ConcurrencyLimitModeFixed,
+		InitialLimit: 1,
+	})
+
+	require.NoError(t, limiter.Acquire(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	require.ErrorIs(t, limiter.Acquire(ctx), context.DeadlineExceeded)
+	assert.Equal(t, 1, limiter.InFlight(), "a call that gave up takes no slot")
+}
+
+func TestAIMDGrowsWhileUsedAndBacksOffOnFailures(t *testing.T) {
+	t.Parallel()
+
+	limiter := concurrencylimiter.New(entities.ConcurrencyLimitPolicy{
+		Mode:             constants.ConcurrencyLimitModeAIMD,
+		InitialLimit:     4,
+		MaxLimit:         8,
+		SlowCallDuration: 100 * time.Millisecond,
+		Backoff:          0.5,
+	})
+
+	var limits []int
+
+	limiter.Subscribe(func(limit int) { limits = append(limits, limit) })
+
+	// a single call at a time does not show whether more would fit
+	for range 20 {
+		call(limiter, 1, time.Millisecond, false)
+	}
+
+	assert.Equal(t, 4, limiter.Limit())
+
+	for range 20 {
+		call(limiter, limiter.Limit(), time.Millisecond, false)
+	}
+
+	assert.Equal(t, 8, limiter.Limit(), "capped at the max limit")
+
+	call(limiter, 1, time.Millisecond, true)
+	assert.Equal(t, 4, limiter.Limit())
+
+	call(limiter, 1, 200*time.Millisecond, false)
+	assert.Equal(t, 2, limiter.Limit(), "slow calls back off too")
+
+	assert.Equal(t, []int{5, 6, 7, 8, 4, 2}, limits)
+}
+
+func TestGradientShrinksWhenLatencyClimbs(t *testing.T) {
+	t.Parallel()
+
+	limiter := concurrencylimiter.New(entities.ConcurrencyLimitPolicy{
+		Mode:         constants.ConcurrencyLimitModeGradient,
+		InitialLimit: 10,
+		MaxLimit:     40,
+	})
+
+	for range 20 {
+		call(limiter, limiter.Limit(), 10*time.Millisecond, false)
+	}
+
+	grown := limiter.Limit()
+	assert.Greater(t, grown, 10)
+
+	for range 5 {
+		call(limiter, 1, 100*time.Millisecond, false)
+	}
+
+	assert.Less(t, limiter.Limit(), grown)
+}
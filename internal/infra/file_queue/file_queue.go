@@ -0,0 +1,292 @@
+package filequeue
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/helpers"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/entities"
+)
+
+const (
+	enqueueOperation = "enqueue"
+	ackOperation     = "ack"
+
+	// truncate the log once everything was acknowledged and it grew past this size.
+	compactThreshold = 1 << 20
+
+	filePermission      = 0o600
+	directoryPermission = 0o755
+)
+
+var errQueueClosed = errors.New("queue closed")
+
+// Queue is an append-only log of enqueue/ack records. Every write goes straight
+// to the file, so a killed process loses nothing that was already accepted.
+type Queue struct {
+	file     *os.File
+	pending  map[string]*entities.QueuedPayment
+	path     string
+	mutex    sync.Mutex
+	sequence uint64
+	written  int64
+}
+
+func New(path string) *Queue {
+	queue := &Queue{
+		path:    path,
+		pending: map[string]*entities.QueuedPayment{},
+	}
+
+	if err := queue.open(); err != nil {
+		log.Fatal(
+			map[string]interface{}{
+				"message": "error opening payment queue file",
+				"path":    path,
+				"error":   err,
+			},
+		)
+	}
+
+	return queue
+}
+
+func (q *Queue) Enqueue(payment *entities.QueuedPayment) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.sequence++
+
+	payment.ID = strconv.FormatUint(q.sequence, 10)
+
+	if err := q.write(&Record{
+		Operation:     enqueueOperation,
+		ID:            payment.ID,
+		CorrelationID: payment.CorrelationID,
+		Amount:        payment.Amount,
+	}); err != nil {
+		return err
+	}
+
+	q.pending[payment.ID] = payment
+
+	return nil
+}
+
+func (q *Queue) Ack(payment *entities.QueuedPayment) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if _, ok := q.pending[payment.ID]; !ok {
+		return nil
+	}
+
+	if err := q.write(&Record{
+		Operation: ackOperation,
+		ID:        payment.ID,
+	}); err != nil {
+		return err
+	}
+
+	delete(q.pending, payment.ID)
+
+	if len(q.pending) == 0 && q.written > compactThreshold {
+		return q.truncate()
+	}
+
+	return nil
+}
+
+func (q *Queue) Pending() ([]*entities.QueuedPayment, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	payments := make([]*entities.QueuedPayment, 0, len(q.pending))
+	for _, payment := range q.pending {
+		payments = append(payments, payment)
+	}
+
+	slices.SortFunc(payments, func(a, b *entities.QueuedPayment) int {
+		return compareIDs(a.ID, b.ID)
+	})
+
+	return payments, nil
+}
+
+func (q *Queue) Len() (int, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	return len(q.pending), nil
+}
+
+func (q *Queue) Close() error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if q.file == nil {
+		return nil
+	}
+
+	err := q.file.Close()
+	q.file = nil
+
+	if err != nil {
+		return fmt.Errorf("error closing queue file: %w", err)
+	}
+
+	return nil
+}
+
+// open replays the existing log and rewrites it with only the pending records.
+func (q *Queue) open() error {
+	if err := os.MkdirAll(filepath.Dir(q.path), directoryPermission); err != nil {
+		return fmt.Errorf("error creating queue directory: %w", err)
+	}
+
+	if err := q.replay(); err != nil {
+		return err
+	}
+
+	payments, _ := q.Pending()
+
+	temporaryPath := q.path + ".tmp"
+
+	//nolint:gosec // path comes from configuration
+	file, err := os.OpenFile(temporaryPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, filePermission)
+	if err != nil {
+		return fmt.Errorf("error creating compacted queue file: %w", err)
+	}
+
+	q.file = file
+	q.written = 0
+
+	for _, payment := range payments {
+		if err := q.write(&Record{
+			Operation:     enqueueOperation,
+			ID:            payment.ID,
+			CorrelationID: payment.CorrelationID,
+			Amount:        payment.Amount,
+		}); err != nil {
+			return err
+		}
+	}
+
+	if err := os.Rename(temporaryPath, q.path); err != nil {
+		return fmt.Errorf("error replacing queue file: %w", err)
+	}
+
+	return nil
+}
+
+func (q *Queue) replay() error {
+	//nolint:gosec // path comes from configuration
+	file, err := os.Open(q.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("error opening queue file: %w", err)
+	}
+
+	defer func() {
+		if err := file.Close(); err != nil {
+			log.Print(
+				map[string]interface{}{
+					"message": "error closing queue file",
+					"error":   err,
+				},
+			)
+		}
+	}()
+
+	scanner := bufio.NewScanner(file)
+
+	for scanner.Scan() {
+		var record Record
+
+		// a partially written last line means the process died mid write
+		if err := helpers.Unmarshal(scanner.Bytes(), &record); err != nil {
+			continue
+		}
+
+		if sequence, err := strconv.ParseUint(record.ID, 10, 64); err == nil && sequence > q.sequence {
+			q.sequence = sequence
+		}
+
+		switch record.Operation {
+		case enqueueOperation:
+			q.pending[record.ID] = &entities.QueuedPayment{
+				ID:            record.ID,
+				CorrelationID: record.CorrelationID,
+				Amount:        record.Amount,
+			}
+		case ackOperation:
+			delete(q.pending, record.ID)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error reading queue file: %w", err)
+	}
+
+	return nil
+}
+
+func (q *Queue) write(record *Record) error {
+	if q.file == nil {
+		return errQueueClosed
+	}
+
+	line, err := helpers.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("error encoding queue record: %w", err)
+	}
+
+	line = append(line, '\n')
+
+	written, err := q.file.Write(line)
+	q.written += int64(written)
+
+	if err != nil {
+		return fmt.Errorf("error writing queue record: %w", err)
+	}
+
+	return nil
+}
+
+func (q *Queue) truncate() error {
+	if err := q.file.Truncate(0); err != nil {
+		return fmt.Errorf("error truncating queue file: %w", err)
+	}
+
+	if _, err := q.file.Seek(0, 0); err != nil {
+		return fmt.Errorf("error rewinding queue file: %w", err)
+	}
+
+	q.written = 0
+
+	return nil
+}
+
+func compareIDs(a, b string) int {
+	first, _ := strconv.ParseUint(a, 10, 64)
+	second, _ := strconv.ParseUint(b, 10, 64)
+
+	switch {
+	case first < second:
+		return -1
+	case first > second:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,68 @@
+package filequeue_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/entities"
+	filequeue "github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/infra/file_queue"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueueReplaysPendingPaymentsAfterReopen(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "queue.log")
+
+	queue := filequeue.New(path)
+
+	first := &entities.QueuedPayment{CorrelationID: "first", Amount: entities.NewMoneyFromCents(1990)}
+	second := &entities.QueuedPayment{CorrelationID: "second", Amount: entities.NewMoneyFromCents(1000)}
+	third := &entities.QueuedPayment{CorrelationID: "third", Amount: entities.NewMoneyFromCents(1)}
+
+	for _, payment := range []*entities.QueuedPayment{first, second, third} {
+		require.NoError(t, queue.Enqueue(payment))
+	}
+
+	require.NoError(t, queue.Ack(second))
+	require.NoError(t, queue.Close())
+
+	reopened := filequeue.New(path)
+
+	pending, err := reopened.Pending()
+	require.NoError(t, err)
+
+	assert.Len(t, pending, 2)
+	assert.Equal(t, "first", pending[0].CorrelationID)
+	assert.Equal(t, entities.NewMoneyFromCents(1990), pending[0].Amount)
+	assert.Equal(t, "third", pending[1].CorrelationID)
+
+	fourth := &entities.QueuedPayment{CorrelationID: "fourth", Amount: entities.NewMoneyFromCents(100)}
+	require.NoError(t, reopened.Enqueue(fourth))
+	assert.NotContains(t, []string{first.ID, third.ID}, fourth.ID, "ids must not be reused after a reopen")
+}
+
+func TestQueueIgnoresPartiallyWrittenRecord(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "queue.log")
+
+	queue := filequeue.New(path)
+	require.NoError(t, queue.Enqueue(&entities.QueuedPayment{CorrelationID: "complete", Amount: entities.NewMoneyFromCents(500)}))
+	require.NoError(t, queue.Close())
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600)
+	require.NoError(t, err)
+
+	_, err = file.WriteString(`{"op":"enqueue","id":"2","correlat`)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	pending, err := filequeue.New(path).Pending()
+	require.NoError(t, err)
+
+	assert.Len(t, pending, 1)
+	assert.Equal(t, "complete", pending[0].CorrelationID)
+}
@@ -0,0 +1,10 @@
+package filequeue
+
+import "github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/entities"
+
+type Record struct {
+	Operation     string         `json:"op"`
+	ID            string         `json:"id"`
+	CorrelationID string         `json:"correlationId,omitempty"`
+	Amount        entities.Money `json:"amount,omitempty"`
+}
@@ -0,0 +1,253 @@
+package healthmonitor
+
+import (
+	"errors"
+	"log"
+	"maps"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/constants"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/contracts"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/entities"
+)
+
+type snapshot = map[entities.ProcessorProvider]entities.ProcessorHealth
+
+// Monitor polls the processors health from a single leader instance, since
+// the health endpoint only answers once every 5 seconds, and shares what it
+// saw through the snapshot store. Every instance, the leader included, routes
+// on the snapshot it last read from the store.
+//
+// Snapshots are immutable once stored: readers get a consistent view of every
+// processor without locking, and a refresh swaps the whole map at once.
+type Monitor struct {
+	elector   contracts.LeaderElector
+	store     contracts.HealthSnapshotStore
+	checkers  map[entities.ProcessorProvider]contracts.ProcessorHealthChecker
+	published snapshot
+	snapshot  atomic.Pointer[snapshot]
+	mutex     sync.RWMutex
+	pollMutex sync.Mutex
+	interval  time.Duration
+	refresh   time.Duration
+	leading   atomic.Bool
+	resigned  atomic.Bool
+}
+
+func New(
+	elector contracts.LeaderElector,
+	store contracts.HealthSnapshotStore,
+	interval time.Duration,
+	refresh time.Duration,
+) *Monitor {
+	monitor := &Monitor{
+		elector:   elector,
+		store:     store,
+		checkers:  map[entities.ProcessorProvider]contracts.ProcessorHealthChecker{},
+		interval:  interval,
+		refresh:   refresh,
+		published: snapshot{},
+	}
+
+	monitor.snapshot.Store(&snapshot{})
+
+	return monitor
+}
+
+func (m *Monitor) Register(processorProvider entities.ProcessorProvider, checker contracts.ProcessorHealthChecker) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.checkers[processorProvider] = checker
+}
+
+func (m *Monitor) ProcessorHealth(processorProvider entities.ProcessorProvider) (entities.ProcessorHealth, bool) {
+	health, ok := (*m.snapshot.Load())[processorProvider]
+
+	return health, ok
+}
+
+func (m *Monitor) ProcessorsHealth() map[entities.ProcessorProvider]entities.ProcessorHealth {
+	return *m.snapshot.Load()
+}
+
+func (m *Monitor) Leading() bool {
+	return m.leading.Load()
+}
+
+// Run polls as leader, or keeps asking for the lease, every interval and reads
+// the shared snapshot every refresh.
+func (m *Monitor) Run() {
+	m.Poll()
+	m.Refresh()
+
+	pollTicker := time.NewTicker(m.interval)
+	defer pollTicker.Stop()
+
+	refreshTicker := time.NewTicker(m.refresh)
+	defer refreshTicker.Stop()
+
+	for {
+		select {
+		case <-pollTicker.C:
+			m.Poll()
+		case <-refreshTicker.C:
+			m.Refresh()
+		}
+	}
+}
+
+// Poll checks every processor and publishes the result when this instance
+// holds the lease, which it no longer asks for once it resigned.
+func (m *Monitor) Poll() {
+	m.pollMutex.Lock()
+	defer m.pollMutex.Unlock()
+
+	if m.resigned.Load() {
+		return
+	}
+
+	leading, err := m.elector.TryLead()
+	if err != nil {
+		go log.Print(
+			map[string]interface{}{
+				"action": "error acquiring health leadership",
+				"error":  err,
+			},
+		)
+	}
+
+	if leading != m.leading.Swap(leading) {
+		if leading {
+			// carry on from what the previous leader published
+			m.published = maps.Clone(*m.snapshot.Load())
+		}
+
+		go log.Print(
+			map[string]interface{}{
+				"action":  "health leadership changed",
+				"leading": leading,
+			},
+		)
+	}
+
+	if !leading {
+		return
+	}
+
+	m.mutex.RLock()
+	checkers := maps.Clone(m.checkers)
+	m.mutex.RUnlock()
+
+	for processorProvider, check := range m.check(checkers) {
+		switch {
+		case errors.Is(check.err, constants.ErrHealthRateLimited):
+			// someone else spent this window, keep what was seen last
+			previous, ok := m.published[processorProvider]
+			if ok {
+				previous.Source = entities.HealthSourceCached
+				previous.LastError = check.err.Error()
+				m.published[processorProvider] = previous
+			}
+		case check.err != nil:
+			m.published[processorProvider] = entities.ProcessorHealth{
+				LastChecked: time.Now().UTC(),
+				LastError:   check.err.Error(),
+				Source:      entities.HealthSourceUnreachable,
+				Failing:     true,
+			}
+		default:
+			m.published[processorProvider] = *check.health
+		}
+	}
+
+	if err := m.store.Publish(m.published); err != nil {
+		go log.Print(
+			map[string]interface{}{
+				"action": "error publishing health snapshot",
+				"error":  err,
+			},
+		)
+
+		return
+	}
+
+	m.Refresh()
+}
+
+type healthCheck struct {
+	health            *entities.ProcessorHealth
+	err               error
+	processorProvider entities.ProcessorProvider
+}
+
+// check asks every processor at once and gives up on the ones still silent
+// after half the interval, so one hung processor can't hold the poll past the
+// lease and leave the others unpublished.
+func (m *Monitor) check(
+	checkers map[entities.ProcessorProvider]contracts.ProcessorHealthChecker,
+) map[entities.ProcessorProvider]healthCheck {
+	// buffered so the late answers don't block once nobody is waiting
+	answers := make(chan healthCheck, len(checkers))
+
+	for processorProvider, checker := range checkers {
+		go func() {
+			health, err := checker.CheckHealth()
+
+			answers <- healthCheck{health: health, err: err, processorProvider: processorProvider}
+		}()
+	}
+
+	timeout := time.NewTimer(m.interval / 2)
+	defer timeout.Stop()
+
+	checks := make(map[entities.ProcessorProvider]healthCheck, len(checkers))
+
+	for len(checks) < len(checkers) {
+		select {
+		case check := <-answers:
+			checks[check.processorProvider] = check
+		case <-timeout.C:
+			for processorProvider := range checkers {
+				if _, ok := checks[processorProvider]; !ok {
+					checks[processorProvider] = healthCheck{err: constants.ErrHealthCheckTimedOut}
+				}
+			}
+		}
+	}
+
+	return checks
+}
+
+// Refresh replaces the local snapshot with the shared one.
+func (m *Monitor) Refresh() {
+	loaded, err := m.store.Load()
+	if err != nil {
+		// keep routing on the last snapshot until the store answers again
+		go log.Print(
+			map[string]interface{}{
+				"action": "error loading health snapshot",
+				"error":  err,
+			},
+		)
+
+		return
+	}
+
+	m.snapshot.Store(&loaded)
+}
+
+// Resign hands leadership over to the next instance asking for it, for good:
+// it waits for a poll under way so the lease is not taken back.
+func (m *Monitor) Resign() error {
+	m.resigned.Store(true)
+
+	m.pollMutex.Lock()
+	defer m.pollMutex.Unlock()
+
+	m.leading.Store(false)
+
+	return m.elector.Resign()
+}
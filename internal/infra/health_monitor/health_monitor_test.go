@@ -0,0 +1,266 @@
+package healthmonitor_test
+
+import (
+	"errors"
+	"maps"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/constants"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/entities"
+	healthmonitor "github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/infra/health_monitor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// lease is the shared key instances compete for.
+type lease struct {
+	holder string
+	mutex  sync.Mutex
+}
+
+type fakeElector struct {
+	lease  *lease
+	holder string
+}
+
+func (e *fakeElector) TryLead() (bool, error) {
+	e.lease.mutex.Lock()
+	defer e.lease.mutex.Unlock()
+
+	if e.lease.holder == "" {
+		e.lease.holder = e.holder
+	}
+
+	return e.lease.holder == e.holder, nil
+}
+
+func (e *fakeElector) Resign() error {
+	e.lease.mutex.Lock()
+	defer e.lease.mutex.Unlock()
+
+	if e.lease.holder == e.holder {
+		e.lease.holder = ""
+	}
+
+	return nil
+}
+
+type fakeStore struct {
+	snapshot map[entities.ProcessorProvider]entities.ProcessorHealth
+	mutex    sync.Mutex
+}
+
+func (s *fakeStore) Publish(snapshot map[entities.ProcessorProvider]entities.ProcessorHealth) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.snapshot = maps.Clone(snapshot)
+
+	return nil
+}
+
+func (s *fakeStore) Load() (map[entities.ProcessorProvider]entities.ProcessorHealth, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return maps.Clone(s.snapshot), nil
+}
+
+var errUnreachable = errors.New("connection refused")
+
+type fakeChecker struct {
+	health *entities.ProcessorHealth
+	err    error
+	calls  atomic.Int64
+}
+
+func (c *fakeChecker) CheckHealth() (*entities.ProcessorHealth, error) {
+	c.calls.Add(1)
+
+	return c.health, c.err
+}
+
+// hungChecker never answers until released.
+type hungChecker struct {
+	release chan struct{}
+}
+
+func (c *hungChecker) CheckHealth() (*entities.ProcessorHealth, error) {
+	<-c.release
+
+	return &entities.ProcessorHealth{}, nil
+}
+
+func newMonitor(shared *lease, store *fakeStore, holder string) *healthmonitor.Monitor {
+	return healthmonitor.New(&fakeElector{lease: shared, holder: holder}, store, time.Second, time.Second)
+}
+
+func TestOnlyTheLeaderPollsAndEveryoneReads(t *testing.T) {
+	t.Parallel()
+
+	shared := &lease{}
+	store := &fakeStore{}
+
+	first := newMonitor(shared, store, "first")
+	second := newMonitor(shared, store, "second")
+
+	firstChecker := &fakeChecker{health: &entities.ProcessorHealth{MinResponseTime: 12}}
+	secondChecker := &fakeChecker{health: &entities.ProcessorHealth{MinResponseTime: 12}}
+
+	first.Register(entities.Default, firstChecker)
+	second.Register(entities.Default, secondChecker)
+
+	first.Poll()
+	second.Poll()
+	second.Refresh()
+
+	assert.True(t, first.Leading())
+	assert.False(t, second.Leading())
+	assert.Equal(t, int64(1), firstChecker.calls.Load())
+	assert.Zero(t, secondChecker.calls.Load())
+
+	health, ok := second.ProcessorHealth(entities.Default)
+	require.True(t, ok)
+	assert.Equal(t, 12, health.MinResponseTime)
+
+	// the leader goes away and the follower takes over on its next poll
+	require.NoError(t, first.Resign())
+
+	first.Poll()
+	assert.False(t, first.Leading(), "a resigned monitor does not take the lease back")
+
+	secondChecker.health = &entities.ProcessorHealth{Failing: true}
+
+	second.Poll()
+	first.Refresh()
+
+	assert.True(t, second.Leading())
+	assert.Equal(t, int64(1), secondChecker.calls.Load())
+
+	health, ok = first.ProcessorHealth(entities.Default)
+	require.True(t, ok)
+	assert.True(t, health.Failing)
+}
+
+func TestRateLimitedCheckKeepsLastHealth(t *testing.T) {
+	t.Parallel()
+
+	monitor := newMonitor(&lease{}, &fakeStore{}, "only")
+
+	checker := &fakeChecker{health: &entities.ProcessorHealth{MinResponseTime: 30}}
+	monitor.Register(entities.Default, checker)
+
+	monitor.Poll()
+
+	checker.health, checker.err = nil, constants.ErrHealthRateLimited
+
+	monitor.Poll()
+
+	health, ok := monitor.ProcessorHealth(entities.Default)
+	require.True(t, ok)
+	assert.False(t, health.Failing)
+	assert.Equal(t, 30, health.MinResponseTime)
+	assert.Equal(t, entities.HealthSourceCached, health.Source)
+	assert.Equal(t, constants.ErrHealthRateLimited.Error(), health.LastError)
+
+	_, ok = monitor.ProcessorHealth(entities.Fallback)
+	assert.False(t, ok)
+}
+
+func TestUnreachableProcessorIsFailing(t *testing.T) {
+	t.Parallel()
+
+	monitor := newMonitor(&lease{}, &fakeStore{}, "only")
+	monitor.Register(entities.Fallback, &fakeChecker{err: errUnreachable})
+
+	monitor.Poll()
+
+	health := monitor.ProcessorsHealth()[entities.Fallback]
+	assert.True(t, health.Failing)
+	assert.Equal(t, entities.HealthSourceUnreachable, health.Source)
+	assert.Equal(t, errUnreachable.Error(), health.LastError)
+	assert.False(t, health.LastChecked.IsZero())
+}
+
+func TestHungProcessorDoesNotHoldThePoll(t *testing.T) {
+	t.Parallel()
+
+	interval := 200 * time.Millisecond
+	monitor := healthmonitor.New(&fakeElector{lease: &lease{}, holder: "only"}, &fakeStore{}, interval, interval)
+
+	hung := &hungChecker{release: make(chan struct{})}
+	defer close(hung.release)
+
+	monitor.Register(entities.Default, hung)
+	monitor.Register(entities.Fallback, &fakeChecker{health: &entities.ProcessorHealth{MinResponseTime: 7}})
+
+	started := time.Now()
+
+	monitor.Poll()
+
+	assert.Less(t, time.Since(started), interval, "the poll must publish before the next one is due")
+
+	health := monitor.ProcessorsHealth()
+	assert.True(t, health[entities.Default].Failing)
+	assert.Equal(t, constants.ErrHealthCheckTimedOut.Error(), health[entities.Default].LastError)
+	assert.False(t, health[entities.Fallback].Failing)
+	assert.Equal(t, 7, health[entities.Fallback].MinResponseTime)
+}
+
+// run with -race: readers never see a snapshot being written.
+func TestConcurrentReadsWhilePolling(t *testing.T) {
+	t.Parallel()
+
+	shared := &lease{}
+	store := &fakeStore{}
+
+	leader := newMonitor(shared, store, "leader")
+	follower := newMonitor(shared, store, "follower")
+
+	leader.Register(entities.Default, &fakeChecker{health: &entities.ProcessorHealth{MinResponseTime: 5}})
+	leader.Register(entities.Fallback, &fakeChecker{err: errUnreachable})
+
+	// take the lease first, the follower has nothing to check
+	leader.Poll()
+
+	var waitGroup sync.WaitGroup
+
+	for _, monitor := range []*healthmonitor.Monitor{leader, follower} {
+		waitGroup.Add(1)
+
+		go func() {
+			defer waitGroup.Done()
+
+			for range 100 {
+				monitor.Poll()
+				monitor.Refresh()
+			}
+		}()
+
+		for range 8 {
+			waitGroup.Add(1)
+
+			go func() {
+				defer waitGroup.Done()
+
+				for range 1000 {
+					health, ok := monitor.ProcessorHealth(entities.Default)
+					if ok {
+						assert.Equal(t, 5, health.MinResponseTime)
+					}
+
+					for _, health := range monitor.ProcessorsHealth() {
+						_ = health.Failing
+					}
+				}
+			}()
+		}
+	}
+
+	waitGroup.Wait()
+
+	assert.Len(t, follower.ProcessorsHealth(), 2)
+}
@@ -0,0 +1,220 @@
+package metrics
+
+import (
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	counterType = "counter"
+	gaugeType   = "gauge"
+
+	labelSeparator = "\xff"
+)
+
+// Registry keeps counters and gauges and renders them in the Prometheus text
+// exposition format, enough for a scraper without pulling a client library in.
+type Registry struct {
+	families []*family
+	mutex    sync.RWMutex
+}
+
+type family struct {
+	series     map[string]*series
+	valueFunc  func() float64
+	name       string
+	help       string
+	metricType string
+	labelNames []string
+	mutex      sync.RWMutex
+}
+
+type series struct {
+	labelValues []string
+	bits        atomic.Uint64
+}
+
+type Counter struct {
+	family *family
+}
+
+type Gauge struct {
+	family *family
+}
+
+func New() *Registry {
+	return &Registry{}
+}
+
+func (r *Registry) Counter(name, help string, labelNames ...string) *Counter {
+	return &Counter{family: r.register(name, help, counterType, labelNames, nil)}
+}
+
+func (r *Registry) Gauge(name, help string, labelNames ...string) *Gauge {
+	return &Gauge{family: r.register(name, help, gaugeType, labelNames, nil)}
+}
+
+// GaugeFunc reports whatever valueFunc returns at render time.
+func (r *Registry) GaugeFunc(name, help string, valueFunc func() float64) {
+	r.register(name, help, gaugeType, nil, valueFunc)
+}
+
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+func (c *Counter) Add(value float64, labelValues ...string) {
+	if c == nil {
+		return
+	}
+
+	c.family.get(labelValues).add(value)
+}
+
+func (g *Gauge) Set(value float64, labelValues ...string) {
+	if g == nil {
+		return
+	}
+
+	g.family.get(labelValues).bits.Store(math.Float64bits(value))
+}
+
+func (g *Gauge) Add(value float64, labelValues ...string) {
+	if g == nil {
+		return
+	}
+
+	g.family.get(labelValues).add(value)
+}
+
+func (r *Registry) Render() []byte {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	var builder strings.Builder
+
+	for _, family := range r.families {
+		family.render(&builder)
+	}
+
+	return []byte(builder.String())
+}
+
+func (r *Registry) register(name, help, metricType string, labelNames []string, valueFunc func() float64) *family {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, registered := range r.families {
+		if registered.name == name {
+			return registered
+		}
+	}
+
+	registered := &family{
+		series:     map[string]*series{},
+		valueFunc:  valueFunc,
+		name:       name,
+		help:       help,
+		metricType: metricType,
+		labelNames: labelNames,
+	}
+
+	r.families = append(r.families, registered)
+
+	return registered
+}
+
+func (f *family) get(labelValues []string) *series {
+	key := strings.Join(labelValues, labelSeparator)
+
+	f.mutex.RLock()
+	found, ok := f.series[key]
+	f.mutex.RUnlock()
+
+	if ok {
+		return found
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if found, ok := f.series[key]; ok {
+		return found
+	}
+
+	created := &series{labelValues: slices.Clone(labelValues)}
+	f.series[key] = created
+
+	return created
+}
+
+func (f *family) render(builder *strings.Builder) {
+	builder.WriteString("# HELP " + f.name + " " + f.help + "\n")
+	builder.WriteString("# TYPE " + f.name + " " + f.metricType + "\n")
+
+	if f.valueFunc != nil {
+		builder.WriteString(f.name + " " + formatValue(f.valueFunc()) + "\n")
+
+		return
+	}
+
+	f.mutex.RLock()
+	keys := make([]string, 0, len(f.series))
+
+	for key := range f.series {
+		keys = append(keys, key)
+	}
+
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		current := f.series[key]
+
+		builder.WriteString(f.name)
+		f.writeLabels(builder, current.labelValues)
+		builder.WriteString(" " + formatValue(math.Float64frombits(current.bits.Load())) + "\n")
+	}
+	f.mutex.RUnlock()
+}
+
+func (f *family) writeLabels(builder *strings.Builder, labelValues []string) {
+	if len(f.labelNames) == 0 {
+		return
+	}
+
+	builder.WriteByte('{')
+
+	for index, labelName := range f.labelNames {
+		if index > 0 {
+			builder.WriteByte(',')
+		}
+
+		value := ""
+		if index < len(labelValues) {
+			value = labelValues[index]
+		}
+
+		builder.WriteString(labelName + "=" + strconv.Quote(value))
+	}
+
+	builder.WriteByte('}')
+}
+
+func (s *series) add(value float64) {
+	for {
+		current := s.bits.Load()
+		next := math.Float64bits(math.Float64frombits(current) + value)
+
+		if s.bits.CompareAndSwap(current, next) {
+			return
+		}
+	}
+}
+
+func formatValue(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
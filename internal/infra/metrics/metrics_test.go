@@ -0,0 +1,53 @@
+package metrics_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/infra/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRender(t *testing.T) {
+	t.Parallel()
+
+	registry := metrics.New()
+
+	decisions := registry.Counter("routing_decisions_total", "Payments routed.", "strategy", "processor")
+	depth := registry.Gauge("queue_depth", "Queued payments.")
+
+	registry.GaugeFunc("uptime_seconds", "Uptime.", func() float64 { return 1.5 })
+
+	var waitGroup sync.WaitGroup
+
+	for range 100 {
+		waitGroup.Add(1)
+
+		go func() {
+			defer waitGroup.Done()
+
+			decisions.Inc("priority", "default")
+		}()
+	}
+
+	waitGroup.Wait()
+
+	decisions.Inc("priority", "fallback")
+	depth.Set(3)
+	depth.Add(-1)
+
+	// registering the same name again returns the existing metric
+	registry.Counter("routing_decisions_total", "Payments routed.", "strategy", "processor").Inc("priority", "fallback")
+
+	assert.Equal(t, `# HELP routing_decisions_total Payments routed.
+# TYPE routing_decisions_total counter
+routing_decisions_total{strategy="priority",processor="default"} 100
+routing_decisions_total{strategy="priority",processor="fallback"} 2
+# HELP queue_depth Queued payments.
+# TYPE queue_depth gauge
+queue_depth 2
+# HELP uptime_seconds Uptime.
+# TYPE uptime_seconds gauge
+uptime_seconds 1.5
+`, string(registry.Render()))
+}
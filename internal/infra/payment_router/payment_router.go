@@ -0,0 +1,79 @@
+package paymentrouter
+
+import (
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/constants"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/contracts"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/infra/metrics"
+)
+
+// Router ranks the configured processors with its strategy for every payment
+// and counts where each payment was sent first.
+type Router struct {
+	strategy           Strategy
+	decisions          *metrics.Counter
+	latency            *metrics.Gauge
+	failureProbability *metrics.Gauge
+	expectedValue      *metrics.Gauge
+	routes             []*Route
+}
+
+func New(strategy Strategy, registry *metrics.Registry, routes ...*Route) *Router {
+	return &Router{
+		strategy: strategy,
+		decisions: registry.Counter("payment_routing_decisions_total",
+			"Payments routed, by strategy and first processor tried or deferred.", "strategy", "processor"),
+		latency: registry.Gauge("payment_routing_latency_seconds",
+			"Moving average of the processor call latency, net-revenue strategy only.", "processor"),
+		failureProbability: registry.Gauge("payment_routing_failure_probability",
+			"Estimated probability of the next processor call failing, net-revenue strategy only.", "processor"),
+		expectedValue: registry.Gauge("payment_routing_expected_value",
+			"Share of the amount a payment is expected to keep, net-revenue strategy only.", "processor"),
+		routes: routes,
+	}
+}
+
+func (r *Router) Routes() []contracts.PaymentProcessor {
+	ranked := make([]*Route, len(r.routes))
+	copy(ranked, r.routes)
+
+	ranked = r.strategy.Rank(ranked)
+
+	r.record(ranked)
+
+	processors := make([]contracts.PaymentProcessor, 0, len(ranked))
+	for _, route := range ranked {
+		processors = append(processors, route)
+	}
+
+	return processors
+}
+
+func (r *Router) Strategy() Strategy {
+	return r.strategy
+}
+
+func (r *Router) record(ranked []*Route) {
+	decision := constants.RoutingDeferred
+	if len(ranked) > 0 {
+		decision = string(ranked[0].config.Name)
+	}
+
+	r.decisions.Inc(r.strategy.Name(), decision)
+
+	// the estimates only drive the net-revenue ranking, the other strategies
+	// do not pay for them on every payment
+	netRevenue, isNetRevenue := r.strategy.(NetRevenueStrategy)
+	if !isNetRevenue {
+		return
+	}
+
+	waitValue := netRevenue.WaitValue(r.routes)
+
+	for _, route := range r.routes {
+		name := string(route.config.Name)
+
+		r.latency.Set(route.Latency().Seconds(), name)
+		r.failureProbability.Set(route.FailureProbability(), name)
+		r.expectedValue.Set(netRevenue.ExpectedValue(route, waitValue), name)
+	}
+}
@@ -0,0 +1,195 @@
+package paymentrouter_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/constants"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/contracts"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/entities"
+	circuitbreaker "github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/infra/circuit_breaker"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/infra/metrics"
+	paymentrouter "github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/infra/payment_router"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errProcessorDown = errors.New("processor down")
+
+type fakeProcessor struct {
+	err   error
+	delay time.Duration
+}
+
+func (p *fakeProcessor) ProcessPayment(*entities.PaymentRequest) (*entities.PaymentResponse, error) {
+	time.Sleep(p.delay)
+
+	return &entities.PaymentResponse{}, p.err
+}
+
+func (p *fakeProcessor) PaymentsSummary(*entities.PaymentSummaryFilters) (*entities.PaymentSummaryResponse, error) {
+	return &entities.PaymentSummaryResponse{}, nil
+}
+
+func newBreaker() contracts.CircuitBreaker[*entities.PaymentResponse] {
+	return circuitbreaker.New[*entities.PaymentResponse](constants.MaxAttemptsBeforeOpen, constants.RecoveryTimeout)
+}
+
+func newRoutes(processors ...contracts.PaymentProcessor) []*paymentrouter.Route {
+	configs := []entities.ProcessorConfig{
+		{Name: "cheap-slow", Fee: 0.01, Priority: 3, Weight: 0},
+		{Name: entities.Default, Fee: 0.05, Priority: 1, Weight: 1},
+		{Name: entities.Fallback, Fee: 0.15, Priority: 2, Weight: 0},
+	}
+
+	routes := make([]*paymentrouter.Route, 0, len(configs))
+	for index, config := range configs {
+		routes = append(routes, paymentrouter.NewRoute(config, processors[index], newBreaker()))
+	}
+
+	return routes
+}
+
+func names(processors []contracts.PaymentProcessor) []entities.ProcessorProvider {
+	result := make([]entities.ProcessorProvider, 0, len(processors))
+	for _, processor := range processors {
+		route, _ := processor.(*paymentrouter.Route)
+		result = append(result, route.Config().Name)
+	}
+
+	return result
+}
+
+func TestStrategies(t *testing.T) {
+	t.Parallel()
+
+	slow := &fakeProcessor{delay: 20 * time.Millisecond}
+	fast := &fakeProcessor{}
+	failing := &fakeProcessor{err: errProcessorDown}
+
+	routes := newRoutes(slow, fast, failing)
+
+	priority, err := paymentrouter.NewStrategy(constants.PaymentRoutingPriority, paymentrouter.CostModel{})
+	require.NoError(t, err)
+	assert.Equal(t,
+		[]entities.ProcessorProvider{entities.Default, entities.Fallback, "cheap-slow"},
+		names(paymentrouter.New(priority, metrics.New(), routes...).Routes()),
+	)
+
+	lowestFee, err := paymentrouter.NewStrategy(constants.PaymentRoutingLowestFee, paymentrouter.CostModel{})
+	require.NoError(t, err)
+	assert.Equal(t,
+		[]entities.ProcessorProvider{"cheap-slow", entities.Default, entities.Fallback},
+		names(paymentrouter.New(lowestFee, metrics.New(), routes...).Routes()),
+	)
+
+	for _, route := range routes {
+		_, _ = route.ProcessPayment(&entities.PaymentRequest{})
+	}
+
+	assert.Equal(t, int64(1), routes[2].Failures())
+	assert.Equal(t, int64(1), routes[1].Successes())
+
+	lowestLatency, err := paymentrouter.NewStrategy(constants.PaymentRoutingLowestLatency, paymentrouter.CostModel{})
+	require.NoError(t, err)
+	assert.Equal(t,
+		[]entities.ProcessorProvider{entities.Default, "cheap-slow", entities.Fallback},
+		names(paymentrouter.New(lowestLatency, metrics.New(), routes...).Routes()),
+	)
+
+	// only the default route has a weight, so it is always picked first
+	weightedRandom, err := paymentrouter.NewStrategy(constants.PaymentRoutingWeightedRandom, paymentrouter.CostModel{})
+	require.NoError(t, err)
+
+	for range 20 {
+		assert.Equal(t, entities.Default, names(paymentrouter.New(weightedRandom, metrics.New(), routes...).Routes())[0])
+	}
+
+	_, err = paymentrouter.NewStrategy("round-robin", paymentrouter.CostModel{})
+	require.Error(t, err)
+}
+
+func TestWeightedRandomFollowsWeights(t *testing.T) {
+	t.Parallel()
+
+	routes := []*paymentrouter.Route{
+		paymentrouter.NewRoute(entities.ProcessorConfig{Name: "a", Priority: 1, Weight: 3}, &fakeProcessor{}, newBreaker()),
+		paymentrouter.NewRoute(entities.ProcessorConfig{Name: "b", Priority: 2, Weight: 1}, &fakeProcessor{}, newBreaker()),
+	}
+
+	router := paymentrouter.New(paymentrouter.WeightedRandomStrategy{}, metrics.New(), routes...)
+
+	picks := map[entities.ProcessorProvider]int{}
+
+	for range 4000 {
+		ranked := names(router.Routes())
+		require.Len(t, ranked, 2)
+
+		picks[ranked[0]]++
+	}
+
+	assert.InDelta(t, 3000, picks["a"], 200)
+	assert.InDelta(t, 1000, picks["b"], 200)
+}
+
+func TestNetRevenueWeighsFeesAgainstWaiting(t *testing.T) {
+	t.Parallel()
+
+	defaultProcessor := &fakeProcessor{err: errProcessorDown}
+
+	routes := []*paymentrouter.Route{
+		paymentrouter.NewRoute(entities.ProcessorConfig{Name: entities.Fallback, Fee: 0.15, Priority: 2}, &fakeProcessor{}, newBreaker()),
+		paymentrouter.NewRoute(entities.ProcessorConfig{Name: entities.Default, Fee: 0.05, Priority: 1}, defaultProcessor, newBreaker()),
+	}
+
+	registry := metrics.New()
+
+	payFallback, err := paymentrouter.NewStrategy(constants.PaymentRoutingNetRevenue, paymentrouter.CostModel{
+		LatencyCost: constants.DefaultRoutingLatencyCost,
+		WaitCost:    constants.DefaultRoutingWaitCost,
+	})
+	require.NoError(t, err)
+
+	router := paymentrouter.New(payFallback, registry, routes...)
+
+	// nothing failed yet, the cheaper fee wins
+	assert.Equal(t, []entities.ProcessorProvider{entities.Default, entities.Fallback}, names(router.Routes()))
+
+	for range 10 {
+		_, _ = routes[1].ProcessPayment(&entities.PaymentRequest{})
+	}
+
+	// the default is expected to fail, paying the fallback fee beats waiting
+	assert.Equal(t, []entities.ProcessorProvider{entities.Fallback}, names(router.Routes()))
+
+	// waiting costs less than the fee difference, so the payment is deferred
+	wait, err := paymentrouter.NewStrategy(constants.PaymentRoutingNetRevenue, paymentrouter.CostModel{
+		LatencyCost: constants.DefaultRoutingLatencyCost,
+		WaitCost:    0.05,
+	})
+	require.NoError(t, err)
+	assert.Empty(t, paymentrouter.New(wait, registry, routes...).Routes())
+
+	rendered := string(registry.Render())
+	assert.Contains(t, rendered, `payment_routing_decisions_total{strategy="net-revenue",processor="default"} 1`)
+	assert.Contains(t, rendered, `payment_routing_decisions_total{strategy="net-revenue",processor="fallback"} 1`)
+	assert.Contains(t, rendered, `payment_routing_decisions_total{strategy="net-revenue",processor="deferred"} 1`)
+}
+
+func TestOnlyNetRevenueRecordsEstimates(t *testing.T) {
+	t.Parallel()
+
+	registry := metrics.New()
+	routes := newRoutes(&fakeProcessor{}, &fakeProcessor{}, &fakeProcessor{})
+
+	priority, err := paymentrouter.NewStrategy(constants.PaymentRoutingPriority, paymentrouter.CostModel{})
+	require.NoError(t, err)
+
+	paymentrouter.New(priority, registry, routes...).Routes()
+
+	rendered := string(registry.Render())
+	assert.Contains(t, rendered, `payment_routing_decisions_total{strategy="priority",processor="default"} 1`)
+	assert.NotContains(t, rendered, `payment_routing_latency_seconds{`)
+	assert.NotContains(t, rendered, `payment_routing_failure_probability{`)
+}
@@ -0,0 +1,149 @@
+package paymentrouter
+
+import (
+	"errors"
+	"math"
+	"sync/atomic"
+	"time"
+
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/constants"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/contracts"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/entities"
+)
+
+const (
+	percent = 100
+	million = 1_000_000
+)
+
+// Route is a configured processor that keeps track of how its calls went, so
+// strategies can rank it by what it actually does. Calls go through the route
+// own breaker; while it is open they fail right away with
+// constants.ErrCircuitBreakerOpen and are not counted.
+type Route struct {
+	processor   contracts.PaymentProcessor
+	breaker     contracts.CircuitBreaker[*entities.PaymentResponse]
+	config      entities.ProcessorConfig
+	latency     atomic.Int64
+	successes   atomic.Int64
+	failures    atomic.Int64
+	failureRate atomic.Int64
+	lastFailure atomic.Int64
+}
+
+func NewRoute(
+	config entities.ProcessorConfig,
+	processor contracts.PaymentProcessor,
+	breaker contracts.CircuitBreaker[*entities.PaymentResponse],
+) *Route {
+	return &Route{
+		processor: processor,
+		breaker:   breaker,
+		config:    config,
+	}
+}
+
+func (r *Route) ProcessPayment(paymentRequest *entities.PaymentRequest) (*entities.PaymentResponse, error) {
+	var (
+		response *entities.PaymentResponse
+		err      = constants.ErrCircuitBreakerOpen
+	)
+
+	// the breaker hands failures to the fallback, which reports the call
+	// outcome, or the rejection when there was no call
+	return r.breaker.Execute(
+		func() (*entities.PaymentResponse, error) {
+			response, err = r.call(paymentRequest)
+
+			return response, err
+		},
+		func() (*entities.PaymentResponse, error) {
+			return response, err
+		},
+	)
+}
+
+func (r *Route) call(paymentRequest *entities.PaymentRequest) (*entities.PaymentResponse, error) {
+	startedAt := time.Now()
+
+	response, err := r.processor.ProcessPayment(paymentRequest)
+	if errors.Is(err, constants.ErrPaymentHedged) {
+		// another processor owns the payment, the call says nothing of its latency
+		return response, err
+	}
+
+	if err != nil {
+		r.failures.Add(1)
+		r.lastFailure.Store(time.Now().UnixNano())
+		r.observe(constants.RoutingFailureLatency, million)
+
+		return response, err
+	}
+
+	r.successes.Add(1)
+	r.observe(time.Since(startedAt), 0)
+
+	return response, nil
+}
+
+func (r *Route) PaymentsSummary(filters *entities.PaymentSummaryFilters) (*entities.PaymentSummaryResponse, error) {
+	return r.processor.PaymentsSummary(filters)
+}
+
+func (r *Route) Config() entities.ProcessorConfig {
+	return r.config
+}
+
+func (r *Route) CircuitBreaker() contracts.CircuitBreaker[*entities.PaymentResponse] {
+	return r.breaker
+}
+
+// Latency is a moving average of the recent calls, zero until the first one.
+func (r *Route) Latency() time.Duration {
+	return time.Duration(r.latency.Load())
+}
+
+func (r *Route) Successes() int64 {
+	return r.successes.Load()
+}
+
+func (r *Route) Failures() int64 {
+	return r.failures.Load()
+}
+
+// FailureProbability is a moving average of the recent outcomes that halves
+// every constants.RoutingRecoveryHalfLife without a new failure, so a processor
+// that went down gets tried again once it had time to recover.
+func (r *Route) FailureProbability() float64 {
+	lastFailure := r.lastFailure.Load()
+	if lastFailure == 0 {
+		return 0
+	}
+
+	elapsed := time.Since(time.Unix(0, lastFailure))
+	decay := math.Exp2(-float64(elapsed) / float64(constants.RoutingRecoveryHalfLife))
+
+	return float64(r.failureRate.Load()) / million * decay
+}
+
+// observe folds a call into the moving averages; failure is in millionths.
+func (r *Route) observe(sample time.Duration, failure int64) {
+	// the first latency sample seeds the average instead of pulling it from zero
+	smooth(&r.latency, int64(sample), true)
+	smooth(&r.failureRate, failure, false)
+}
+
+func smooth(average *atomic.Int64, sample int64, seed bool) {
+	for {
+		current := average.Load()
+
+		next := current + (sample-current)*constants.RoutingSmoothing/percent
+		if seed && current == 0 {
+			next = sample
+		}
+
+		if average.CompareAndSwap(current, next) {
+			return
+		}
+	}
+}
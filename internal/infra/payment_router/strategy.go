@@ -0,0 +1,193 @@
+package paymentrouter
+
+import (
+	"cmp"
+	"errors"
+	"math/rand/v2"
+	"slices"
+
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/constants"
+)
+
+var errUnknownStrategy = errors.New("unknown routing strategy")
+
+// Strategy orders the routes to try for a payment, best first. Rank gets its
+// own copy of the routes, may reorder it freely and may leave routes out; an
+// empty ranking means the payment is better off waiting.
+type Strategy interface {
+	Name() string
+	Rank(routes []*Route) []*Route
+}
+
+func NewStrategy(name string, costModel CostModel) (Strategy, error) {
+	switch name {
+	case "", constants.PaymentRoutingPriority:
+		return PriorityStrategy{}, nil
+	case constants.PaymentRoutingLowestFee:
+		return LowestFeeStrategy{}, nil
+	case constants.PaymentRoutingLowestLatency:
+		return LowestLatencyStrategy{}, nil
+	case constants.PaymentRoutingWeightedRandom:
+		return WeightedRandomStrategy{}, nil
+	case constants.PaymentRoutingNetRevenue:
+		return NetRevenueStrategy{costModel: costModel}, nil
+	default:
+		return nil, constants.NewErrorWrapper(errUnknownStrategy, name)
+	}
+}
+
+// PriorityStrategy tries the lowest priority number first.
+type PriorityStrategy struct{}
+
+func (PriorityStrategy) Name() string {
+	return constants.PaymentRoutingPriority
+}
+
+func (PriorityStrategy) Rank(routes []*Route) []*Route {
+	slices.SortStableFunc(routes, byPriority)
+
+	return routes
+}
+
+// LowestFeeStrategy tries the cheapest processor first, priority breaking ties.
+type LowestFeeStrategy struct{}
+
+func (LowestFeeStrategy) Name() string {
+	return constants.PaymentRoutingLowestFee
+}
+
+func (LowestFeeStrategy) Rank(routes []*Route) []*Route {
+	slices.SortStableFunc(routes, func(a, b *Route) int {
+		if order := cmp.Compare(a.config.Fee, b.config.Fee); order != 0 {
+			return order
+		}
+
+		return byPriority(a, b)
+	})
+
+	return routes
+}
+
+// LowestLatencyStrategy tries the fastest processor first. Routes never called
+// yet rank first, so every processor gets measured.
+type LowestLatencyStrategy struct{}
+
+func (LowestLatencyStrategy) Name() string {
+	return constants.PaymentRoutingLowestLatency
+}
+
+func (LowestLatencyStrategy) Rank(routes []*Route) []*Route {
+	slices.SortStableFunc(routes, func(a, b *Route) int {
+		if order := cmp.Compare(a.Latency(), b.Latency()); order != 0 {
+			return order
+		}
+
+		return byPriority(a, b)
+	})
+
+	return routes
+}
+
+// WeightedRandomStrategy picks the first route at random in proportion to its
+// weight, the others follow by priority.
+type WeightedRandomStrategy struct{}
+
+func (WeightedRandomStrategy) Name() string {
+	return constants.PaymentRoutingWeightedRandom
+}
+
+func (WeightedRandomStrategy) Rank(routes []*Route) []*Route {
+	slices.SortStableFunc(routes, byPriority)
+
+	total := 0
+	for _, route := range routes {
+		total += max(route.config.Weight, 0)
+	}
+
+	if total == 0 {
+		return routes
+	}
+
+	//nolint:gosec // routing does not need a secure random source
+	pick := rand.IntN(total)
+
+	for index, route := range routes {
+		pick -= max(route.config.Weight, 0)
+		if pick < 0 {
+			copy(routes[1:index+1], routes[:index])
+			routes[0] = route
+
+			return routes
+		}
+	}
+
+	return routes
+}
+
+// CostModel prices what a payment loses besides the processor fee, as a share
+// of its amount.
+type CostModel struct {
+	// LatencyCost is lost per second the processor takes to answer.
+	LatencyCost float64
+	// WaitCost is lost by putting the payment off until the cheapest processor
+	// recovers instead of sending it anywhere now.
+	WaitCost float64
+}
+
+// NetRevenueStrategy ranks the routes by the share of the amount they are
+// expected to keep and leaves out those worth less than waiting for the
+// cheapest processor, so a payment can be deferred rather than paying a higher
+// fee.
+type NetRevenueStrategy struct {
+	costModel CostModel
+}
+
+func (NetRevenueStrategy) Name() string {
+	return constants.PaymentRoutingNetRevenue
+}
+
+func (s NetRevenueStrategy) Rank(routes []*Route) []*Route {
+	waitValue := s.WaitValue(routes)
+
+	values := make(map[*Route]float64, len(routes))
+	for _, route := range routes {
+		values[route] = s.ExpectedValue(route, waitValue)
+	}
+
+	slices.SortStableFunc(routes, func(a, b *Route) int {
+		if order := cmp.Compare(values[b], values[a]); order != 0 {
+			return order
+		}
+
+		return byPriority(a, b)
+	})
+
+	return slices.DeleteFunc(routes, func(route *Route) bool {
+		return values[route] < waitValue
+	})
+}
+
+// WaitValue is what a payment keeps when it waits for the cheapest route.
+func (s NetRevenueStrategy) WaitValue(routes []*Route) float64 {
+	lowestFee := 1.0
+	for _, route := range routes {
+		lowestFee = min(lowestFee, route.config.Fee)
+	}
+
+	return 1 - lowestFee - s.costModel.WaitCost
+}
+
+// ExpectedValue is what a payment keeps when sent to the route now: the amount
+// less the fee when the call succeeds, the wait value when it fails and has to
+// be retried later, less what the time it takes costs either way.
+func (s NetRevenueStrategy) ExpectedValue(route *Route, waitValue float64) float64 {
+	failure := route.FailureProbability()
+
+	return (1-failure)*(1-route.config.Fee) +
+		failure*waitValue -
+		s.costModel.LatencyCost*route.Latency().Seconds()
+}
+
+func byPriority(a, b *Route) int {
+	return a.config.Priority - b.config.Priority
+}
@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"log"
+
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/contracts"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/entities"
+)
+
+// ReadFallback writes to the primary backend and keeps the fallback one up to
+// date on a best effort basis, reading from the fallback whenever the primary
+// cannot answer a summary.
+type ReadFallback struct {
+	primary  contracts.Storage
+	fallback contracts.Storage
+}
+
+func NewReadFallback(primary, fallback contracts.Storage) *ReadFallback {
+	return &ReadFallback{
+		primary:  primary,
+		fallback: fallback,
+	}
+}
+
+func (r *ReadFallback) Save(payload *entities.PaymentPayloadStorage) error {
+	if err := r.primary.Save(payload); err != nil {
+		return err
+	}
+
+	if err := r.fallback.Save(payload); err != nil {
+		go log.Print(
+			map[string]interface{}{
+				"correlation_id": payload.ID,
+				"action":         "error saving to fallback storage",
+				"error":          err,
+			},
+		)
+	}
+
+	return nil
+}
+
+func (r *ReadFallback) Retrieve(filters *entities.PaymentSummaryFilters) (*entities.PaymentResultStorage, error) {
+	result, err := r.primary.Retrieve(filters)
+	if err == nil {
+		return result, nil
+	}
+
+	go log.Print(
+		map[string]interface{}{
+			"action": "reading summary from fallback storage",
+			"error":  err,
+		},
+	)
+
+	return r.fallback.Retrieve(filters)
+}
+
+func (r *ReadFallback) Claim(correlationID string) (bool, error) {
+	return r.primary.Claim(correlationID)
+}
+
+func (r *ReadFallback) Release(correlationID string) error {
+	return r.primary.Release(correlationID)
+}
+
+func (r *ReadFallback) Ping() error {
+	return r.primary.Ping()
+}
@@ -0,0 +1,116 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/constants"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/contracts"
+)
+
+var (
+	errUnknownBackend    = errors.New("unknown storage backend")
+	errUnknownMode       = errors.New("unknown storage mode")
+	errMissingSecondary  = errors.New("storage mode needs a secondary backend")
+	errSameBackends      = errors.New("primary and secondary storage backends are the same")
+	errDuplicateRegister = errors.New("storage backend already registered")
+)
+
+type Factory func() contracts.Storage
+
+// Registry maps backend names to their constructors. Constructors only run when
+// Build picks them, since most of them connect and log.Fatal when they cannot.
+type Registry struct {
+	factories map[string]Factory
+	mutex     sync.RWMutex
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		factories: map[string]Factory{},
+	}
+}
+
+func (r *Registry) Register(name string, factory Factory) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, ok := r.factories[name]; ok {
+		return constants.NewErrorWrapper(errDuplicateRegister, name)
+	}
+
+	r.factories[name] = factory
+
+	return nil
+}
+
+func (r *Registry) Backends() []string {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	names := make([]string, 0, len(r.factories))
+	for name := range r.factories {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// Build returns the primary backend alone or composed with the secondary one
+// according to mode, see the constants.StorageMode* values.
+func (r *Registry) Build(mode, primaryName, secondaryName string) (contracts.Storage, error) {
+	if mode == "" {
+		mode = constants.StorageModeSingle
+	}
+
+	switch mode {
+	case constants.StorageModeSingle:
+		return r.create(primaryName)
+	case constants.StorageModeWriteThrough, constants.StorageModeReadFallback, constants.StorageModeShadow:
+	default:
+		return nil, constants.NewErrorWrapper(errUnknownMode, mode)
+	}
+
+	if secondaryName == "" {
+		return nil, constants.NewErrorWrapper(errMissingSecondary, mode)
+	}
+
+	if secondaryName == primaryName {
+		return nil, constants.NewErrorWrapper(errSameBackends, primaryName)
+	}
+
+	primary, err := r.create(primaryName)
+	if err != nil {
+		return nil, err
+	}
+
+	secondary, err := r.create(secondaryName)
+	if err != nil {
+		return nil, err
+	}
+
+	switch mode {
+	case constants.StorageModeWriteThrough:
+		return NewWriteThrough(primary, secondary), nil
+	case constants.StorageModeReadFallback:
+		return NewReadFallback(primary, secondary), nil
+	default:
+		return NewShadow(primary, secondary), nil
+	}
+}
+
+func (r *Registry) create(name string) (contracts.Storage, error) {
+	r.mutex.RLock()
+	factory, ok := r.factories[name]
+	r.mutex.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("%w: %s, expected one of %v", errUnknownBackend, name, r.Backends())
+	}
+
+	return factory(), nil
+}
@@ -0,0 +1,133 @@
+package storage
+
+import (
+	"log"
+	"sync/atomic"
+
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/constants"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/contracts"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/entities"
+)
+
+// Shadow serves everything from the primary backend and mirrors saves to the
+// shadow one in the background, so a new backend can be filled up and compared
+// before switching to it. Summaries that differ between both are logged.
+// A single goroutine mirrors the saves through a bounded queue, dropping them
+// when it is full, and only one summary comparison runs at a time. A mismatch
+// is logged with the saves dropped so far, which it may come down to.
+type Shadow struct {
+	primary   contracts.Storage
+	shadow    contracts.Storage
+	mirrors   chan *entities.PaymentPayloadStorage
+	dropped   atomic.Int64
+	comparing atomic.Bool
+}
+
+func NewShadow(primary, shadow contracts.Storage) *Shadow {
+	s := &Shadow{
+		primary: primary,
+		shadow:  shadow,
+		mirrors: make(chan *entities.PaymentPayloadStorage, constants.ShadowSaveQueueSize),
+	}
+
+	go s.mirror()
+
+	return s
+}
+
+func (s *Shadow) Save(payload *entities.PaymentPayloadStorage) error {
+	if err := s.primary.Save(payload); err != nil {
+		return err
+	}
+
+	mirrored := *payload
+
+	select {
+	case s.mirrors <- &mirrored:
+	default:
+		s.dropped.Add(1)
+
+		log.Print(
+			map[string]interface{}{
+				"correlation_id": mirrored.ID,
+				"action":         "shadow storage queue full, save not mirrored",
+			},
+		)
+	}
+
+	return nil
+}
+
+// Dropped counts the saves never mirrored because the queue was full.
+func (s *Shadow) Dropped() int64 {
+	return s.dropped.Load()
+}
+
+func (s *Shadow) mirror() {
+	for payload := range s.mirrors {
+		if err := s.shadow.Save(payload); err != nil {
+			log.Print(
+				map[string]interface{}{
+					"correlation_id": payload.ID,
+					"action":         "error saving to shadow storage",
+					"error":          err,
+				},
+			)
+		}
+	}
+}
+
+func (s *Shadow) Retrieve(filters *entities.PaymentSummaryFilters) (*entities.PaymentResultStorage, error) {
+	result, err := s.primary.Retrieve(filters)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.comparing.CompareAndSwap(false, true) {
+		go s.compare(filters, result)
+	}
+
+	return result, nil
+}
+
+func (s *Shadow) Claim(correlationID string) (bool, error) {
+	return s.primary.Claim(correlationID)
+}
+
+func (s *Shadow) Release(correlationID string) error {
+	return s.primary.Release(correlationID)
+}
+
+// Ping ignores the shadow backend, nothing waits on it.
+func (s *Shadow) Ping() error {
+	return s.primary.Ping()
+}
+
+func (s *Shadow) compare(filters *entities.PaymentSummaryFilters, expected *entities.PaymentResultStorage) {
+	defer s.comparing.Store(false)
+
+	shadowResult, err := s.shadow.Retrieve(filters)
+	if err != nil {
+		log.Print(
+			map[string]interface{}{
+				"action": "error reading summary from shadow storage",
+				"error":  err,
+			},
+		)
+
+		return
+	}
+
+	if shadowResult.Equal(&expected.PaymentSummaryResponse) {
+		return
+	}
+
+	log.Print(
+		map[string]interface{}{
+			"action":  "shadow storage summary mismatch",
+			"primary": expected.PaymentSummaryResponse,
+			"shadow":  shadowResult.PaymentSummaryResponse,
+			"dropped": s.dropped.Load(),
+		},
+	)
+}
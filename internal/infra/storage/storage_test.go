@@ -0,0 +1,208 @@
+package storage_test
+
+import (
+	"errors"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/constants"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/contracts"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/entities"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/infra/clients/memory"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/infra/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errUnavailable = errors.New("unavailable")
+
+// unavailableStorage fails every call, like a backend that lost its connection.
+type unavailableStorage struct{}
+
+func (unavailableStorage) Save(*entities.PaymentPayloadStorage) error { return errUnavailable }
+
+func (unavailableStorage) Retrieve(*entities.PaymentSummaryFilters) (*entities.PaymentResultStorage, error) {
+	return nil, errUnavailable
+}
+
+func (unavailableStorage) Claim(string) (bool, error) { return false, errUnavailable }
+
+func (unavailableStorage) Release(string) error { return errUnavailable }
+
+func (unavailableStorage) Ping() error { return errUnavailable }
+
+// flakyStorage fails the first failures saves, then saves to the wrapped storage.
+type flakyStorage struct {
+	contracts.Storage
+	failures atomic.Int32
+}
+
+func (f *flakyStorage) Save(payload *entities.PaymentPayloadStorage) error {
+	if f.failures.Add(-1) >= 0 {
+		return errUnavailable
+	}
+
+	return f.Storage.Save(payload)
+}
+
+// stuckStorage never finishes a save, like a backend that stopped answering.
+type stuckStorage struct {
+	unavailableStorage
+}
+
+func (stuckStorage) Save(*entities.PaymentPayloadStorage) error {
+	select {}
+}
+
+func payment(id string) *entities.PaymentPayloadStorage {
+	return &entities.PaymentPayloadStorage{
+		ID:                id,
+		ProcessorProvider: entities.Default,
+		RequestedAt:       time.Now().UTC().Format(constants.DefaultTimeFormat),
+		Amount:            entities.NewMoneyFromCents(1990),
+	}
+}
+
+func newRegistry(t *testing.T, backends map[string]contracts.Storage) *storage.Registry {
+	t.Helper()
+
+	registry := storage.NewRegistry()
+
+	for name, backend := range backends {
+		require.NoError(t, registry.Register(name, func() contracts.Storage { return backend }))
+	}
+
+	return registry
+}
+
+func TestBuildValidatesConfiguration(t *testing.T) {
+	t.Parallel()
+
+	registry := newRegistry(t, map[string]contracts.Storage{
+		constants.StorageBackendMemory: memory.New(),
+		constants.StorageBackendRedis:  memory.New(),
+	})
+
+	single, err := registry.Build("", constants.StorageBackendMemory, "")
+	require.NoError(t, err)
+	assert.IsType(t, &memory.Client{}, single)
+
+	_, err = registry.Build(constants.StorageModeSingle, constants.StorageBackendPostgres, "")
+	require.Error(t, err)
+
+	_, err = registry.Build("mirror", constants.StorageBackendMemory, constants.StorageBackendRedis)
+	require.Error(t, err)
+
+	_, err = registry.Build(constants.StorageModeShadow, constants.StorageBackendMemory, "")
+	require.Error(t, err)
+
+	_, err = registry.Build(constants.StorageModeShadow, constants.StorageBackendMemory, constants.StorageBackendMemory)
+	require.Error(t, err)
+
+	require.Error(t, registry.Register(constants.StorageBackendMemory, func() contracts.Storage { return memory.New() }))
+
+	assert.Equal(t, []string{constants.StorageBackendMemory, constants.StorageBackendRedis}, registry.Backends())
+}
+
+func TestWriteThroughSavesToBoth(t *testing.T) {
+	t.Parallel()
+
+	primary, secondary := memory.New(), memory.New()
+
+	writeThrough := storage.NewWriteThrough(primary, secondary)
+	require.NoError(t, writeThrough.Save(payment("a")))
+
+	for _, backend := range []contracts.Storage{primary, secondary} {
+		result, err := backend.Retrieve(nil)
+		require.NoError(t, err)
+		assert.Equal(t, 1, result.Default.TotalRequests)
+	}
+
+	require.ErrorIs(t, storage.NewWriteThrough(primary, unavailableStorage{}).Save(payment("b")), errUnavailable)
+}
+
+func TestWriteThroughRetriesTheSecondarySave(t *testing.T) {
+	t.Parallel()
+
+	primary, secondary := memory.New(), &flakyStorage{Storage: memory.New()}
+	secondary.failures.Store(constants.SecondarySaveMaxAttempts - 1)
+
+	require.NoError(t, storage.NewWriteThrough(primary, secondary).Save(payment("a")))
+
+	result, err := secondary.Retrieve(nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Default.TotalRequests)
+}
+
+func TestReadFallbackReadsSecondaryWhenPrimaryFails(t *testing.T) {
+	t.Parallel()
+
+	fallback := memory.New()
+	require.NoError(t, fallback.Save(payment("a")))
+
+	readFallback := storage.NewReadFallback(unavailableStorage{}, fallback)
+
+	result, err := readFallback.Retrieve(nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Default.TotalRequests)
+
+	require.ErrorIs(t, readFallback.Save(payment("b")), errUnavailable)
+
+	// a fallback failure never fails the save
+	require.NoError(t, storage.NewReadFallback(memory.New(), unavailableStorage{}).Save(payment("c")))
+}
+
+func TestShadowNeverAffectsResults(t *testing.T) {
+	t.Parallel()
+
+	primary, shadowed := memory.New(), memory.New()
+
+	shadow := storage.NewShadow(primary, shadowed)
+	require.NoError(t, shadow.Save(payment("a")))
+
+	assert.Eventually(t, func() bool {
+		result, err := shadowed.Retrieve(nil)
+
+		return err == nil && result.Default.TotalRequests == 1
+	}, time.Second, 10*time.Millisecond)
+
+	broken := storage.NewShadow(primary, unavailableStorage{})
+	require.NoError(t, broken.Save(payment("b")))
+
+	result, err := broken.Retrieve(nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Default.TotalRequests)
+}
+
+func TestShadowDropsMirroredSavesWhenTheQueueIsFull(t *testing.T) {
+	t.Parallel()
+
+	primary := memory.New()
+	shadow := storage.NewShadow(primary, stuckStorage{})
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		for i := range constants.ShadowSaveQueueSize + 10 {
+			assert.NoError(t, shadow.Save(payment(strconv.Itoa(i))))
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("a stuck shadow backend blocked the saves")
+	}
+
+	result, err := primary.Retrieve(nil)
+	require.NoError(t, err)
+	assert.Equal(t, constants.ShadowSaveQueueSize+10, result.Default.TotalRequests)
+
+	// the mirroring goroutine may have taken one save out of the queue
+	assert.GreaterOrEqual(t, shadow.Dropped(), int64(9))
+	assert.LessOrEqual(t, shadow.Dropped(), int64(10))
+}
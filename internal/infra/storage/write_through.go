@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/constants"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/helpers"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/contracts"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/entities"
+)
+
+// secondarySavePolicy retries a secondary save that failed once the primary
+// already had the payment; every backend ignores a correlationId it already
+// saved, so saving it again is safe.
+var secondarySavePolicy = &helpers.RetryPolicy{
+	Jitter:      constants.RetryJitterFull,
+	BaseDelay:   constants.SecondarySaveBaseDelay,
+	MaxDelay:    constants.SecondarySaveMaxDelay,
+	MaxAttempts: constants.SecondarySaveMaxAttempts,
+}
+
+// WriteThrough saves every payment to both backends and answers from the
+// primary one. A save only succeeds once both have it, the secondary save
+// being retried a few times before the save fails.
+type WriteThrough struct {
+	primary   contracts.Storage
+	secondary contracts.Storage
+}
+
+func NewWriteThrough(primary, secondary contracts.Storage) *WriteThrough {
+	return &WriteThrough{
+		primary:   primary,
+		secondary: secondary,
+	}
+}
+
+func (w *WriteThrough) Save(payload *entities.PaymentPayloadStorage) error {
+	if err := w.primary.Save(payload); err != nil {
+		return fmt.Errorf("error saving to primary storage: %w", err)
+	}
+
+	if _, err := helpers.Retry(context.Background(), secondarySavePolicy, func() (struct{}, error) {
+		return struct{}{}, w.secondary.Save(payload)
+	}); err != nil {
+		return fmt.Errorf("error saving to secondary storage: %w", err)
+	}
+
+	return nil
+}
+
+func (w *WriteThrough) Retrieve(filters *entities.PaymentSummaryFilters) (*entities.PaymentResultStorage, error) {
+	return w.primary.Retrieve(filters)
+}
+
+// Claim only uses the primary backend, claims must have a single source of truth.
+func (w *WriteThrough) Claim(correlationID string) (bool, error) {
+	return w.primary.Claim(correlationID)
+}
+
+func (w *WriteThrough) Release(correlationID string) error {
+	return w.primary.Release(correlationID)
+}
+
+// Ping needs both backends, a save fails as soon as either does.
+func (w *WriteThrough) Ping() error {
+	if err := w.primary.Ping(); err != nil {
+		return fmt.Errorf("error pinging primary storage: %w", err)
+	}
+
+	if err := w.secondary.Ping(); err != nil {
+		return fmt.Errorf("error pinging secondary storage: %w", err)
+	}
+
+	return nil
+}
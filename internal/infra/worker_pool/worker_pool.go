@@ -4,26 +4,55 @@ import (
 	"log"
 	"runtime"
 	"sync"
+	"time"
+
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/constants"
 )
 
+// WorkerPool runs tasks on maxWorkers workers, holding at most queueSize of
+// them while the workers are busy. What happens past that is up
+// to the overflow policy: constants.WorkerPoolOverflowBlock makes Submit wait
+// up to blockTimeout for room, any other policy fails right away.
+//
+// At most Parallelism tasks run at once, a worker holding the task it took
+// until another one ends when the pool is already that busy. Raising it past
+// the workers there are starts the missing ones, they are never stopped.
+//
+// Once Shutdown is called the pool takes no more tasks, the workers finish the
+// queued ones and exit.
 type WorkerPool struct {
-	workers  int
-	taskChan chan func()
-	wg       sync.WaitGroup
+	taskChan     chan func()
+	slots        *sync.Cond
+	overflow     string
+	wg           sync.WaitGroup
+	closing      sync.RWMutex
+	blockTimeout time.Duration
+	workers      int
+	parallelism  int
+	running      int
+	mutex        sync.Mutex
+	closed       bool
 }
 
-func New(maxWorkers int) *WorkerPool {
+func New(maxWorkers, queueSize int, overflow string, blockTimeout time.Duration) *WorkerPool {
 	if maxWorkers <= 0 {
 		maxWorkers = runtime.NumCPU()
 	}
 
-	multiple := 2
+	if queueSize <= 0 {
+		queueSize = maxWorkers * constants.DefaultWorkerPoolQueueFactor
+	}
 
 	pool := &WorkerPool{
-		workers:  maxWorkers,
-		taskChan: make(chan func(), maxWorkers*multiple),
+		workers:      maxWorkers,
+		parallelism:  maxWorkers,
+		taskChan:     make(chan func(), queueSize),
+		overflow:     overflow,
+		blockTimeout: blockTimeout,
 	}
 
+	pool.slots = sync.NewCond(&pool.mutex)
+
 	for range maxWorkers {
 		go pool.worker()
 	}
@@ -33,6 +62,8 @@ func New(maxWorkers int) *WorkerPool {
 
 func (p *WorkerPool) worker() {
 	for task := range p.taskChan {
+		p.acquire()
+
 		func() {
 			defer func() {
 				if r := recover(); r != nil {
@@ -43,6 +74,7 @@ func (p *WorkerPool) worker() {
 						},
 					)
 				}
+				p.release()
 				p.wg.Done()
 			}()
 			task()
@@ -50,15 +82,148 @@ func (p *WorkerPool) worker() {
 	}
 }
 
-func (p *WorkerPool) Submit(task func()) {
+func (p *WorkerPool) acquire() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	for p.running >= p.parallelism {
+		p.slots.Wait()
+	}
+
+	p.running++
+}
+
+func (p *WorkerPool) release() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.running--
+	p.slots.Signal()
+}
+
+// SetParallelism changes how many tasks run at once, at least one, starting
+// workers as needed.
+func (p *WorkerPool) SetParallelism(parallelism int) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.parallelism = max(parallelism, 1)
+
+	// after Shutdown they find the task channel closed and exit right away
+	for ; p.workers < p.parallelism; p.workers++ {
+		go p.worker()
+	}
+
+	p.slots.Broadcast()
+}
+
+func (p *WorkerPool) Parallelism() int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.parallelism
+}
+
+// Submit queues the task following the overflow policy, returning
+// constants.ErrWorkerPoolSaturated when it could not and
+// constants.ErrWorkerPoolClosed after Shutdown.
+func (p *WorkerPool) Submit(task func()) error {
+	if p.overflow != constants.WorkerPoolOverflowBlock {
+		return p.TrySubmit(task)
+	}
+
+	if task == nil {
+		return nil
+	}
+
+	// held while sending, the task channel cannot be closed under us
+	p.closing.RLock()
+	defer p.closing.RUnlock()
+
+	if p.closed {
+		return constants.ErrWorkerPoolClosed
+	}
+
+	p.wg.Add(1)
+
+	timer := time.NewTimer(p.blockTimeout)
+	defer timer.Stop()
+
+	select {
+	case p.taskChan <- task:
+		return nil
+	case <-timer.C:
+		p.wg.Done()
+
+		return constants.ErrWorkerPoolSaturated
+	}
+}
+
+// TrySubmit queues the task only if there is room right now.
+func (p *WorkerPool) TrySubmit(task func()) error {
 	if task == nil {
-		return
+		return nil
+	}
+
+	p.closing.RLock()
+	defer p.closing.RUnlock()
+
+	if p.closed {
+		return constants.ErrWorkerPoolClosed
 	}
 
 	p.wg.Add(1)
-	p.taskChan <- task
+
+	select {
+	case p.taskChan <- task:
+		return nil
+	default:
+		p.wg.Done()
+
+		return constants.ErrWorkerPoolSaturated
+	}
 }
 
 func (p *WorkerPool) Wait() {
 	p.wg.Wait()
 }
+
+// Shutdown stops taking tasks and waits for the submitted ones until the
+// deadline, telling whether they all ended.
+func (p *WorkerPool) Shutdown(deadline time.Time) bool {
+	p.closing.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.taskChan)
+	}
+	p.closing.Unlock()
+
+	done := make(chan struct{})
+
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+
+	select {
+	case <-done:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+func (p *WorkerPool) QueueDepth() int {
+	return len(p.taskChan)
+}
+
+func (p *WorkerPool) Capacity() int {
+	return cap(p.taskChan)
+}
+
+func (p *WorkerPool) Overflow() string {
+	return p.overflow
+}
@@ -0,0 +1,141 @@
+package workerpool_test
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/constants"
+	workerpool "github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/infra/worker_pool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fill keeps the only worker busy and the queue full until release is closed.
+func fill(t *testing.T, pool *workerpool.WorkerPool) chan struct{} {
+	t.Helper()
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+
+	require.NoError(t, pool.TrySubmit(func() {
+		close(started)
+		<-release
+	}))
+
+	<-started
+
+	for range pool.Capacity() {
+		require.NoError(t, pool.TrySubmit(func() { <-release }))
+	}
+
+	return release
+}
+
+func TestTrySubmitFailsOnceTheQueueIsFull(t *testing.T) {
+	t.Parallel()
+
+	pool := workerpool.New(1, 2, constants.WorkerPoolOverflowReject, 0)
+	release := fill(t, pool)
+
+	assert.Equal(t, 2, pool.QueueDepth())
+	require.ErrorIs(t, pool.TrySubmit(func() {}), constants.ErrWorkerPoolSaturated)
+	require.ErrorIs(t, pool.Submit(func() {}), constants.ErrWorkerPoolSaturated)
+
+	close(release)
+	pool.Wait()
+
+	assert.Equal(t, 0, pool.QueueDepth())
+}
+
+func TestBlockingSubmitWaitsForRoomUntilTheDeadline(t *testing.T) {
+	t.Parallel()
+
+	pool := workerpool.New(1, 1, constants.WorkerPoolOverflowBlock, 20*time.Millisecond)
+	release := fill(t, pool)
+
+	startedAt := time.Now()
+
+	require.ErrorIs(t, pool.Submit(func() {}), constants.ErrWorkerPoolSaturated)
+	assert.GreaterOrEqual(t, time.Since(startedAt), 20*time.Millisecond)
+
+	var ran atomic.Bool
+
+	time.AfterFunc(5*time.Millisecond, func() { close(release) })
+
+	require.NoError(t, pool.Submit(func() { ran.Store(true) }))
+
+	pool.Wait()
+
+	assert.True(t, ran.Load())
+}
+
+func TestParallelismBoundsTheTasksRunningAtOnce(t *testing.T) {
+	t.Parallel()
+
+	pool := workerpool.New(4, 16, constants.WorkerPoolOverflowReject, 0)
+	pool.SetParallelism(2)
+
+	var running, peak atomic.Int32
+
+	for range 12 {
+		require.NoError(t, pool.TrySubmit(func() {
+			current := running.Add(1)
+
+			for {
+				highest := peak.Load()
+				if current <= highest || peak.CompareAndSwap(highest, current) {
+					break
+				}
+			}
+
+			time.Sleep(2 * time.Millisecond)
+			running.Add(-1)
+		}))
+	}
+
+	pool.Wait()
+
+	assert.Equal(t, int32(2), peak.Load())
+}
+
+func TestParallelismPastThePoolSizeStartsWorkers(t *testing.T) {
+	t.Parallel()
+
+	pool := workerpool.New(2, 16, constants.WorkerPoolOverflowReject, 0)
+	pool.SetParallelism(8)
+
+	assert.Equal(t, 8, pool.Parallelism())
+
+	var running atomic.Int32
+
+	release := make(chan struct{})
+
+	for range 8 {
+		require.NoError(t, pool.TrySubmit(func() {
+			running.Add(1)
+			<-release
+		}))
+	}
+
+	assert.Eventually(t, func() bool { return running.Load() == 8 }, time.Second, time.Millisecond)
+
+	close(release)
+	pool.Wait()
+}
+
+func TestShutdownDrainsTheQueueUntilTheDeadline(t *testing.T) {
+	t.Parallel()
+
+	pool := workerpool.New(1, 2, constants.WorkerPoolOverflowBlock, 20*time.Millisecond)
+	release := fill(t, pool)
+
+	assert.False(t, pool.Shutdown(time.Now().Add(10*time.Millisecond)), "the deadline passed with tasks queued")
+	require.ErrorIs(t, pool.Submit(func() {}), constants.ErrWorkerPoolClosed)
+	require.ErrorIs(t, pool.TrySubmit(func() {}), constants.ErrWorkerPoolClosed)
+
+	close(release)
+
+	assert.True(t, pool.Shutdown(time.Now().Add(time.Second)))
+	assert.Equal(t, 0, pool.QueueDepth())
+}
@@ -0,0 +1,27 @@
+package circuitbreakercontroller
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/constants"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/helpers"
+	listcircuitbreakers "github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/application/usecases/list_circuit_breakers"
+)
+
+type Controller struct {
+	usecase *listcircuitbreakers.UseCase
+}
+
+func NewController(listCircuitBreakersUseCase *listcircuitbreakers.UseCase) *Controller {
+	return &Controller{
+		usecase: listCircuitBreakersUseCase,
+	}
+}
+
+func (c *Controller) List(ctx *fiber.Ctx) error {
+	circuitBreakers := c.usecase.Execute()
+
+	return helpers.CreateResponse(ctx, &helpers.SuccessListResponse{
+		Data:  circuitBreakers,
+		Count: len(circuitBreakers),
+	}, constants.HTTPStatusOK)
+}
@@ -0,0 +1,70 @@
+package deadlettercontroller
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/constants"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/helpers"
+	managedeadletters "github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/application/usecases/manage_dead_letters"
+)
+
+type Controller struct {
+	usecase *managedeadletters.UseCase
+}
+
+func NewController(manageDeadLettersUseCase *managedeadletters.UseCase) *Controller {
+	return &Controller{
+		usecase: manageDeadLettersUseCase,
+	}
+}
+
+func (c *Controller) List(ctx *fiber.Ctx) error {
+	payments, err := c.usecase.List()
+	if err != nil {
+		return errorResponse(ctx, "error listing dead letters", err)
+	}
+
+	return helpers.CreateResponse(ctx, &helpers.SuccessListResponse{
+		Data:  payments,
+		Count: len(payments),
+	}, constants.HTTPStatusOK)
+}
+
+func (c *Controller) Find(ctx *fiber.Ctx) error {
+	payment, err := c.usecase.Find(ctx.Params("correlationId"))
+	if err != nil {
+		return errorResponse(ctx, "error finding dead letter", err)
+	}
+
+	return helpers.CreateResponse(ctx, payment, constants.HTTPStatusOK)
+}
+
+func (c *Controller) Requeue(ctx *fiber.Ctx) error {
+	if err := c.usecase.Requeue(ctx.Params("correlationId")); err != nil {
+		return errorResponse(ctx, "error requeueing dead letter", err)
+	}
+
+	return ctx.SendStatus(constants.HTTPStatusAccepted)
+}
+
+func (c *Controller) Discard(ctx *fiber.Ctx) error {
+	if err := c.usecase.Discard(ctx.Params("correlationId")); err != nil {
+		return errorResponse(ctx, "error discarding dead letter", err)
+	}
+
+	return ctx.SendStatus(constants.HTTPStatusNoContent)
+}
+
+func errorResponse(ctx *fiber.Ctx, message string, err error) error {
+	status := constants.HTTPStatusInternalServerError
+	if errors.Is(err, constants.ErrDeadLetterNotFound) {
+		status = constants.HTTPStatusNotFound
+	}
+
+	return helpers.CreateResponse(ctx, &helpers.ErrorResponse{
+		Message:     message,
+		Description: err.Error(),
+		StatusCode:  status,
+	}, status)
+}
@@ -0,0 +1,174 @@
+package deadlettercontroller_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/constants"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/helpers"
+	managedeadletters "github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/application/usecases/manage_dead_letters"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/entities"
+	deadlettercontroller "github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/presentation/controllers/dead_letter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeDeadLetterStore struct {
+	payments map[string]*entities.DeadLetterPayment
+	mutex    sync.Mutex
+}
+
+func (s *fakeDeadLetterStore) Add(payment *entities.DeadLetterPayment) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.payments[payment.CorrelationID] = payment
+
+	return nil
+}
+
+func (s *fakeDeadLetterStore) List() ([]*entities.DeadLetterPayment, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	payments := make([]*entities.DeadLetterPayment, 0, len(s.payments))
+	for _, payment := range s.payments {
+		payments = append(payments, payment)
+	}
+
+	return payments, nil
+}
+
+func (s *fakeDeadLetterStore) Get(correlationID string) (*entities.DeadLetterPayment, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	payment, ok := s.payments[correlationID]
+	if !ok {
+		return nil, constants.ErrDeadLetterNotFound
+	}
+
+	return payment, nil
+}
+
+func (s *fakeDeadLetterStore) Remove(correlationID string) (bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	_, ok := s.payments[correlationID]
+	delete(s.payments, correlationID)
+
+	return ok, nil
+}
+
+type fakeRetryQueue struct {
+	scheduled []*entities.QueuedPayment
+}
+
+func (q *fakeRetryQueue) Schedule(payment *entities.QueuedPayment, _ time.Time) error {
+	q.scheduled = append(q.scheduled, payment)
+
+	return nil
+}
+
+func (q *fakeRetryQueue) Claim(time.Time, int) ([]*entities.QueuedPayment, error) { return nil, nil }
+
+func (q *fakeRetryQueue) Extend(*entities.QueuedPayment, time.Time) (bool, error) { return true, nil }
+
+func (q *fakeRetryQueue) Ack(*entities.QueuedPayment) error { return nil }
+
+func (q *fakeRetryQueue) Len() (int, error) { return len(q.scheduled), nil }
+
+type fakeStatusStore struct{}
+
+func (fakeStatusStore) Record(string, ...*entities.PaymentTransition) error { return nil }
+
+func (fakeStatusStore) Get(string) (*entities.PaymentStatus, error) {
+	return nil, constants.ErrPaymentNotFound
+}
+
+func newApp(t *testing.T) (*fiber.App, *fakeRetryQueue) {
+	t.Helper()
+
+	store := &fakeDeadLetterStore{payments: map[string]*entities.DeadLetterPayment{
+		"exhausted": {CorrelationID: "exhausted", Amount: entities.NewMoneyFromCents(1990), Attempts: 5},
+	}}
+	retryQueue := &fakeRetryQueue{}
+
+	controller := deadlettercontroller.NewController(managedeadletters.NewUseCase(store, retryQueue, fakeStatusStore{}))
+
+	app := fiber.New(fiber.Config{JSONEncoder: helpers.Marshal, JSONDecoder: helpers.Unmarshal})
+	group := app.Group("/admin/dead-letters")
+	group.Get("", controller.List)
+	group.Get("/:correlationId", controller.Find)
+	group.Post("/:correlationId/requeue", controller.Requeue)
+	group.Delete("/:correlationId", controller.Discard)
+
+	return app, retryQueue
+}
+
+func call(t *testing.T, app *fiber.App, method, path string) (int, string) {
+	t.Helper()
+
+	response, err := app.Test(httptest.NewRequest(method, path, nil))
+	require.NoError(t, err)
+
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+	require.NoError(t, err)
+
+	return response.StatusCode, string(body)
+}
+
+func TestListAndFindDeadLetters(t *testing.T) {
+	t.Parallel()
+
+	app, _ := newApp(t)
+
+	status, body := call(t, app, http.MethodGet, "/admin/dead-letters")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, body, `"count":1`)
+	assert.Contains(t, body, `"correlationId":"exhausted"`)
+
+	status, body = call(t, app, http.MethodGet, "/admin/dead-letters/exhausted")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, body, `"attempts":5`)
+
+	status, _ = call(t, app, http.MethodGet, "/admin/dead-letters/unknown")
+	assert.Equal(t, http.StatusNotFound, status)
+}
+
+func TestRequeueSchedulesTheDeadLetterAgain(t *testing.T) {
+	t.Parallel()
+
+	app, retryQueue := newApp(t)
+
+	status, _ := call(t, app, http.MethodPost, "/admin/dead-letters/exhausted/requeue")
+	assert.Equal(t, http.StatusAccepted, status)
+
+	require.Len(t, retryQueue.scheduled, 1)
+	assert.Equal(t, "exhausted", retryQueue.scheduled[0].CorrelationID)
+	assert.Equal(t, 0, retryQueue.scheduled[0].Attempts, "a fresh set of attempts")
+
+	status, _ = call(t, app, http.MethodPost, "/admin/dead-letters/exhausted/requeue")
+	assert.Equal(t, http.StatusNotFound, status)
+}
+
+func TestDiscardDropsTheDeadLetter(t *testing.T) {
+	t.Parallel()
+
+	app, retryQueue := newApp(t)
+
+	status, _ := call(t, app, http.MethodDelete, "/admin/dead-letters/exhausted")
+	assert.Equal(t, http.StatusNoContent, status)
+	assert.Empty(t, retryQueue.scheduled)
+
+	status, _ = call(t, app, http.MethodDelete, "/admin/dead-letters/exhausted")
+	assert.Equal(t, http.StatusNotFound, status)
+}
@@ -17,6 +17,7 @@ func NewController(healthUseCase *healthcheck.UseCase) *Controller {
 	}
 }
 
+// Check reports every dependency and always answers 200, see Ready for probes.
 func (c *Controller) Check(ctx *fiber.Ctx) error {
 	check, err := c.usecase.Execute()
 	if err != nil {
@@ -29,3 +30,26 @@ func (c *Controller) Check(ctx *fiber.Ctx) error {
 
 	return helpers.CreateResponse(ctx, check, constants.HTTPStatusOK)
 }
+
+func (c *Controller) Live(ctx *fiber.Ctx) error {
+	return helpers.CreateResponse(ctx, c.usecase.Live(), constants.HTTPStatusOK)
+}
+
+// Ready answers 503 while the instance cannot take payments in, so the load
+// balancer sends them to another one.
+func (c *Controller) Ready(ctx *fiber.Ctx) error {
+	check, err := c.usecase.Execute()
+	if err != nil {
+		return helpers.CreateResponse(ctx, &helpers.ErrorResponse{
+			Message:     "error checking readiness",
+			Description: err.Error(),
+			StatusCode:  constants.HTTPStatusServiceUnavailable,
+		}, constants.HTTPStatusServiceUnavailable)
+	}
+
+	if check.Status != constants.HealthStatusUp {
+		return helpers.CreateResponse(ctx, check, constants.HTTPStatusServiceUnavailable)
+	}
+
+	return helpers.CreateResponse(ctx, check, constants.HTTPStatusOK)
+}
@@ -0,0 +1,25 @@
+package metricscontroller
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/constants"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/contracts"
+)
+
+const contentType = "text/plain; version=0.0.4; charset=utf-8"
+
+type Controller struct {
+	exporter contracts.MetricsExporter
+}
+
+func NewController(exporter contracts.MetricsExporter) *Controller {
+	return &Controller{
+		exporter: exporter,
+	}
+}
+
+func (c *Controller) Export(ctx *fiber.Ctx) error {
+	ctx.Set(fiber.HeaderContentType, contentType)
+
+	return ctx.Status(constants.HTTPStatusOK).Send(c.exporter.Render())
+}
@@ -1,35 +1,61 @@
 package paymentcontroller
 
 import (
+	"errors"
 	"log"
+	"strconv"
+	"sync"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/marincor/rinha-de-backend-2025-marincor-golang/constants"
 	"github.com/marincor/rinha-de-backend-2025-marincor-golang/helpers"
 	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/application/dtos"
 	processpayment "github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/application/usecases/process_payment"
+	retrievepaymentstatus "github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/application/usecases/retrieve_payment_status"
 	retrievepaymentsummary "github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/application/usecases/retrieve_payment_summary"
 	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/contracts"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/domain/entities"
 	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/presentation/controllers/payment/validators"
 )
 
 type Controller struct {
 	processPaymentUsecase         *processpayment.UseCase
 	retrievePaymentSummaryUsecase *retrievepaymentsummary.UseCase
+	retrievePaymentStatusUsecase  *retrievepaymentstatus.UseCase
 	validator                     *validators.Validator
 	workerpool                    contracts.WorkerPoolManager
+	stop                          chan struct{}
+	// dispatched holds the payments submitted to the pool that no worker has
+	// taken yet, whoever removes one first either runs it or persists it.
+	dispatched sync.Map
+	// held keeps the payments that neither the pool nor the retry queue took,
+	// dispatched again on the next retries tick. New payments are turned away
+	// once it reaches constants.MaxHeldPayments.
+	held         []*entities.QueuedPayment
+	heldMutex    sync.Mutex
+	stopOnce     sync.Once
+	rejectStatus int
 }
 
+// NewController answers rejectStatus, 503 or 429, to the payments turned away
+// while the worker pool is full under the reject overflow policy, or while too
+// many payments are held for want of a retry queue.
 func NewController(
 	processPaymentUsecase *processpayment.UseCase,
 	retrievePaymentSummaryUsecase *retrievepaymentsummary.UseCase,
+	retrievePaymentStatusUsecase *retrievepaymentstatus.UseCase,
 	workerpool contracts.WorkerPoolManager,
+	rejectStatus int,
 ) *Controller {
 	return &Controller{
 		processPaymentUsecase:         processPaymentUsecase,
 		retrievePaymentSummaryUsecase: retrievePaymentSummaryUsecase,
+		retrievePaymentStatusUsecase:  retrievePaymentStatusUsecase,
 		validator:                     validators.New(),
 		workerpool:                    workerpool,
+		stop:                          make(chan struct{}),
+		rejectStatus:                  rejectStatus,
 	}
 }
 
@@ -44,8 +70,160 @@ func (c *Controller) ProcessPayment(ctx *fiber.Ctx) error {
 		}, constants.HTTPStatusUnprocessableEntity)
 	}
 
-	go c.workerpool.Submit(func() {
-		_, err := c.processPaymentUsecase.Execute(&paymentRequest)
+	// shed the load before accepting anything, a payment turned away leaves
+	// nothing behind and the client can simply retry it
+	if err := c.overloaded(); err != nil {
+		ctx.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(constants.WorkerPoolRetryAfter.Seconds())))
+
+		return helpers.CreateResponse(ctx, &helpers.ErrorResponse{
+			Message:     "error accepting payment",
+			Description: err.Error(),
+			StatusCode:  c.rejectStatus,
+		}, c.rejectStatus)
+	}
+
+	queuedPayment, err := c.processPaymentUsecase.Enqueue(&paymentRequest)
+	if err != nil && !errors.Is(err, constants.ErrDuplicatePayment) {
+		return helpers.CreateResponse(ctx, &helpers.ErrorResponse{
+			Message:     "error accepting payment",
+			Description: err.Error(),
+			StatusCode:  constants.HTTPStatusServiceUnavailable,
+		}, constants.HTTPStatusServiceUnavailable)
+	}
+
+	statusCode := constants.HTTPStatusNoContent
+
+	// accepted either way, but one that had to wait for workers is not being
+	// processed yet
+	if queuedPayment != nil {
+		taken, err := c.dispatch(queuedPayment)
+		if err != nil {
+			c.hold(queuedPayment, err)
+		}
+
+		if !taken {
+			statusCode = constants.HTTPStatusAccepted
+		}
+	}
+
+	response := ctx.Response()
+
+	response.Header.Set("Content-Length", "0")
+
+	response.SetStatusCode(statusCode)
+
+	return nil
+}
+
+// overloaded tells why a new payment must be turned away, nil when it can be
+// accepted.
+func (c *Controller) overloaded() error {
+	if c.workerpool.Overflow() == constants.WorkerPoolOverflowReject &&
+		c.workerpool.QueueDepth() >= c.workerpool.Capacity() {
+		return constants.ErrWorkerPoolSaturated
+	}
+
+	c.heldMutex.Lock()
+	defer c.heldMutex.Unlock()
+
+	if len(c.held) >= constants.MaxHeldPayments {
+		return constants.ErrTooManyPaymentsHeld
+	}
+
+	return nil
+}
+
+// ReplayPendingPayments resubmits what was accepted but not processed before the last shutdown.
+func (c *Controller) ReplayPendingPayments() {
+	payments, err := c.processPaymentUsecase.Pending()
+	if err != nil {
+		log.Print(
+			map[string]any{
+				"message": "error replaying pending payments",
+				"error":   err,
+			},
+		)
+
+		return
+	}
+
+	if len(payments) > 0 {
+		log.Print(
+			map[string]any{
+				"message": "replaying pending payments",
+				"amount":  len(payments),
+			},
+		)
+	}
+
+	for _, payment := range payments {
+		if _, err := c.dispatch(payment); err != nil {
+			c.hold(payment, err)
+		}
+	}
+}
+
+// DispatchRetries keeps feeding the worker pool with failed payments whose
+// next attempt is due, claiming no more of them than the pool has room for:
+// the rest stay scheduled instead of being claimed only to be spilled back.
+func (c *Controller) DispatchRetries() {
+	ticker := time.NewTicker(constants.PaymentRetryPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+		}
+
+		c.heldMutex.Lock()
+		payments := c.held
+		c.held = nil
+		c.heldMutex.Unlock()
+
+		for _, payment := range payments {
+			if _, err := c.dispatch(payment); err != nil {
+				c.hold(payment, err)
+			}
+		}
+
+		room := min(c.workerpool.Capacity()-c.workerpool.QueueDepth(), constants.PaymentRetryBatchSize)
+		if room <= 0 {
+			continue
+		}
+
+		payments, err := c.processPaymentUsecase.DueRetries(room)
+		if err != nil {
+			go log.Print(
+				map[string]any{
+					"message": "error claiming payment retries",
+					"error":   err,
+				},
+			)
+		}
+
+		for _, payment := range payments {
+			if _, err := c.dispatch(payment); err != nil {
+				c.hold(payment, err)
+			}
+		}
+	}
+}
+
+// dispatch hands the payment to the worker pool, spilling it to the retry
+// queue when the pool has no room; it tells whether a worker will take it and
+// returns the error when spilling failed too, leaving the payment with the
+// caller.
+func (c *Controller) dispatch(queuedPayment *entities.QueuedPayment) (bool, error) {
+	c.dispatched.Store(queuedPayment, struct{}{})
+
+	err := c.workerpool.Submit(func() {
+		if _, taken := c.dispatched.LoadAndDelete(queuedPayment); !taken {
+			return
+		}
+
+		_, err := c.processPaymentUsecase.Execute(queuedPayment)
 		if err != nil {
 			go log.Print(
 				map[string]any{
@@ -55,14 +233,81 @@ func (c *Controller) ProcessPayment(ctx *fiber.Ctx) error {
 			)
 		}
 	})
+	if err == nil {
+		return true, nil
+	}
 
-	response := ctx.Response()
+	c.dispatched.Delete(queuedPayment)
 
-	response.Header.Set("Content-Length", "0")
+	if err := c.processPaymentUsecase.Spill(queuedPayment); err != nil {
+		return false, err
+	}
 
-	response.SetStatusCode(constants.HTTPStatusNoContent)
+	return false, nil
+}
 
-	return nil
+// hold keeps a payment that could not be spilled for the next retries tick.
+// A claimed retry is left alone, it comes due again once its lease runs out.
+// Payments already accepted are always kept, the cap only stops new ones.
+func (c *Controller) hold(queuedPayment *entities.QueuedPayment, spillErr error) {
+	go log.Print(
+		map[string]any{
+			"message":        "error spilling payment",
+			"correlation_id": queuedPayment.CorrelationID,
+			"error":          spillErr,
+		},
+	)
+
+	if queuedPayment.RetryID != "" {
+		return
+	}
+
+	c.heldMutex.Lock()
+	defer c.heldMutex.Unlock()
+
+	c.held = append(c.held, queuedPayment)
+}
+
+// Drain stops claiming retries and lets the worker pool finish until the
+// deadline, then persists to the retry queue the payments no worker took, for
+// the other instance or the next boot to process.
+func (c *Controller) Drain(deadline time.Time) *dtos.DrainReport {
+	c.stopOnce.Do(func() { close(c.stop) })
+
+	report := &dtos.DrainReport{Drained: c.workerpool.Shutdown(deadline)}
+
+	c.heldMutex.Lock()
+	payments := c.held
+	c.held = nil
+	c.heldMutex.Unlock()
+
+	c.dispatched.Range(func(key, _ any) bool {
+		if _, taken := c.dispatched.LoadAndDelete(key); taken {
+			payments = append(payments, key.(*entities.QueuedPayment))
+		}
+
+		return true
+	})
+
+	for _, queuedPayment := range payments {
+		if err := c.processPaymentUsecase.Spill(queuedPayment); err != nil {
+			report.Failed++
+
+			log.Print(
+				map[string]any{
+					"message":        "error persisting payment on shutdown",
+					"correlation_id": queuedPayment.CorrelationID,
+					"error":          err,
+				},
+			)
+
+			continue
+		}
+
+		report.Persisted++
+	}
+
+	return report
 }
 
 func (c *Controller) RetrievePaymentSummary(ctx *fiber.Ctx) error {
@@ -103,3 +348,21 @@ func (c *Controller) RetrievePaymentSummary(ctx *fiber.Ctx) error {
 
 	return nil
 }
+
+func (c *Controller) RetrievePaymentStatus(ctx *fiber.Ctx) error {
+	status, err := c.retrievePaymentStatusUsecase.Execute(ctx.Params("correlationId"))
+	if err != nil {
+		statusCode := constants.HTTPStatusInternalServerError
+		if errors.Is(err, constants.ErrPaymentNotFound) {
+			statusCode = constants.HTTPStatusNotFound
+		}
+
+		return helpers.CreateResponse(ctx, &helpers.ErrorResponse{
+			Message:     "error retrieving payment status",
+			Description: err.Error(),
+			StatusCode:  statusCode,
+		}, statusCode)
+	}
+
+	return helpers.CreateResponse(ctx, status, constants.HTTPStatusOK)
+}
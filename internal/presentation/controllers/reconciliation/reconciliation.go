@@ -0,0 +1,103 @@
+package reconciliationcontroller
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/constants"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/helpers"
+	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/application/dtos"
+	reconcilepayments "github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/application/usecases/reconcile_payments"
+)
+
+type Controller struct {
+	usecase *reconcilepayments.UseCase
+}
+
+func NewController(reconcilePaymentsUseCase *reconcilepayments.UseCase) *Controller {
+	return &Controller{
+		usecase: reconcilePaymentsUseCase,
+	}
+}
+
+// Retrieve returns the latest report; running a reconciliation may repair the
+// storage, so it is only done by Run, on POST.
+func (c *Controller) Retrieve(ctx *fiber.Ctx) error {
+	report, err := c.usecase.LastReport()
+	if err != nil {
+		return errorResponse(ctx, "error retrieving reconciliation report", err)
+	}
+
+	return helpers.CreateResponse(ctx, report, constants.HTTPStatusOK)
+}
+
+func (c *Controller) Run(ctx *fiber.Ctx) error {
+	var request dtos.ReconciliationRequest
+
+	if err := ctx.QueryParser(&request); err != nil {
+		return helpers.CreateResponse(ctx, &helpers.ErrorResponse{
+			Message:     "error parsing query params",
+			Description: err.Error(),
+			StatusCode:  constants.HTTPStatusUnprocessableEntity,
+		}, constants.HTTPStatusUnprocessableEntity)
+	}
+
+	report, err := c.usecase.Execute(&request, constants.ReconciliationTriggerManual)
+	if err != nil {
+		return errorResponse(ctx, "error reconciling payments", err)
+	}
+
+	return helpers.CreateResponse(ctx, report, constants.HTTPStatusOK)
+}
+
+// ReconcilePeriodically checks the latest window on every tick.
+func (c *Controller) ReconcilePeriodically(interval time.Duration, repair bool) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		report, err := c.usecase.Execute(
+			&dtos.ReconciliationRequest{Repair: repair},
+			constants.ReconciliationTriggerScheduled,
+		)
+		if err != nil {
+			go log.Print(
+				map[string]any{
+					"message": "error reconciling payments",
+					"error":   err,
+				},
+			)
+
+			continue
+		}
+
+		if !report.Consistent {
+			go log.Print(
+				map[string]any{
+					"message":    "payments summary disagrees with processors",
+					"mismatches": len(report.Mismatches),
+					"errors":     report.Errors,
+				},
+			)
+		}
+	}
+}
+
+func errorResponse(ctx *fiber.Ctx, message string, err error) error {
+	status := constants.HTTPStatusInternalServerError
+
+	switch {
+	case errors.Is(err, constants.ErrReconciliationNotFound):
+		status = constants.HTTPStatusNotFound
+	case errors.Is(err, constants.ErrInvalidReconciliationWindow):
+		status = constants.HTTPStatusBadRequest
+	}
+
+	return helpers.CreateResponse(ctx, &helpers.ErrorResponse{
+		Message:     message,
+		Description: err.Error(),
+		StatusCode:  status,
+	}, status)
+}
@@ -1,7 +1,6 @@
 package main
 
 import (
-	"log"
 	"os"
 	"os/signal"
 	"syscall"
@@ -18,29 +17,18 @@ func main() {
 
 	app.ApplicationInit()
 
-	MaxGoRoutinesToProcess := 10
+	workerPool := workerpool.New(
+		appinstance.Data.Config.WorkerPoolWorkers,
+		appinstance.Data.Config.WorkerPoolQueueSize,
+		appinstance.Data.Config.WorkerPoolOverflow,
+		appinstance.Data.Config.WorkerPoolBlockTimeout,
+	)
 
-	workerPool := workerpool.New(MaxGoRoutinesToProcess)
-
-	appinstance.Data.Server = route(workerPool)
+	server, shutdown := route(workerPool)
+	appinstance.Data.Server = server
 
 	go app.Setup(appinstance.Data.Config.ServerPort)
-	defer func() {
-		if err := appinstance.Data.Server.Shutdown(); err != nil {
-			log.Print(
-				map[string]interface{}{
-					"message": "error shutting down server",
-					"error":   err,
-				},
-			)
-		} else {
-			log.Print("Server closed")
-		}
-	}()
 
 	<-sigChan
-	log.Print("Received signal, shutting down...")
-
-	log.Print("Waiting for tasks to finish...")
-	workerPool.Wait()
+	shutdown.run()
 }
@@ -10,7 +10,7 @@ import (
 	"github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/infra/app/appinstance"
 )
 
-func route(workerPool contracts.WorkerPoolManager) *fiber.App {
+func route(workerPool contracts.WorkerPoolManager) (*fiber.App, *shutdown) {
 	// middlewares
 	appinstance.Data.Server.Use(compress.New(compress.Config{
 		Level: compress.LevelBestSpeed,
@@ -20,17 +20,64 @@ func route(workerPool contracts.WorkerPoolManager) *fiber.App {
 		appinstance.Data.Server.Use(pprof.New())
 	}
 
-	healthController := makeHealthController()
-	paymentController := makePaymentController(appinstance.Data.Config, workerPool)
+	dependencies := makeDependencies(appinstance.Data.Config)
+	followConcurrencyLimits(dependencies, workerPool)
+
+	healthUseCase := makeHealthUseCase(dependencies, workerPool)
+	healthController := makeHealthController(healthUseCase)
+
+	paymentController := makePaymentController(appinstance.Data.Config, dependencies, workerPool)
+
+	deadLetterController := makeDeadLetterController(dependencies)
+	reconciliationController := makeReconciliationController(appinstance.Data.Config, dependencies)
+	metricsController := makeMetricsController(dependencies, workerPool)
+	circuitBreakerController := makeCircuitBreakerController(dependencies)
+
+	go dependencies.healthMonitor.Run()
+	go paymentController.ReplayPendingPayments()
+	go paymentController.DispatchRetries()
+
+	if appinstance.Data.Config.ReconciliationEnabled {
+		go reconciliationController.ReconcilePeriodically(
+			appinstance.Data.Config.ReconciliationInterval,
+			appinstance.Data.Config.ReconciliationRepair,
+		)
+	}
 
 	healthGroup := appinstance.Data.Server.Group("/health")
 	healthGroup.Get("", healthController.Check).Name("health_check")
+	healthGroup.Get("/live", healthController.Live).Name("health_live")
+	healthGroup.Get("/ready", healthController.Ready).Name("health_ready")
+
+	metricsGroup := appinstance.Data.Server.Group("/metrics")
+	metricsGroup.Get("", metricsController.Export).Name("export_metrics")
 
 	paymentGroup := appinstance.Data.Server.Group("/payments")
 	paymentGroup.Post("", paymentController.ProcessPayment).Name("process_payment")
+	paymentGroup.Get("/:correlationId", paymentController.RetrievePaymentStatus).Name("retrieve_payment_status")
 
 	paymentsSummaryGroup := appinstance.Data.Server.Group("/payments-summary")
 	paymentsSummaryGroup.Get("", paymentController.RetrievePaymentSummary).Name("retrieve_payment_summary")
 
-	return appinstance.Data.Server
+	deadLetterGroup := appinstance.Data.Server.Group("/admin/dead-letters")
+	deadLetterGroup.Get("", deadLetterController.List).Name("list_dead_letters")
+	deadLetterGroup.Get("/:correlationId", deadLetterController.Find).Name("find_dead_letter")
+	deadLetterGroup.Post("/:correlationId/requeue", deadLetterController.Requeue).Name("requeue_dead_letter")
+	deadLetterGroup.Delete("/:correlationId", deadLetterController.Discard).Name("discard_dead_letter")
+
+	circuitBreakerGroup := appinstance.Data.Server.Group("/admin/circuit-breakers")
+	circuitBreakerGroup.Get("", circuitBreakerController.List).Name("list_circuit_breakers")
+
+	reconciliationGroup := appinstance.Data.Server.Group("/admin/reconciliation")
+	reconciliationGroup.Get("", reconciliationController.Retrieve).Name("retrieve_reconciliation")
+	reconciliationGroup.Post("", reconciliationController.Run).Name("run_reconciliation")
+
+	return appinstance.Data.Server, &shutdown{
+		server:   appinstance.Data.Server,
+		health:   healthUseCase,
+		monitor:  dependencies.healthMonitor,
+		payments: paymentController,
+		timeout:  appinstance.Data.Config.ShutdownTimeout,
+		grace:    appinstance.Data.Config.ShutdownGrace,
+	}
 }
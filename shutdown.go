@@ -0,0 +1,66 @@
+package main
+
+import (
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	healthcheck "github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/application/usecases/health_check"
+	healthmonitor "github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/infra/health_monitor"
+	paymentcontroller "github.com/marincor/rinha-de-backend-2025-marincor-golang/internal/presentation/controllers/payment"
+)
+
+// shutdown winds the instance down in order: readiness fails first so the load
+// balancer stops sending payments and the health leadership goes to the other
+// instance, the server then stops accepting them and the worker pool drains
+// until the deadline. Payments no worker took by then are
+// persisted for the other instance or the next boot.
+type shutdown struct {
+	server   *fiber.App
+	health   *healthcheck.UseCase
+	monitor  *healthmonitor.Monitor
+	payments *paymentcontroller.Controller
+	timeout  time.Duration
+	grace    time.Duration
+}
+
+func (s *shutdown) run() {
+	startedAt := time.Now()
+	deadline := startedAt.Add(s.timeout)
+
+	s.health.Drain()
+
+	resignErr := s.monitor.Resign()
+
+	log.Print(
+		map[string]any{
+			"message": "shutting down",
+			"timeout": s.timeout.String(),
+		},
+	)
+
+	time.Sleep(min(s.grace, s.timeout))
+
+	serverErr := s.server.ShutdownWithTimeout(time.Until(deadline))
+	if serverErr != nil {
+		log.Print(
+			map[string]any{
+				"message": "error shutting down server",
+				"error":   serverErr,
+			},
+		)
+	}
+
+	report := s.payments.Drain(deadline)
+
+	log.Print(
+		map[string]any{
+			"message":      "shutdown finished",
+			"drained":      report.Drained,
+			"persisted":    report.Persisted,
+			"failed":       report.Failed,
+			"resign_error": resignErr,
+			"elapsed":      time.Since(startedAt).String(),
+		},
+	)
+}